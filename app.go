@@ -12,6 +12,7 @@ package main
 
 import (
 	"os"
+	"time"
 
 	"github.com/futcity/controller/auth"
 	"github.com/futcity/controller/configs"
@@ -29,11 +30,20 @@ type App struct {
 	log     *utils.Log
 	cfg     *utils.Configs
 	db      *db.Database
+	wdt     *core.Watchdog
+	rec     *core.Reconciler
+	cmd     *core.Commander
+	fw      *core.Firmware
+	sched   *core.Scheduler
+	sun     *core.Sun
+	rules   *core.Rules
+	timers  *core.Timers
 }
 
 // NewApp Make new struct
 func NewApp(s *core.Storage, srv *server.WebServer, a *auth.Authorization, l *utils.Log,
-	c *utils.Configs, d *db.Database) *App {
+	c *utils.Configs, d *db.Database, w *core.Watchdog, r *core.Reconciler, cmd *core.Commander,
+	f *core.Firmware, sch *core.Scheduler, sun *core.Sun, rl *core.Rules, t *core.Timers) *App {
 	return &App{
 		storage: s,
 		server:  srv,
@@ -41,6 +51,14 @@ func NewApp(s *core.Storage, srv *server.WebServer, a *auth.Authorization, l *ut
 		log:     l,
 		cfg:     c,
 		db:      d,
+		wdt:     w,
+		rec:     r,
+		cmd:     cmd,
+		fw:      f,
+		sched:   sch,
+		sun:     sun,
+		rules:   rl,
+		timers:  t,
 	}
 }
 
@@ -87,11 +105,126 @@ func (a *App) Start() {
 		a.log.Error("APP", "Fail to load profile database", err.Error())
 		return
 	}
-	err = a.db.LoadRelayBase()
+	err = a.db.LoadGroupBase()
 	if err != nil {
-		a.log.Error("APP", "Fail to load relay database", err.Error())
+		a.log.Error("APP", "Fail to load group database", err.Error())
 		return
 	}
+	err = a.db.LoadSceneBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load scene database", err.Error())
+		return
+	}
+	err = a.db.LoadInterlockBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load interlock database", err.Error())
+		return
+	}
+	err = a.db.LoadTokenBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load device tokens database", err.Error())
+		return
+	}
+	if ac.Pairing.Timeout > 0 {
+		a.aut.SetPairingTimeout(time.Duration(ac.Pairing.Timeout) * time.Second)
+	}
+	err = a.storage.LoadBases()
+	if err != nil {
+		a.log.Error("APP", "Fail to load device types databases", err.Error())
+		return
+	}
+
+	//
+	// Load firmware images
+	//
+	if ac.Firmware.Path != "" {
+		a.fw.SetPath(ac.Firmware.Path)
+	}
+	err = a.db.LoadFirmwareBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load firmware database", err.Error())
+		return
+	}
+
+	//
+	// Starting devices watchdog
+	//
+	var interval = core.WatchdogInterval
+	if ac.Watchdog.Interval > 0 {
+		interval = time.Duration(ac.Watchdog.Interval) * time.Second
+	}
+	if ac.Watchdog.Timeout > 0 {
+		a.wdt.SetTimeout(time.Duration(ac.Watchdog.Timeout) * time.Second)
+	}
+	for _, typ := range ac.Watchdog.Types {
+		a.wdt.SetTypeTimeout(typ.Type, time.Duration(typ.Timeout)*time.Second)
+	}
+	a.wdt.Start(interval)
+
+	//
+	// Starting relays reconciliation
+	//
+	interval = core.ReconcileInterval
+	if ac.Reconcile.Interval > 0 {
+		interval = time.Duration(ac.Reconcile.Interval) * time.Second
+	}
+	if ac.Reconcile.Deadline > 0 {
+		a.rec.SetDeadline(time.Duration(ac.Reconcile.Deadline) * time.Second)
+	}
+	a.rec.Start(interval)
+
+	//
+	// Starting interlocks pending commands completion
+	//
+	a.cmd.Start()
+
+	//
+	// Starting scheduler
+	//
+	if ac.Location.Timezone != "" || ac.Location.Latitude != 0 || ac.Location.Longitude != 0 {
+		var tz = time.Local
+		if ac.Location.Timezone != "" {
+			tz, err = time.LoadLocation(ac.Location.Timezone)
+			if err != nil {
+				a.log.Error("APP", "Fail to load timezone", err.Error())
+				return
+			}
+		}
+		a.sun.SetLocation(ac.Location.Latitude, ac.Location.Longitude, tz)
+	}
+	err = a.db.LoadScheduleBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load schedule database", err.Error())
+		return
+	}
+	interval = core.SchedulerInterval
+	if ac.Scheduler.Interval > 0 {
+		interval = time.Duration(ac.Scheduler.Interval) * time.Second
+	}
+	if ac.Scheduler.Lateness > 0 {
+		a.sched.SetLateness(time.Duration(ac.Scheduler.Lateness) * time.Second)
+	}
+	a.sched.Start(interval)
+
+	//
+	// Starting rules engine
+	//
+	err = a.db.LoadRuleBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load rule database", err.Error())
+		return
+	}
+	a.rules.Start(core.RulesInterval)
+
+	//
+	// Starting relays timers
+	//
+	err = a.db.LoadTimerBase()
+	if err != nil {
+		a.log.Error("APP", "Fail to load timer database", err.Error())
+		return
+	}
+	a.timers.Start(core.TimersInterval)
 
 	//
 	// Starting server
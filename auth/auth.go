@@ -10,41 +10,59 @@
 
 package auth
 
-import "errors"
+import (
+	"errors"
+	"sync"
+	"time"
+)
 
-// Authorization User profiles
+// Authorization User profiles and devices tokens
 type Authorization struct {
-	prof map[string]*Profile
+	mtx            sync.RWMutex
+	prof           map[string]*Profile
+	tokens         map[string]DeviceToken
+	codes          map[string]PairingCode
+	pairingTimeout time.Duration
 }
 
 // NewAuthorization Make new struct
 func NewAuthorization() *Authorization {
 	return &Authorization{
-		prof: make(map[string]*Profile),
+		prof:           make(map[string]*Profile),
+		tokens:         make(map[string]DeviceToken),
+		codes:          make(map[string]PairingCode),
+		pairingTimeout: PairingTimeout,
 	}
 }
 
 // AddProfile Add new user profile
 func (a *Authorization) AddProfile(prof *Profile) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
 	a.prof[prof.APIKey()] = prof
 }
 
 // DeleteProfile Delete user profile
 func (a *Authorization) DeleteProfile(name string) error {
-	for _, prof := range a.prof {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for key, prof := range a.prof {
 		if prof.Name() == name {
-			delete(a.prof, name)
+			delete(a.prof, key)
 			return nil
 		}
 	}
 	return errors.New("Profile not found")
 }
 
-// Validation Check user device by key
+// Validation Check user device by key. Device token can only read
+// own device
 func (a *Authorization) Validation(key string, device string) (bool, bool) {
-	var prof = a.prof[key]
+	var prof = a.profile(key)
 	if prof == nil {
-		return false, false
+		return a.isDeviceToken(key, device), false
 	}
 
 	if prof.Admin() {
@@ -59,8 +77,119 @@ func (a *Authorization) Validation(key string, device string) (bool, bool) {
 	return dev.Read(), dev.Write()
 }
 
+// ChannelValidation Check user device channel by key, device rights
+// are used for channels without own rights
+func (a *Authorization) ChannelValidation(key string, device string, channel int) (bool, bool) {
+	var prof = a.profile(key)
+	if prof == nil {
+		return a.isDeviceToken(key, device), false
+	}
+
+	if prof.Admin() {
+		return true, true
+	}
+
+	var dev = prof.Device(device)
+	if dev == nil {
+		return false, false
+	}
+
+	var ch = dev.Channel(channel)
+	if ch == nil {
+		return dev.Read(), dev.Write()
+	}
+
+	return ch.Read(), ch.Write()
+}
+
+// RenameDevice Move device rights, token and pairing codes to new
+// device name
+func (a *Authorization) RenameDevice(name string, newName string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, prof := range a.prof {
+		prof.RenameDevice(name, newName)
+	}
+
+	for hash, token := range a.tokens {
+		if token.Device == name {
+			token.Device = newName
+			a.tokens[hash] = token
+		}
+	}
+	for c, pc := range a.codes {
+		if pc.Device == name {
+			pc.Device = newName
+			a.codes[c] = pc
+		}
+	}
+}
+
+// ForgetDevice Remove rights of removed device from all profiles and
+// revoke its token and pairing codes
+func (a *Authorization) ForgetDevice(device string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var found bool
+
+	for _, prof := range a.prof {
+		if prof.Device(device) != nil {
+			prof.RemoveDevice(device)
+			found = true
+		}
+	}
+
+	for c, pc := range a.codes {
+		if pc.Device == device {
+			delete(a.codes, c)
+		}
+	}
+
+	return a.revoke(device) || found
+}
+
+// ForgetChannels Remove rights of multi relay channels from count and
+// above from all profiles
+func (a *Authorization) ForgetChannels(device string, count int) bool {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	var found bool
+
+	for _, prof := range a.prof {
+		var dev = prof.Device(device)
+		if dev == nil {
+			continue
+		}
+
+		for _, ch := range dev.Channels() {
+			if ch.Channel() >= count {
+				dev.RemoveChannel(ch.Channel())
+				found = true
+			}
+		}
+	}
+
+	return found
+}
+
+// RemoveGroup Remove group from all profiles
+func (a *Authorization) RemoveGroup(name string) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	for _, prof := range a.prof {
+		prof.RemoveGroup(name)
+	}
+}
+
 // Profiles Get all profiles
 func (a *Authorization) Profiles() []*Profile {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
 	var profiles []*Profile
 
 	for _, prof := range a.prof {
@@ -72,6 +201,9 @@ func (a *Authorization) Profiles() []*Profile {
 
 // Profiles Get profile by name
 func (a *Authorization) Profile(name string) *Profile {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
 	for _, profile := range a.prof {
 		if profile.Name() == name {
 			return profile
@@ -80,9 +212,14 @@ func (a *Authorization) Profile(name string) *Profile {
 	return nil
 }
 
+// ProfileByKey Get profile by API key
+func (a *Authorization) ProfileByKey(key string) *Profile {
+	return a.profile(key)
+}
+
 // IsAdmin Check admin status
 func (a *Authorization) IsAdmin(key string) bool {
-	var prof = a.prof[key]
+	var prof = a.profile(key)
 	if prof == nil {
 		return false
 	}
@@ -95,11 +232,18 @@ func (a *Authorization) IsAdmin(key string) bool {
 }
 
 // Groups Get profile groups
-func (a *Authorization) Groups(key string) (*[]string, error) {
-	var profile = a.prof[key]
+func (a *Authorization) Groups(key string) ([]string, error) {
+	var profile = a.profile(key)
 	if profile == nil {
 		return nil, errors.New("Profile not found")
 	}
 
 	return profile.Groups(), nil
 }
+
+func (a *Authorization) profile(key string) *Profile {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.prof[key]
+}
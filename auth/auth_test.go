@@ -0,0 +1,89 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package auth
+
+import "testing"
+
+func TestChannelValidation(t *testing.T) {
+	var a = NewAuthorization()
+	a.AddProfile(NewProfile("admin", "admin-key", true))
+
+	var user = NewProfile("user", "user-key", false)
+	var board = NewProfileDevice("board0", true, false)
+	board.AddChannel(NewProfileChannel(1, true, true))
+	board.AddChannel(NewProfileChannel(2, false, false))
+	user.AddDevice(board)
+	a.AddProfile(user)
+
+	var tests = []struct {
+		key     string
+		device  string
+		channel int
+		read    bool
+		write   bool
+	}{
+		{"admin-key", "board0", 5, true, true},
+		{"user-key", "board0", 0, true, false},
+		{"user-key", "board0", 1, true, true},
+		{"user-key", "board0", 2, false, false},
+		{"user-key", "board1", 0, false, false},
+		{"wrong-key", "board0", 0, false, false},
+	}
+	for _, test := range tests {
+		var read, write = a.ChannelValidation(test.key, test.device, test.channel)
+		if read != test.read || write != test.write {
+			t.Errorf("%s %s channel %d: got %v %v, want %v %v", test.key, test.device, test.channel,
+				read, write, test.read, test.write)
+		}
+	}
+
+	// Re-added device replaces channels rights
+	user.AddDevice(NewProfileDevice("board0", true, false))
+	var read, write = a.ChannelValidation("user-key", "board0", 1)
+	if !read || write {
+		t.Error("old channel rights are kept:", read, write)
+	}
+	read, _ = a.ChannelValidation("user-key", "board0", 2)
+	if !read {
+		t.Error("old channel rights are kept for denied channel")
+	}
+
+	// Channels rights are moved with device name
+	board = NewProfileDevice("board0", false, false)
+	board.AddChannel(NewProfileChannel(3, true, true))
+	user.AddDevice(board)
+	a.RenameDevice("board0", "board1")
+	read, write = a.ChannelValidation("user-key", "board1", 3)
+	if !read || !write || user.Device("board0") != nil {
+		t.Error("channel rights are not renamed:", read, write)
+	}
+}
+
+func TestForgetChannels(t *testing.T) {
+	var a = NewAuthorization()
+
+	var user = NewProfile("user", "user-key", false)
+	var board = NewProfileDevice("board0", true, false)
+	board.AddChannel(NewProfileChannel(0, true, true))
+	board.AddChannel(NewProfileChannel(2, true, true))
+	user.AddDevice(board)
+	a.AddProfile(user)
+
+	if a.ForgetChannels("board0", 3) || a.ForgetChannels("board1", 0) {
+		t.Error("rights of existing channels are forgotten")
+	}
+	if !a.ForgetChannels("board0", 1) {
+		t.Fatal("rights of removed channels are not forgotten")
+	}
+	if len(board.Channels()) != 1 || board.Channel(0) == nil {
+		t.Error("wrong channels rights:", board.Channels())
+	}
+}
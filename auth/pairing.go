@@ -0,0 +1,242 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Pairing default settings. Code must be long enough to resist guessing
+// during its lifetime
+const (
+	PairingTimeout  = 10 * time.Minute
+	PairingCodeSize = 8
+)
+
+// DeviceToken Device own credentials. Only token hash is stored
+type DeviceToken struct {
+	Hash    string
+	Device  string
+	Created time.Time
+}
+
+// PairingCode One-time code which is exchanged for device token
+type PairingCode struct {
+	Code    string
+	Device  string
+	Expires time.Time
+}
+
+// SetPairingTimeout Set pairing code lifetime
+func (a *Authorization) SetPairingTimeout(timeout time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.pairingTimeout = timeout
+}
+
+// CreatePairingCode Make one-time pairing code for device, previous
+// device code is replaced
+func (a *Authorization) CreatePairingCode(device string) (PairingCode, error) {
+	var code, err = randomHex(PairingCodeSize)
+	if err != nil {
+		return PairingCode{}, err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.removeExpiredCodes()
+	for c, pc := range a.codes {
+		if pc.Device == device {
+			delete(a.codes, c)
+		}
+	}
+
+	var pc = PairingCode{
+		Code:    code,
+		Device:  device,
+		Expires: time.Now().Add(a.pairingTimeout),
+	}
+	a.codes[code] = pc
+
+	return pc, nil
+}
+
+// Pair Exchange pairing code for new device token. Previous device
+// token is revoked
+func (a *Authorization) Pair(code string) (string, string, error) {
+	var token, err = randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.removeExpiredCodes()
+	var pc, ok = a.codes[code]
+	if !ok {
+		return "", "", errors.New("Wrong pairing code")
+	}
+	delete(a.codes, code)
+
+	a.revoke(pc.Device)
+	a.tokens[hashToken(token)] = DeviceToken{
+		Hash:    hashToken(token),
+		Device:  pc.Device,
+		Created: time.Now(),
+	}
+
+	return pc.Device, token, nil
+}
+
+// RestoreDeviceToken Add saved device token
+func (a *Authorization) RestoreDeviceToken(token DeviceToken) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.tokens[token.Hash] = token
+}
+
+// RevokeDevice Delete device token and pairing codes
+func (a *Authorization) RevokeDevice(device string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for c, pc := range a.codes {
+		if pc.Device == device {
+			delete(a.codes, c)
+		}
+	}
+
+	return a.revoke(device)
+}
+
+// DeviceTokens Get all device tokens sorted by device name
+func (a *Authorization) DeviceTokens() []DeviceToken {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	var tokens []DeviceToken
+
+	for _, token := range a.tokens {
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Device < tokens[j].Device
+	})
+
+	return tokens
+}
+
+// PairingCodes Get all active pairing codes sorted by device name
+func (a *Authorization) PairingCodes() []PairingCode {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	var codes []PairingCode
+	var now = time.Now()
+
+	for _, pc := range a.codes {
+		if now.Before(pc.Expires) {
+			codes = append(codes, pc)
+		}
+	}
+
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].Device < codes[j].Device
+	})
+
+	return codes
+}
+
+// ReportValidation Check device state can be reported by key. Users with
+// write rights and device own token can report
+func (a *Authorization) ReportValidation(key string, device string) bool {
+	var _, write = a.Validation(key, device)
+	if write {
+		return true
+	}
+
+	return a.isDeviceToken(key, device)
+}
+
+// TokenDevice Get device of device token
+func (a *Authorization) TokenDevice(key string) (string, bool) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	if len(a.tokens) == 0 {
+		return "", false
+	}
+
+	var token, ok = a.tokens[hashToken(key)]
+	return token.Device, ok
+}
+
+// isDeviceToken Check key is device own token
+func (a *Authorization) isDeviceToken(key string, device string) bool {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	if len(a.tokens) == 0 {
+		return false
+	}
+
+	var token, ok = a.tokens[hashToken(key)]
+	return ok && token.Device == device
+}
+
+func (a *Authorization) revoke(device string) bool {
+	var found bool
+
+	for hash, token := range a.tokens {
+		if token.Device == device {
+			delete(a.tokens, hash)
+			found = true
+		}
+	}
+
+	return found
+}
+
+func (a *Authorization) removeExpiredCodes() {
+	var now = time.Now()
+
+	for c, pc := range a.codes {
+		if !now.Before(pc.Expires) {
+			delete(a.codes, c)
+		}
+	}
+}
+
+func hashToken(token string) string {
+	var sum = sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	var buf = make([]byte, size)
+
+	var _, err = rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -10,17 +10,52 @@
 
 package auth
 
+import (
+	"sort"
+	"sync"
+)
+
+// ProfileChannel Access rights for single device channel
+type ProfileChannel struct {
+	channel int
+	read    bool
+	write   bool
+}
+
+func NewProfileChannel(channel int, read bool, write bool) *ProfileChannel {
+	return &ProfileChannel{
+		channel: channel,
+		read:    read,
+		write:   write,
+	}
+}
+
+func (p *ProfileChannel) Channel() int {
+	return p.channel
+}
+
+func (p *ProfileChannel) Read() bool {
+	return p.read
+}
+
+func (p *ProfileChannel) Write() bool {
+	return p.write
+}
+
 type ProfileDevice struct {
-	name  string
-	read  bool
-	write bool
+	mtx      sync.RWMutex
+	name     string
+	read     bool
+	write    bool
+	channels map[int]*ProfileChannel
 }
 
 func NewProfileDevice(name string, read bool, write bool) *ProfileDevice {
 	return &ProfileDevice{
-		name:  name,
-		read:  read,
-		write: write,
+		name:     name,
+		read:     read,
+		write:    write,
+		channels: make(map[int]*ProfileChannel),
 	}
 }
 
@@ -35,3 +70,41 @@ func (p *ProfileDevice) Read() bool {
 func (p *ProfileDevice) Write() bool {
 	return p.write
 }
+
+func (p *ProfileDevice) AddChannel(channel *ProfileChannel) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.channels[channel.Channel()] = channel
+}
+
+func (p *ProfileDevice) RemoveChannel(channel int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	delete(p.channels, channel)
+}
+
+func (p *ProfileDevice) Channel(channel int) *ProfileChannel {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	return p.channels[channel]
+}
+
+// Channels Get channels rights sorted by channel number
+func (p *ProfileDevice) Channels() []*ProfileChannel {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	var chans []*ProfileChannel
+
+	for _, ch := range p.channels {
+		chans = append(chans, ch)
+	}
+	sort.Slice(chans, func(i, j int) bool {
+		return chans[i].Channel() < chans[j].Channel()
+	})
+
+	return chans
+}
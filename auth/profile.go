@@ -10,7 +10,10 @@
 
 package auth
 
+import "sync"
+
 type Profile struct {
+	mtx     sync.RWMutex
 	name    string
 	admin   bool
 	key     string
@@ -27,23 +30,44 @@ func NewProfile(name string, key string, admin bool) *Profile {
 	}
 }
 
+// AddDevice Add device rights. Rights of device which was added before
+// are replaced with its channels rights
 func (p *Profile) AddDevice(device *ProfileDevice) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	p.devices[device.Name()] = device
 }
 
 func (p *Profile) AddGroup(grp string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	p.groups = append(p.groups, grp)
 }
 
-func (p *Profile) Groups() *[]string {
-	return &p.groups
+// Groups Get copy of profile groups
+func (p *Profile) Groups() []string {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	var grps = make([]string, len(p.groups))
+	copy(grps, p.groups)
+
+	return grps
 }
 
 func (p *Profile) Device(name string) *ProfileDevice {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
 	return p.devices[name]
 }
 
 func (p *Profile) Devices() []*ProfileDevice {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
 	var devs []*ProfileDevice
 
 	for _, dev := range p.devices {
@@ -54,10 +78,35 @@ func (p *Profile) Devices() []*ProfileDevice {
 }
 
 func (p *Profile) RemoveDevice(name string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	delete(p.devices, name)
 }
 
+// RenameDevice Move device rights to new device name
+func (p *Profile) RenameDevice(name string, newName string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var old = p.devices[name]
+	if old == nil {
+		return
+	}
+
+	var dev = NewProfileDevice(newName, old.Read(), old.Write())
+	for _, ch := range old.Channels() {
+		dev.AddChannel(ch)
+	}
+
+	delete(p.devices, name)
+	p.devices[newName] = dev
+}
+
 func (p *Profile) RemoveGroup(name string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	var grps []string
 
 	for _, grp := range p.groups {
@@ -65,8 +114,7 @@ func (p *Profile) RemoveGroup(name string) {
 			grps = append(grps, grp)
 		}
 	}
-	p.groups = make([]string, len(grps))
-	copy(p.groups, grps)
+	p.groups = grps
 }
 
 func (p *Profile) Name() string {
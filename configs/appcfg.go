@@ -25,7 +25,48 @@ type ServerCfg struct {
 	Port int
 }
 
+type WatchdogTypeCfg struct {
+	Type    string
+	Timeout int
+}
+
+type WatchdogCfg struct {
+	Interval int
+	Timeout  int
+	Types    []WatchdogTypeCfg
+}
+
+type ReconcileCfg struct {
+	Interval int
+	Deadline int
+}
+
+type FirmwareCfg struct {
+	Path string
+}
+
+type PairingCfg struct {
+	Timeout int
+}
+
+type SchedulerCfg struct {
+	Interval int
+	Lateness int
+}
+
+type LocationCfg struct {
+	Latitude  float64
+	Longitude float64
+	Timezone  string
+}
+
 type AppCfg struct {
-	Server ServerCfg
-	Db     DbCfg
+	Server    ServerCfg
+	Db        DbCfg
+	Watchdog  WatchdogCfg
+	Reconcile ReconcileCfg
+	Firmware  FirmwareCfg
+	Pairing   PairingCfg
+	Scheduler SchedulerCfg
+	Location  LocationCfg
 }
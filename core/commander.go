@@ -0,0 +1,744 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// Automation commands actions
+const (
+	ActionOn     = "on"
+	ActionOff    = "off"
+	ActionSwitch = "switch"
+	ActionLevel  = "level"
+	ActionScene  = "scene"
+)
+
+// ErrForbidden Device command is not allowed
+var ErrForbidden = errors.New("Authorization failed")
+
+// Allowed Check device channel write rights. Channel is -1 for
+// whole device
+type Allowed func(device string, channel int) bool
+
+// PendingError Turning on of interlock member which waits for other
+// members reporting off. Turning on is completed by commander
+type PendingError struct {
+	Err error
+}
+
+func (e *PendingError) Error() string {
+	return e.Err.Error()
+}
+
+// IsPending Check that turning on is pending
+func IsPending(err error) bool {
+	var _, ok = err.(*PendingError)
+	return ok
+}
+
+// ActionResult Result of single device command
+type ActionResult struct {
+	Device string
+	Err    error
+}
+
+// Commander Devices commands. Every status change from web API, groups
+// and automation is applied and saved here
+type Commander struct {
+	storage *Storage
+	rec     *Reconciler
+	scenes  *Scenes
+	locks   *Interlocks
+	events  *Events
+	log     *utils.Log
+
+	mtx     sync.Mutex
+	timers  *Timers
+	pending map[string]func() error
+	stop    chan struct{}
+	sub     *Subscription
+}
+
+// NewCommander Make new devices commander
+func NewCommander(s *Storage, r *Reconciler, sc *Scenes, il *Interlocks, e *Events, l *utils.Log) *Commander {
+	return &Commander{
+		storage: s,
+		rec:     r,
+		scenes:  sc,
+		locks:   il,
+		events:  e,
+		log:     l,
+		pending: make(map[string]func() error),
+	}
+}
+
+// setTimers Set relays timers. Timer is canceled when its relay is
+// switched off by any command
+func (c *Commander) setTimers(t *Timers) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.timers = t
+}
+
+// IsSwitchable Check device status can be set by commander
+func (c *Commander) IsSwitchable(dev devices.IDevice) bool {
+	switch dev.(type) {
+	case *base.Relay, *base.Light, *base.MultiRelay:
+		return true
+	}
+	return false
+}
+
+// SetStatus Set device desired status. All channels of multi relay
+// are set
+func (c *Commander) SetStatus(actor string, dev devices.IDevice, status bool) error {
+	switch d := dev.(type) {
+	case *base.Relay:
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+
+		return c.setRelay(actor, d, status)
+
+	case *base.MultiRelay:
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+
+		var on []int
+		for i := range d.Channels() {
+			if status {
+				on = append(on, i)
+			}
+		}
+		return c.setChannels(actor, d, on, func() error {
+			for _, ch := range d.Channels() {
+				ch.SetStatus(status)
+			}
+			return nil
+		})
+	}
+
+	return c.apply(actor, dev, func() error {
+		switch d := dev.(type) {
+		case *base.Light:
+			d.SetStatus(status)
+		default:
+			return errors.New("Device can't be switched")
+		}
+		return nil
+	})
+}
+
+// Switch Invert device desired status. All channels of multi relay
+// are inverted
+func (c *Commander) Switch(actor string, dev devices.IDevice) error {
+	if relay, ok := dev.(*base.Relay); ok {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+
+		return c.setRelay(actor, relay, relay.Mode() == base.RelayPulse || !relay.Status())
+	}
+
+	if relay, ok := dev.(*base.MultiRelay); ok {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+
+		var on []int
+		for i, ch := range relay.Channels() {
+			if !ch.Status() {
+				on = append(on, i)
+			}
+		}
+		return c.setChannels(actor, relay, on, func() error {
+			for _, ch := range relay.Channels() {
+				ch.Switch()
+			}
+			return nil
+		})
+	}
+
+	return c.apply(actor, dev, func() error {
+		switch d := dev.(type) {
+		case *base.Light:
+			d.Switch()
+		default:
+			return errors.New("Device can't be switched")
+		}
+		return nil
+	})
+}
+
+// SetMode Set relay mode and pulse width, new desired status is
+// published. Pulse relay can't be kept on by timer, so its timer is
+// canceled
+func (c *Commander) SetMode(actor string, relay *base.Relay, mode string, width time.Duration) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var err = c.apply(actor, relay, func() error {
+		relay.SetMode(mode, width)
+		return nil
+	})
+	if mode != base.RelayPulse {
+		return err
+	}
+
+	return c.cancelTimer(relay, err)
+}
+
+// SetChannelStatus Set multi relay channel desired status
+func (c *Commander) SetChannelStatus(actor string, relay *base.MultiRelay, num int, status bool) error {
+	var ch = relay.Channel(num)
+	if ch == nil {
+		return errors.New("Channel not found")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var on []int
+	if status {
+		on = append(on, num)
+	}
+	return c.setChannels(actor, relay, on, func() error {
+		ch.SetStatus(status)
+		return nil
+	})
+}
+
+// SwitchChannel Invert multi relay channel desired status
+func (c *Commander) SwitchChannel(actor string, relay *base.MultiRelay, num int) error {
+	var ch = relay.Channel(num)
+	if ch == nil {
+		return errors.New("Channel not found")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var on []int
+	if !ch.Status() {
+		on = append(on, num)
+	}
+	return c.setChannels(actor, relay, on, func() error {
+		ch.Switch()
+		return nil
+	})
+}
+
+// SetChannelDescription Set multi relay channel description
+func (c *Commander) SetChannelDescription(actor string, relay *base.MultiRelay, num int, desc string) error {
+	var ch = relay.Channel(num)
+	if ch == nil {
+		return errors.New("Channel not found")
+	}
+
+	return c.apply(actor, relay, func() error {
+		ch.SetDescription(desc)
+		return nil
+	})
+}
+
+// SetChannels Set multi relay board channels count. Interlocks and
+// rights of removed channels are forgotten
+func (c *Commander) SetChannels(actor string, relay *base.MultiRelay, count int) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var err = c.apply(actor, relay, func() error {
+		return relay.SetChannels(count)
+	})
+	if err != nil && !IsNotSaved(err) {
+		return err
+	}
+
+	var errRefs = c.storage.ForgetChannelReferences(relay.Name(), count)
+	if errRefs != nil {
+		return errRefs
+	}
+
+	return err
+}
+
+// SetLevel Set dimmer level with transition time
+func (c *Commander) SetLevel(actor string, dimmer *base.Dimmer, level int, duration time.Duration) error {
+	return c.apply(actor, dimmer, func() error {
+		dimmer.SetLevel(level, duration)
+		return nil
+	})
+}
+
+// StepLevel Change dimmer level relative to its target with transition
+// time, level is clamped by dimmer
+func (c *Commander) StepLevel(actor string, dimmer *base.Dimmer, delta int, duration time.Duration) error {
+	return c.apply(actor, dimmer, func() error {
+		dimmer.Step(delta, duration)
+		return nil
+	})
+}
+
+// Apply Set device target state. Multi relay channels which are not
+// allowed are skipped, ErrForbidden is returned if nothing is allowed
+func (c *Commander) Apply(actor string, action SceneAction, allowed Allowed) error {
+	var dev = c.storage.Device(action.Device)
+	if dev == nil {
+		return errors.New("Device not found")
+	}
+
+	switch d := dev.(type) {
+	case *base.Dimmer:
+		if !allowed(d.Name(), -1) {
+			return ErrForbidden
+		}
+		return c.SetLevel(actor, d, action.Level, 0)
+
+	case *base.MultiRelay:
+		var count int
+		for i := range d.Channels() {
+			if !allowed(d.Name(), i) {
+				continue
+			}
+			count++
+
+			var err = c.SetChannelStatus(actor, d, i, action.Status)
+			if err != nil {
+				return err
+			}
+		}
+		if count == 0 {
+			return ErrForbidden
+		}
+		return nil
+	}
+
+	if !allowed(dev.Name(), -1) {
+		return ErrForbidden
+	}
+	return c.SetStatus(actor, dev, action.Status)
+}
+
+// ActivateScene Apply all scene actions and publish scene event with
+// scene name when any action was applied
+func (c *Commander) ActivateScene(actor string, name string, allowed Allowed) ([]ActionResult, error) {
+	var scene, ok = c.scenes.Scene(name)
+	if !ok {
+		return nil, errors.New("Scene not found")
+	}
+
+	var results []ActionResult
+	var applied bool
+	for _, action := range scene.Actions {
+		var err = c.Apply(actor, action, allowed)
+		if err == nil {
+			applied = true
+		}
+		results = append(results, ActionResult{
+			Device: action.Device,
+			Err:    err,
+		})
+	}
+
+	if !applied {
+		c.log.Error("COMMANDER", "Scene \""+name+"\" not activated", "No actions applied")
+		return results, nil
+	}
+
+	c.log.Info("COMMANDER", "Scene \""+name+"\" activated")
+	c.events.Publish(Event{Type: EventScene, Actor: actor, Value: name})
+
+	return results, nil
+}
+
+// IsAction Check automation command action
+func IsAction(action string) bool {
+	switch action {
+	case ActionOn, ActionOff, ActionSwitch, ActionLevel, ActionScene:
+		return true
+	}
+	return false
+}
+
+// CheckTarget Check automation command target exists and supports action
+func (c *Commander) CheckTarget(target string, action string) error {
+	switch action {
+	case ActionScene:
+		if _, ok := c.scenes.Scene(target); !ok {
+			return errors.New("Scene not found")
+		}
+	case ActionLevel:
+		if _, ok := c.storage.Device(target).(*base.Dimmer); !ok {
+			return errors.New("Dimmer not found")
+		}
+	case ActionOn, ActionOff, ActionSwitch:
+		var dev = c.storage.Device(target)
+		if dev == nil {
+			return errors.New("Device not found")
+		}
+		if !c.IsSwitchable(dev) {
+			return errors.New("Device can't be switched")
+		}
+	default:
+		return errors.New("Unknown action")
+	}
+	return nil
+}
+
+// Execute Run automation command for device or scene without user
+// rights checking
+func (c *Commander) Execute(actor string, target string, action string, level int) error {
+	var allowed = func(string, int) bool { return true }
+
+	var err = c.CheckTarget(target, action)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case ActionOn, ActionOff:
+		return c.Apply(actor, SceneAction{Device: target, Status: action == ActionOn}, allowed)
+	case ActionLevel:
+		return c.Apply(actor, SceneAction{Device: target, Level: level}, allowed)
+	case ActionSwitch:
+		return c.Switch(actor, c.storage.Device(target))
+	}
+
+	_, err = c.ActivateScene(actor, target, allowed)
+	return err
+}
+
+// setRelay Set relay status with interlocks checking. Commander lock
+// must be held, so concurrent commands can't turn on interlocked relays
+func (c *Commander) setRelay(actor string, relay *base.Relay, status bool) error {
+	delete(c.pending, relay.Name())
+
+	if status {
+		var err = c.checkInterlocks(actor, []InterlockMember{{Device: relay.Name(), Channel: -1}})
+		if IsPending(err) {
+			c.pending[relay.Name()] = func() error {
+				return c.setRelay(actor, relay, true)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var err = c.apply(actor, relay, func() error {
+		relay.SetStatus(status)
+		return nil
+	})
+	if status {
+		return err
+	}
+
+	return c.cancelTimer(relay, err)
+}
+
+// cancelTimer Remove relay timer after change was applied and saved.
+// Timer of not saved change is kept, so timers retry switching off.
+// Commander lock must be held
+func (c *Commander) cancelTimer(relay *base.Relay, err error) error {
+	if c.timers == nil || err != nil {
+		return err
+	}
+
+	var _, errTimer = c.timers.Cancel(relay.Name())
+	if errTimer != nil {
+		return &SaveError{Err: errTimer}
+	}
+
+	return nil
+}
+
+// setChannels Change multi relay channels with interlocks checking of
+// channels which are turned on. Commander lock must be held
+func (c *Commander) setChannels(actor string, relay *base.MultiRelay, on []int, change func() error) error {
+	var members []InterlockMember
+	for _, num := range on {
+		members = append(members, InterlockMember{Device: relay.Name(), Channel: num})
+	}
+
+	delete(c.pending, relay.Name())
+
+	var err = c.checkInterlocks(actor, members)
+	if IsPending(err) {
+		c.pending[relay.Name()] = func() error {
+			return c.setChannels(actor, relay, on, change)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.apply(actor, relay, change)
+}
+
+// checkInterlocks Refuse turning on of interlock members while other
+// member is on or switch other members off by interlock policy. Forced
+// turning on is pending until switched off members report off, so
+// interlocked relays are never on together. Pending turning on of other
+// members is canceled
+func (c *Commander) checkInterlocks(actor string, members []InterlockMember) error {
+	var locks []Interlock
+	for _, lock := range c.locks.Interlocks() {
+		var count int
+		for _, m := range members {
+			if lock.index(m.Device, m.Channel) >= 0 {
+				count++
+			}
+		}
+		if count > 1 {
+			return errors.New("Interlock \"" + lock.Name + "\": members can't be turned on together")
+		}
+		if count == 1 {
+			locks = append(locks, lock)
+		}
+	}
+
+	// Refusing interlocks are checked before any relay is switched off
+	for _, lock := range locks {
+		if lock.Policy != InterlockRefuse {
+			continue
+		}
+		for _, other := range c.others(lock, members) {
+			var status, state = c.memberState(other)
+			if status {
+				return errors.New("Interlock \"" + lock.Name + "\": " + other.String() + " is on")
+			}
+			if state {
+				return errors.New("Interlock \"" + lock.Name + "\": " + other.String() + " is switching off")
+			}
+		}
+	}
+
+	var pending error
+	for _, lock := range locks {
+		if lock.Policy != InterlockForce {
+			continue
+		}
+		for _, other := range c.others(lock, members) {
+			var status, state = c.memberState(other)
+			if status {
+				var err = c.switchOff(actor, other)
+				if err != nil {
+					return err
+				}
+				c.log.Info("COMMANDER", "Interlock \""+lock.Name+"\" switched off "+other.String())
+			}
+			if state && pending == nil {
+				pending = &PendingError{
+					Err: errors.New("Interlock \"" + lock.Name + "\": " + other.String() + " is switching off"),
+				}
+			}
+		}
+	}
+
+	for _, lock := range locks {
+		for _, other := range c.others(lock, members) {
+			delete(c.pending, other.Device)
+		}
+	}
+
+	return pending
+}
+
+// Start Start completing of pending turning on in background. Every
+// state report rechecks all pending commands, so dropped event is
+// handled by next one
+func (c *Commander) Start() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.stop != nil {
+		return
+	}
+	c.stop = make(chan struct{})
+	c.sub = c.events.Subscribe(0, EventStateChanged, EventOffline)
+
+	go c.run(c.stop, c.sub.Events())
+}
+
+// Stop Stop completing of pending turning on
+func (c *Commander) Stop() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		c.events.Unsubscribe(c.sub)
+		c.stop = nil
+		c.sub = nil
+	}
+}
+
+// HandleEvent Complete pending turning on of interlock members when
+// device reports new state or goes offline
+func (c *Commander) HandleEvent(e Event) {
+	if e.Type != EventStateChanged && e.Type != EventOffline {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var list []string
+	for device := range c.pending {
+		list = append(list, device)
+	}
+	sort.Strings(list)
+
+	for _, device := range list {
+		var retry, ok = c.pending[device]
+		if !ok {
+			continue
+		}
+		delete(c.pending, device)
+
+		if c.storage.Device(device) == nil {
+			continue
+		}
+
+		var err = retry()
+		if IsPending(err) {
+			continue
+		}
+		if err != nil {
+			c.log.Error("COMMANDER", "Fail to turn on \""+device+"\"", err.Error())
+			continue
+		}
+		c.log.Info("COMMANDER", "Pending turning on \""+device+"\" is completed")
+	}
+}
+
+func (c *Commander) run(stop chan struct{}, events <-chan Event) {
+	for {
+		select {
+		case <-stop:
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			c.HandleEvent(e)
+		}
+	}
+}
+
+// others Get interlock members except members which are turned on
+func (c *Commander) others(lock Interlock, members []InterlockMember) []InterlockMember {
+	var list []InterlockMember
+
+	for _, m := range lock.Members {
+		var found bool
+		for _, on := range members {
+			if m == on {
+				found = true
+			}
+		}
+		if !found {
+			list = append(list, m)
+		}
+	}
+
+	return list
+}
+
+// memberState Get interlock member desired status and reported state.
+// State of offline device is stale, so it is ignored
+func (c *Commander) memberState(m InterlockMember) (bool, bool) {
+	switch d := c.storage.Device(m.Device).(type) {
+	case *base.Relay:
+		return d.Status(), d.State() && d.Online()
+	case *base.MultiRelay:
+		var ch = d.Channel(m.Channel)
+		if ch != nil {
+			return ch.Status(), ch.State() && d.Online()
+		}
+	}
+	return false, false
+}
+
+// switchOff Set interlock member desired status off
+func (c *Commander) switchOff(actor string, m InterlockMember) error {
+	switch d := c.storage.Device(m.Device).(type) {
+	case *base.Relay:
+		return c.setRelay(actor, d, false)
+	case *base.MultiRelay:
+		return c.apply(actor, d, func() error {
+			var ch = d.Channel(m.Channel)
+			if ch == nil {
+				return errors.New("Channel not found")
+			}
+
+			ch.SetStatus(false)
+			return nil
+		})
+	}
+	return errors.New("Relay not found")
+}
+
+// apply Change device, save its type database and publish requested
+// status. Status is published when change was applied but not saved
+func (c *Commander) apply(actor string, dev devices.IDevice, change func() error) error {
+	var err = c.storage.Commit(dev.Type(), change)
+
+	if relay, ok := dev.(*base.Relay); ok {
+		c.rec.CheckRelay(relay)
+	}
+
+	if err == nil || IsNotSaved(err) {
+		c.events.Publish(Event{
+			Type:   EventStatusRequested,
+			Actor:  actor,
+			Device: dev.Name(),
+			Value:  DesiredState(dev),
+		})
+	}
+
+	return err
+}
+
+// DesiredState Get device desired status in DeviceState format. Pulse
+// relay status is "pulse" with commands counter
+func DesiredState(dev devices.IDevice) string {
+	switch d := dev.(type) {
+	case *base.Relay:
+		if d.Mode() == base.RelayPulse {
+			return "pulse " + strconv.Itoa(d.Pulse())
+		}
+		return onOff(d.Status())
+	case *base.Light:
+		return onOff(d.Status())
+	case *base.Dimmer:
+		return strconv.Itoa(d.Target())
+	case *base.MultiRelay:
+		var states string
+		for _, ch := range d.Channels() {
+			if ch.Status() {
+				states += "1"
+			} else {
+				states += "0"
+			}
+		}
+		return states
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"testing"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+// newTestCommander Make commander with empty storage, scenes and
+// interlocks
+func newTestCommander(t *testing.T) (*Commander, *Storage, *Events) {
+	var log = newTestLog(t)
+	var storage = newTestStorage(log)
+	var events = NewEvents()
+	var cmd = NewCommander(storage, NewReconciler(storage, events, log), NewScenes(), NewInterlocks(), events, log)
+
+	return cmd, storage, events
+}
+
+func TestSwitchMultiRelay(t *testing.T) {
+	var cmd, storage, _ = newTestCommander(t)
+
+	var err = storage.AddDevice("board0", "Board", "multirelay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var board = storage.Device("board0").(*base.MultiRelay)
+	err = board.SetChannels(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Channel(1).SetStatus(true)
+
+	// Switch action inverts every channel
+	err = cmd.CheckTarget("board0", ActionSwitch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Execute(ActorRules, "board0", ActionSwitch, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !board.Channel(0).Status() || board.Channel(1).Status() {
+		t.Error("multi relay channels are not inverted")
+	}
+}
+
+func TestStepLevel(t *testing.T) {
+	var cmd, storage, events = newTestCommander(t)
+	var sub = events.Subscribe(0, EventStatusRequested)
+
+	var err = storage.AddDevice("dimmer0", "Dimmer", "dimmer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dimmer = storage.Device("dimmer0").(*base.Dimmer)
+
+	// Steps are clamped and published
+	for _, step := range []struct {
+		delta int
+		level string
+	}{{30, "30"}, {90, "100"}, {-150, "0"}} {
+		err = cmd.StepLevel(UserActor("admin"), dimmer, step.delta, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var event = nextEvent(t, sub)
+		if event.Device != "dimmer0" || event.Value != step.level || event.Actor != UserActor("admin") {
+			t.Error("wrong step event:", event)
+		}
+	}
+	noEvent(t, sub)
+}
+
+func TestSetChannels(t *testing.T) {
+	var cmd, storage, events = newTestCommander(t)
+	var sub = events.Subscribe(0, EventStatusRequested)
+	var saves int
+
+	storage.RegisterRefs("interlock", cmd.locks, func() error {
+		saves++
+		return nil
+	})
+
+	var err = storage.AddDevice("board0", "Board", "multirelay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var board = storage.Device("board0").(*base.MultiRelay)
+	err = cmd.SetChannels(UserActor("admin"), board, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, sub); event.Value != "0000" || saves != 0 {
+		t.Error("wrong channels event:", event, saves)
+	}
+
+	cmd.locks.Add("gates", InterlockRefuse)
+	cmd.locks.AddDevice("gates", "board0", 1)
+	cmd.locks.AddDevice("gates", "board0", 3)
+
+	// Interlocks of removed channels are forgotten
+	err = cmd.SetChannels(UserActor("admin"), board, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock, _ = cmd.locks.Interlock("gates")
+	if len(lock.Members) != 1 || lock.Members[0].Channel != 1 || saves != 1 {
+		t.Error("removed channel is kept in interlock:", lock.Members, saves)
+	}
+	if event := nextEvent(t, sub); event.Value != "00" {
+		t.Error("wrong channels event:", event)
+	}
+
+	// Wrong count changes nothing
+	err = cmd.SetChannels(UserActor("admin"), board, 0)
+	if err == nil || len(board.Channels()) != 2 || saves != 1 {
+		t.Error("wrong channels count is set:", err)
+	}
+	noEvent(t, sub)
+
+	// Channel description is published
+	err = cmd.SetChannelDescription(UserActor("admin"), board, 1, "Gate")
+	if err != nil || board.Channel(1).Description() != "Gate" {
+		t.Error("channel description is not set:", err)
+	}
+	nextEvent(t, sub)
+	err = cmd.SetChannelDescription(UserActor("admin"), board, 2, "Gate")
+	if err == nil {
+		t.Error("description of missing channel is set")
+	}
+}
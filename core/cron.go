@@ -0,0 +1,181 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronMaxYears Max years to search next cron time
+const CronMaxYears = 5
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron Parsed cron expression: minute, hour, day of month, month
+// and day of week. Fields support "*", lists, ranges and steps
+type Cron struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Day of month and day of week fields are "*"
+	domAny bool
+	dowAny bool
+}
+
+// ParseCron Parse five fields cron expression or macro like "@daily"
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+
+	var fields = strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("Cron expression must have 5 fields")
+	}
+
+	var c = &Cron{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	var err error
+
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+
+	// Sunday can be set as 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// Next Get first cron time after t. Zero time is returned if
+// there is no such time in CronMaxYears
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	var limit = t.AddDate(CronMaxYears, 0, 0)
+
+	for t.Before(limit) {
+		var year, month, day = t.Date()
+
+		if c.month&(1<<uint(month)) == 0 {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatch(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatch Check day of month and day of week. If both are
+// restricted, any of them must match like in classic cron
+func (c *Cron) dayMatch(t time.Time) bool {
+	var dom = c.dom&(1<<uint(t.Day())) != 0
+	var dow = c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseCronField Parse comma separated list of "*", "a", "a-b"
+// with optional "/step" to bit mask
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		var rng = part
+		var step = 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.New("Wrong cron step \"" + part + "\"")
+			}
+		}
+
+		var from, to = min, max
+		if rng != "*" {
+			var bounds = strings.SplitN(rng, "-", 2)
+			var err error
+
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, errors.New("Wrong cron value \"" + part + "\"")
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, errors.New("Wrong cron value \"" + part + "\"")
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, errors.New("Cron value \"" + part + "\" out of range")
+		}
+
+		for i := from; i <= to; i += step {
+			mask |= 1 << uint(i)
+		}
+	}
+
+	return mask, nil
+}
@@ -0,0 +1,47 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	var tests = []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 9-17 * * 1-5", time.Date(2021, 3, 1, 8, 50, 0, 0, time.UTC), time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2021, 3, 5, 17, 45, 0, 0, time.UTC), time.Date(2021, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 0", time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), time.Date(2021, 3, 7, 12, 0, 0, 0, time.UTC)},
+		{"30 6 * * 7", time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 7, 6, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 3, 1, 23, 10, 0, 0, time.UTC), time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		var cron, err = ParseCron(test.expr)
+		if err != nil {
+			t.Fatal(test.expr, err)
+		}
+		if next := cron.Next(test.from); !next.Equal(test.want) {
+			t.Error(test.expr, "next after", test.from, "is", next, "want", test.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Error("wrong cron expression is parsed:", expr)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// BinaryTypes Binary input device types with active and inactive state names
+var BinaryTypes = map[string][2]string{
+	"door":   {"open", "closed"},
+	"window": {"open", "closed"},
+	"motion": {"detected", "clear"},
+	"leak":   {"detected", "clear"},
+}
+
+type Binary struct {
+	devices.Device
+	mtx     sync.RWMutex
+	state   bool
+	known   bool
+	changed time.Time
+}
+
+func NewBinary(name string, desc string, devType string) *Binary {
+	var dev = &Binary{}
+
+	dev.SetName(name)
+	dev.SetDescription(desc)
+	dev.SetOnline(false)
+	dev.SetType(devType)
+
+	return dev
+}
+
+func (b *Binary) State() bool {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	return b.state
+}
+
+// StateName Get human readable state
+func (b *Binary) StateName() string {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	if !b.known {
+		return "unknown"
+	}
+	if b.state {
+		return BinaryTypes[b.Type()][0]
+	}
+	return BinaryTypes[b.Type()][1]
+}
+
+// Changed Get last transition time
+func (b *Binary) Changed() time.Time {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	return b.changed
+}
+
+// Update Set reported state and check state transition,
+// the first report counts as transition only for active state
+func (b *Binary) Update(state bool) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	var transition = (b.known && b.state != state) || (!b.known && state)
+
+	if !b.known || transition {
+		b.changed = time.Now()
+	}
+	b.state = state
+	b.known = true
+	b.SetOnline(true)
+
+	return transition
+}
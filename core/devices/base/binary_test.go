@@ -0,0 +1,42 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import "testing"
+
+func TestBinaryTransitions(t *testing.T) {
+	var door = NewBinary("door0", "Front door", "door")
+	if door.StateName() != "unknown" || !door.Changed().IsZero() {
+		t.Fatal("wrong new binary input:", door.StateName(), door.Changed())
+	}
+
+	// First inactive report is not a transition
+	if door.Update(false) {
+		t.Error("first closed report is transition")
+	}
+	if door.StateName() != "closed" || door.Changed().IsZero() || !door.Online() {
+		t.Error("wrong first report:", door.StateName(), door.Changed())
+	}
+
+	var changed = door.Changed()
+	if door.Update(false) || door.Changed() != changed {
+		t.Error("same state report is transition")
+	}
+	if !door.Update(true) || door.StateName() != "open" || door.Changed().Before(changed) {
+		t.Error("open report is not transition:", door.StateName())
+	}
+
+	// First active report is a transition
+	var leak = NewBinary("leak0", "Bath", "leak")
+	if !leak.Update(true) || leak.StateName() != "detected" {
+		t.Error("first detected report is not transition:", leak.StateName())
+	}
+}
@@ -0,0 +1,165 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// Dimmer levels range and transition tick
+const (
+	DimmerMinLevel       = 0
+	DimmerMaxLevel       = 100
+	DimmerTransitionTick = 200 * time.Millisecond
+)
+
+type Dimmer struct {
+	devices.Device
+	mtx    sync.Mutex
+	level  int
+	target int
+	state  int
+	stop   chan struct{}
+}
+
+func NewDimmer(name string, desc string) *Dimmer {
+	var dev = &Dimmer{}
+
+	dev.SetName(name)
+	dev.SetDescription(desc)
+	dev.SetOnline(false)
+	dev.SetType("dimmer")
+
+	return dev
+}
+
+// SetLevel Set desired level, spreading it over duration when it is not zero
+func (d *Dimmer) SetLevel(level int, duration time.Duration) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.setLevel(level, duration)
+}
+
+// Step Change desired level relative to the current target
+func (d *Dimmer) Step(delta int, duration time.Duration) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.setLevel(d.target+delta, duration)
+}
+
+// Level Current desired level
+func (d *Dimmer) Level() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return d.level
+}
+
+// Target Final desired level of the running transition
+func (d *Dimmer) Target() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return d.target
+}
+
+// Transition Check running transition
+func (d *Dimmer) Transition() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return d.stop != nil
+}
+
+func (d *Dimmer) SetState(value int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.state = value
+}
+
+func (d *Dimmer) State() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return d.state
+}
+
+func (d *Dimmer) Update(state int) {
+	d.SetState(state)
+	d.SetOnline(true)
+}
+
+func (d *Dimmer) setLevel(level int, duration time.Duration) {
+	if level < DimmerMinLevel {
+		level = DimmerMinLevel
+	}
+	if level > DimmerMaxLevel {
+		level = DimmerMaxLevel
+	}
+
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+	}
+	d.target = level
+
+	var diff = level - d.level
+	if diff < 0 {
+		diff = -diff
+	}
+	if duration <= 0 || diff == 0 {
+		d.level = level
+		return
+	}
+
+	var steps = int(duration / DimmerTransitionTick)
+	if steps < 1 {
+		steps = 1
+	}
+	if steps > diff {
+		steps = diff
+	}
+
+	d.stop = make(chan struct{})
+	go d.transition(d.level, level, steps, duration/time.Duration(steps), d.stop)
+}
+
+func (d *Dimmer) transition(from int, to int, steps int, interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		d.mtx.Lock()
+		select {
+		case <-stop:
+			d.mtx.Unlock()
+			return
+		default:
+		}
+		d.level = from + (to-from)*i/steps
+		if i == steps {
+			d.stop = nil
+		}
+		d.mtx.Unlock()
+	}
+}
@@ -0,0 +1,81 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"testing"
+	"time"
+)
+
+// waitLevel Wait for dimmer transition to reach level
+func waitLevel(t *testing.T, dimmer *Dimmer, level int) {
+	var deadline = time.Now().Add(5 * time.Second)
+	for dimmer.Level() != level || dimmer.Transition() {
+		if time.Now().After(deadline) {
+			t.Fatal("dimmer level is not reached:", dimmer.Level(), "want:", level)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDimmerTransitions(t *testing.T) {
+	var dimmer = NewDimmer("dimmer0", "Hall")
+
+	// Level without duration is set at once and clamped to range
+	dimmer.SetLevel(150, 0)
+	if dimmer.Level() != DimmerMaxLevel || dimmer.Target() != DimmerMaxLevel || dimmer.Transition() {
+		t.Error("wrong clamped level:", dimmer.Level(), dimmer.Target())
+	}
+	dimmer.Step(-130, 0)
+	if dimmer.Level() != DimmerMinLevel {
+		t.Error("wrong step level:", dimmer.Level())
+	}
+
+	// Transition goes to target by steps
+	dimmer.SetLevel(50, 4*DimmerTransitionTick)
+	if dimmer.Target() != 50 || !dimmer.Transition() {
+		t.Fatal("transition is not started:", dimmer.Target())
+	}
+	var levels = map[int]bool{}
+	for dimmer.Transition() {
+		levels[dimmer.Level()] = true
+		time.Sleep(DimmerTransitionTick / 4)
+	}
+	waitLevel(t, dimmer, 50)
+	if len(levels) < 2 {
+		t.Error("transition has no intermediate levels:", levels)
+	}
+
+	// New level stops running transition
+	dimmer.SetLevel(0, time.Minute)
+	dimmer.SetLevel(80, 0)
+	if dimmer.Transition() || dimmer.Level() != 80 {
+		t.Error("transition is not stopped:", dimmer.Level())
+	}
+	time.Sleep(2 * DimmerTransitionTick)
+	if dimmer.Level() != 80 {
+		t.Error("stopped transition changes level:", dimmer.Level())
+	}
+
+	// Step is relative to target of running transition
+	dimmer.SetLevel(20, 2*DimmerTransitionTick)
+	dimmer.Step(10, 2*DimmerTransitionTick)
+	if dimmer.Target() != 30 {
+		t.Error("wrong step target:", dimmer.Target())
+	}
+	waitLevel(t, dimmer, 30)
+
+	// Reported level doesn't change desired level
+	dimmer.Update(10)
+	if dimmer.State() != 10 || dimmer.Level() != 30 || !dimmer.Online() {
+		t.Error("wrong reported dimmer:", dimmer.State(), dimmer.Level())
+	}
+}
@@ -10,10 +10,15 @@
 
 package base
 
-import "github.com/futcity/controller/core/devices"
+import (
+	"sync"
+
+	"github.com/futcity/controller/core/devices"
+)
 
 type Light struct {
 	devices.Device
+	mtx      sync.RWMutex
 	status   bool
 	state    bool
 	updateDb func(name string, val bool)
@@ -33,26 +38,48 @@ func NewLight(name string, desc string, defStatus bool, updDb func(name string,
 }
 
 func (r *Light) SetStatus(value bool) {
+	r.mtx.Lock()
 	r.status = value
+	r.mtx.Unlock()
+
 	if r.updateDb != nil {
 		go r.updateDb(r.Name(), value)
 	}
 }
 
 func (r *Light) Status() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
 	return r.status
 }
 
 func (r *Light) SetState(value bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
 	r.state = value
 }
 
 func (r *Light) State() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
 	return r.state
 }
 
-func (r *Light) Switch() {
-	r.SetStatus(!r.Status())
+// Switch Invert status and get new value
+func (r *Light) Switch() bool {
+	r.mtx.Lock()
+	var status = !r.status
+	r.status = status
+	r.mtx.Unlock()
+
+	if r.updateDb != nil {
+		go r.updateDb(r.Name(), status)
+	}
+
+	return status
 }
 
 func (r *Light) Update(state bool) {
@@ -0,0 +1,53 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLight(t *testing.T) {
+	var saved = make(chan bool, 2)
+	var light = NewLight("light0", "Hall", true, func(name string, val bool) {
+		saved <- val
+	})
+
+	if light.Type() != "light" || !light.Status() || light.State() || light.Online() {
+		t.Fatal("wrong new light:", light.Type(), light.Status(), light.State(), light.Online())
+	}
+
+	// Desired status is saved by callback
+	var checkSaved = func(want bool) {
+		select {
+		case val := <-saved:
+			if val != want {
+				t.Error("wrong saved status:", val)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("light status is not saved")
+		}
+	}
+
+	light.Switch()
+	if light.Status() {
+		t.Error("light status is not switched")
+	}
+	checkSaved(false)
+	light.SetStatus(true)
+	checkSaved(true)
+
+	// Reported state doesn't change desired status
+	light.Update(false)
+	if light.State() || !light.Status() || !light.Online() {
+		t.Error("wrong reported light:", light.Status(), light.State(), light.Online())
+	}
+}
@@ -0,0 +1,195 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// Meter totals periods formats and max stored days
+const (
+	MeterDayFormat   = "2006-01-02"
+	MeterMonthFormat = "2006-01"
+	MeterMaxDays     = 92
+)
+
+// MeterTotal Consumed energy for single period
+type MeterTotal struct {
+	Period string
+	Energy float64
+}
+
+type Meter struct {
+	devices.Device
+	mtx     sync.RWMutex
+	voltage float64
+	current float64
+	power   float64
+	counter float64
+	known   bool
+	total   float64
+	daily   map[string]float64
+	monthly map[string]float64
+}
+
+func NewMeter(name string, desc string) *Meter {
+	var dev = &Meter{}
+
+	dev.SetName(name)
+	dev.SetDescription(desc)
+	dev.SetOnline(false)
+	dev.SetType("meter")
+	dev.daily = make(map[string]float64)
+	dev.monthly = make(map[string]float64)
+
+	return dev
+}
+
+func (m *Meter) Voltage() float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.voltage
+}
+
+func (m *Meter) Current() float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.current
+}
+
+func (m *Meter) Power() float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.power
+}
+
+// Counter Get last reported energy counter in kWh
+func (m *Meter) Counter() float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.counter
+}
+
+// Total Get energy consumed since meter was added in kWh
+func (m *Meter) Total() float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.total
+}
+
+// Restore Set saved counter and totals
+func (m *Meter) Restore(counter float64, total float64, daily []MeterTotal, monthly []MeterTotal) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.counter = counter
+	m.known = true
+	m.total = total
+
+	for _, day := range daily {
+		m.daily[day.Period] = day.Energy
+	}
+	for _, month := range monthly {
+		m.monthly[month.Period] = month.Energy
+	}
+}
+
+func (m *Meter) Daily() []MeterTotal {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.totals(m.daily)
+}
+
+func (m *Meter) Monthly() []MeterTotal {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.totals(m.monthly)
+}
+
+// Day Get energy consumed at day of time
+func (m *Meter) Day(t time.Time) float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.daily[t.Format(MeterDayFormat)]
+}
+
+// Month Get energy consumed at month of time
+func (m *Meter) Month(t time.Time) float64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.monthly[t.Format(MeterMonthFormat)]
+}
+
+// Update Set reported values and accumulate consumed energy,
+// counter less than previous one means meter reset after reboot
+func (m *Meter) Update(voltage float64, current float64, power float64, counter float64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var now = time.Now()
+	var delta float64
+
+	if m.known {
+		if counter >= m.counter {
+			delta = counter - m.counter
+		} else {
+			delta = counter
+		}
+	}
+
+	m.voltage = voltage
+	m.current = current
+	m.power = power
+	m.counter = counter
+	m.known = true
+
+	m.total += delta
+	m.daily[now.Format(MeterDayFormat)] += delta
+	m.monthly[now.Format(MeterMonthFormat)] += delta
+
+	var oldest = now.AddDate(0, 0, -MeterMaxDays).Format(MeterDayFormat)
+	for day := range m.daily {
+		if day < oldest {
+			delete(m.daily, day)
+		}
+	}
+
+	m.SetOnline(true)
+}
+
+func (m *Meter) totals(periods map[string]float64) []MeterTotal {
+	var list []MeterTotal
+
+	for period, energy := range periods {
+		list = append(list, MeterTotal{
+			Period: period,
+			Energy: energy,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Period < list[j].Period
+	})
+
+	return list
+}
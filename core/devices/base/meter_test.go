@@ -0,0 +1,71 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// near Compare energy values with rounding error
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMeterTotals(t *testing.T) {
+	var meter = NewMeter("meter0", "House")
+	var now = time.Now()
+
+	// First report only sets counter
+	meter.Update(230, 1, 230, 100)
+	if meter.Total() != 0 || meter.Day(now) != 0 || !meter.Online() {
+		t.Error("first report is counted:", meter.Total())
+	}
+
+	meter.Update(230, 2, 460, 101.5)
+	meter.Update(231, 2, 462, 102)
+	if !near(meter.Total(), 2) || !near(meter.Day(now), 2) || !near(meter.Month(now), 2) {
+		t.Error("wrong totals:", meter.Total(), meter.Day(now), meter.Month(now))
+	}
+	if meter.Voltage() != 231 || meter.Current() != 2 || meter.Power() != 462 || meter.Counter() != 102 {
+		t.Error("wrong reported values")
+	}
+
+	// Counter reset after reboot counts from zero
+	meter.Update(230, 1, 230, 0.5)
+	if !near(meter.Total(), 2.5) || !near(meter.Day(now), 2.5) {
+		t.Error("wrong totals after reset:", meter.Total(), meter.Day(now))
+	}
+
+	// Restored totals are kept and old days are removed on update
+	var old = now.AddDate(0, 0, -MeterMaxDays-1)
+	var lastMonth = now.AddDate(0, -1, 0)
+	meter.Restore(10, 50, []MeterTotal{
+		{Period: old.Format(MeterDayFormat), Energy: 7},
+		{Period: now.AddDate(0, 0, -1).Format(MeterDayFormat), Energy: 3},
+	}, []MeterTotal{
+		{Period: lastMonth.Format(MeterMonthFormat), Energy: 40},
+	})
+	meter.Update(230, 1, 230, 11)
+
+	var daily = meter.Daily()
+	if len(daily) != 2 || daily[0].Energy != 3 || !near(daily[1].Energy, 3.5) {
+		t.Error("wrong daily totals:", daily)
+	}
+	var monthly = meter.Monthly()
+	if len(monthly) != 2 || monthly[0].Energy != 40 || monthly[0].Period > monthly[1].Period {
+		t.Error("wrong monthly totals:", monthly)
+	}
+	if !near(meter.Total(), 51) {
+		t.Error("wrong restored total:", meter.Total())
+	}
+}
@@ -0,0 +1,158 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// MultiRelayMaxChannels Max channels count of multi-channel relay board
+const MultiRelayMaxChannels = 16
+
+type RelayChannel struct {
+	mtx    sync.RWMutex
+	desc   string
+	status bool
+	state  bool
+}
+
+func (c *RelayChannel) SetDescription(desc string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.desc = desc
+}
+
+func (c *RelayChannel) Description() string {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.desc
+}
+
+func (c *RelayChannel) SetStatus(value bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.status = value
+}
+
+func (c *RelayChannel) Status() bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.status
+}
+
+func (c *RelayChannel) SetState(value bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.state = value
+}
+
+func (c *RelayChannel) State() bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.state
+}
+
+// Switch Invert status and get new value
+func (c *RelayChannel) Switch() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.status = !c.status
+
+	return c.status
+}
+
+type MultiRelay struct {
+	devices.Device
+	mtx      sync.RWMutex
+	channels []*RelayChannel
+}
+
+// NewMultiRelay Make board without channels, channels count is set
+// by SetChannels
+func NewMultiRelay(name string, desc string) *MultiRelay {
+	var dev = &MultiRelay{}
+
+	dev.SetName(name)
+	dev.SetDescription(desc)
+	dev.SetOnline(false)
+	dev.SetType("multirelay")
+
+	return dev
+}
+
+// SetChannels Set board channels count. Kept channels don't lose their
+// status, state and description
+func (m *MultiRelay) SetChannels(count int) error {
+	if count < 1 || count > MultiRelayMaxChannels {
+		return errors.New("Wrong channels count")
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if count < len(m.channels) {
+		m.channels = m.channels[:count]
+	}
+	for len(m.channels) < count {
+		m.channels = append(m.channels, &RelayChannel{})
+	}
+
+	return nil
+}
+
+// Channel Get channel by number, channels are numbered from zero
+func (m *MultiRelay) Channel(num int) *RelayChannel {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if num < 0 || num >= len(m.channels) {
+		return nil
+	}
+	return m.channels[num]
+}
+
+// Channels Get copy of board channels list
+func (m *MultiRelay) Channels() []*RelayChannel {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	var chans = make([]*RelayChannel, len(m.channels))
+	copy(chans, m.channels)
+
+	return chans
+}
+
+// Update Set reported states of all channels
+func (m *MultiRelay) Update(states []bool) error {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if len(states) != len(m.channels) {
+		return errors.New("Wrong channels count")
+	}
+
+	for i, state := range states {
+		m.channels[i].SetState(state)
+	}
+	m.SetOnline(true)
+
+	return nil
+}
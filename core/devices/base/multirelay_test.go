@@ -0,0 +1,61 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import "testing"
+
+func TestMultiRelayChannels(t *testing.T) {
+	var board = NewMultiRelay("board0", "Garage")
+	if board.Type() != "multirelay" || len(board.Channels()) != 0 {
+		t.Fatal("wrong new board:", board.Type(), len(board.Channels()))
+	}
+
+	for _, count := range []int{0, -1, MultiRelayMaxChannels + 1} {
+		if board.SetChannels(count) == nil {
+			t.Error("wrong channels count is set:", count)
+		}
+	}
+
+	var err = board.SetChannels(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Channel(1).SetStatus(true)
+	board.Channel(1).SetDescription("Gate")
+	if board.Channel(4) != nil || board.Channel(-1) != nil {
+		t.Error("channel out of range is found")
+	}
+
+	// Board reports all channels at once
+	if board.Update([]bool{true, false}) == nil {
+		t.Error("wrong states count is reported")
+	}
+	err = board.Update([]bool{false, true, false, true})
+	if err != nil || !board.Channel(3).State() || board.Channel(0).State() || !board.Online() {
+		t.Error("wrong reported states:", err)
+	}
+
+	// Kept channels don't lose state when count is changed
+	err = board.SetChannels(2)
+	if err != nil || len(board.Channels()) != 2 {
+		t.Fatal("channels are not removed:", err, len(board.Channels()))
+	}
+	err = board.SetChannels(8)
+	if err != nil || len(board.Channels()) != 8 {
+		t.Fatal("channels are not added:", err, len(board.Channels()))
+	}
+	if !board.Channel(1).Status() || !board.Channel(1).State() || board.Channel(1).Description() != "Gate" {
+		t.Error("kept channel lost its state")
+	}
+	if board.Channel(3).State() {
+		t.Error("new channel has state of removed one")
+	}
+}
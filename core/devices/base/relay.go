@@ -11,13 +11,43 @@
 package base
 
 import (
+	"sync"
+	"time"
+
 	"github.com/futcity/controller/core/devices"
 )
 
+// Relay desired and reported states reconciliation
+const (
+	RelaySynced   = "synced"
+	RelayPending  = "pending"
+	RelayMismatch = "mismatch"
+)
+
+// Relay modes. Latching relay keeps status, pulse relay closes contact
+// for pulse width on every "on" command, inverted relay output is
+// active on low level
+const (
+	RelayLatching = "latching"
+	RelayPulse    = "pulse"
+	RelayInverted = "inverted"
+)
+
+// Relay pulse width limits
+const (
+	RelayPulseWidth = 500 * time.Millisecond
+	RelayPulseMax   = time.Minute
+)
+
 type Relay struct {
 	devices.Device
-	status bool
-	state  bool
+	mtx     sync.RWMutex
+	status  bool
+	state   bool
+	changed time.Time
+	mode    string
+	width   time.Duration
+	pulse   int
 }
 
 func NewRelay(name string, desc string) *Relay {
@@ -27,31 +57,169 @@ func NewRelay(name string, desc string) *Relay {
 	dev.SetDescription(desc)
 	dev.SetOnline(false)
 	dev.SetType("relay")
+	dev.mode = RelayLatching
+	dev.width = RelayPulseWidth
 
 	return dev
 }
 
+// IsRelayMode Check relay mode name
+func IsRelayMode(mode string) bool {
+	switch mode {
+	case RelayLatching, RelayPulse, RelayInverted:
+		return true
+	}
+	return false
+}
+
+// SetMode Set relay mode and pulse width, status of pulse relay is
+// reset
+func (r *Relay) SetMode(mode string, width time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if mode == RelayPulse && r.status {
+		r.status = false
+		r.changed = time.Now()
+	}
+	r.mode = mode
+	r.width = width
+}
+
+func (r *Relay) Mode() string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.mode
+}
+
+// PulseWidth Get contact closing time of pulse relay
+func (r *Relay) PulseWidth() time.Duration {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.width
+}
+
+// Pulse Get pulse relay commands counter. Device makes pulse when
+// counter is changed
+func (r *Relay) Pulse() int {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.pulse
+}
+
+// SetPulse Restore pulse relay commands counter
+func (r *Relay) SetPulse(pulse int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.pulse = pulse
+}
+
+// Output Get output level which device must set. Pulse relay output
+// is idle between pulses
+func (r *Relay) Output() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	switch r.mode {
+	case RelayPulse:
+		return false
+	case RelayInverted:
+		return !r.status
+	}
+	return r.status
+}
+
 func (r *Relay) SetStatus(value bool) {
-	r.status = value
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.setStatus(value)
 }
 
 func (r *Relay) Status() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
 	return r.status
 }
 
+// Changed Get last desired status change time
+func (r *Relay) Changed() time.Time {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.changed
+}
+
+// Sync Get reconciliation state, wrong state reported by device
+// later than deadline after status change is a mismatch
+func (r *Relay) Sync(deadline time.Duration) string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if r.state == r.status {
+		return RelaySynced
+	}
+	if r.LastSeen().After(r.changed.Add(deadline)) {
+		return RelayMismatch
+	}
+	return RelayPending
+}
+
 func (r *Relay) SetState(value bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
 	r.state = value
 }
 
 func (r *Relay) State() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
 	return r.state
 }
 
-func (r *Relay) Switch() {
-	r.SetStatus(!r.Status())
+// Switch Invert status and get new value. Pulse relay makes pulse
+func (r *Relay) Switch() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.mode == RelayPulse {
+		r.setStatus(true)
+	} else {
+		r.setStatus(!r.status)
+	}
+
+	return r.status
 }
 
-func (r *Relay) Update(state bool) {
-	r.SetState(state)
+// Update Set state by reported output level
+func (r *Relay) Update(level bool) {
+	r.mtx.Lock()
+	r.state = level != (r.mode == RelayInverted)
+	r.mtx.Unlock()
+
 	r.SetOnline(true)
 }
+
+// setStatus Set desired status. Pulse relay status is always off,
+// "on" command makes pulse
+func (r *Relay) setStatus(value bool) {
+	if r.mode == RelayPulse {
+		if value {
+			r.pulse++
+			r.changed = time.Now()
+		}
+		return
+	}
+
+	if r.status != value || r.changed.IsZero() {
+		r.changed = time.Now()
+	}
+	r.status = value
+}
@@ -0,0 +1,94 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// SensorHistorySize Max readings count in sensor history
+const SensorHistorySize = 1440
+
+// SensorData Single sensor reading
+type SensorData struct {
+	Time        time.Time
+	Temperature float64
+	Humidity    float64
+	Pressure    float64
+	HasPressure bool
+}
+
+type Sensor struct {
+	devices.Device
+	mtx     sync.RWMutex
+	data    SensorData
+	history []SensorData
+	pos     int
+}
+
+func NewSensor(name string, desc string) *Sensor {
+	var dev = &Sensor{}
+
+	dev.SetName(name)
+	dev.SetDescription(desc)
+	dev.SetOnline(false)
+	dev.SetType("sensor")
+	dev.history = make([]SensorData, 0, SensorHistorySize)
+
+	return dev
+}
+
+func (s *Sensor) Data() SensorData {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.data
+}
+
+// History Get readings from time range in chronological order
+func (s *Sensor) History(from time.Time, to time.Time) []SensorData {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var list []SensorData
+
+	for i := 0; i < len(s.history); i++ {
+		var data = s.history[(s.pos+i)%len(s.history)]
+		if data.Time.Before(from) || data.Time.After(to) {
+			continue
+		}
+		list = append(list, data)
+	}
+
+	return list
+}
+
+func (s *Sensor) Update(data SensorData) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if data.Time.IsZero() {
+		data.Time = time.Now()
+	}
+	s.data = data
+
+	if len(s.history) < SensorHistorySize {
+		s.history = append(s.history, data)
+	} else {
+		s.history[s.pos] = data
+		s.pos = (s.pos + 1) % SensorHistorySize
+	}
+
+	s.SetOnline(true)
+}
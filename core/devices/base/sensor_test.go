@@ -0,0 +1,58 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package base
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSensorHistory(t *testing.T) {
+	var sensor = NewSensor("sensor0", "Bath")
+	var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Readings are added to history and the last one is current
+	for i := 0; i < 10; i++ {
+		sensor.Update(SensorData{
+			Time:        start.Add(time.Duration(i) * time.Minute),
+			Temperature: float64(i),
+		})
+	}
+	if sensor.Data().Temperature != 9 || !sensor.Online() {
+		t.Error("wrong current reading:", sensor.Data())
+	}
+
+	var list = sensor.History(start.Add(2*time.Minute), start.Add(4*time.Minute))
+	if len(list) != 3 || list[0].Temperature != 2 || list[2].Temperature != 4 {
+		t.Error("wrong history range:", list)
+	}
+
+	// Zero reading time is set to now
+	sensor.Update(SensorData{Temperature: 20})
+	if sensor.Data().Time.IsZero() {
+		t.Error("reading time is not set")
+	}
+
+	// Oldest readings are replaced when history is full
+	for i := 0; i < SensorHistorySize; i++ {
+		sensor.Update(SensorData{
+			Time:        start.Add(time.Hour + time.Duration(i)*time.Minute),
+			Temperature: float64(100 + i),
+		})
+	}
+	list = sensor.History(start, start.Add(time.Hour*48))
+	if len(list) != SensorHistorySize {
+		t.Fatal("wrong history size:", len(list))
+	}
+	if list[0].Temperature != 100 || list[len(list)-1].Temperature != float64(100+SensorHistorySize-1) {
+		t.Error("history is not in chronological order:", list[0], list[len(list)-1])
+	}
+}
@@ -10,6 +10,12 @@
 
 package devices
 
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
 type IDevice interface {
 	ID() int
 	SetID(id int)
@@ -20,53 +26,284 @@ type IDevice interface {
 	SetDescription(key string)
 	Description() string
 	SetOnline(value bool)
+	SetOfflineIfStale(timeout time.Duration) bool
 	Online() bool
+	LastSeen() time.Time
+	Room() string
+	SetRoom(room string)
+	Tags() []string
+	HasTag(tag string) bool
+	AddTag(tag string)
+	RemoveTag(tag string)
+	Firmware() string
+	SetFirmware(version string)
+	IP() string
+	SetIP(ip string)
+	MAC() string
+	SetMAC(mac string)
+	Attribute(key string) (string, bool)
+	Attributes() map[string]string
+	SetAttribute(key string, value string)
+	RemoveAttribute(key string)
 }
 
 type Device struct {
-	id      int
-	name    string
-	online  bool
-	desc    string
-	devType string
+	id       int
+	name     string
+	online   bool
+	lastSeen time.Time
+	desc     string
+	devType  string
+	room     string
+	tags     []string
+	firmware string
+	ip       string
+	mac      string
+	attrs    map[string]string
+	mtx      sync.RWMutex
 }
 
 func (d *Device) ID() int {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
 	return d.id
 }
 
 func (d *Device) SetID(id int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	d.id = id
 }
 
 func (d *Device) Name() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
 	return d.name
 }
 
 func (d *Device) SetName(name string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	d.name = name
 }
 
 func (d *Device) Online() bool {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
 	return d.online
 }
 
+// SetOnline Set online status, online device is marked as seen now
 func (d *Device) SetOnline(value bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	d.online = value
+	if value {
+		d.lastSeen = time.Now()
+	}
+}
+
+// SetOfflineIfStale Set device offline if it was not seen during timeout.
+// Check and change are made under one lock, so concurrent report is not
+// lost
+func (d *Device) SetOfflineIfStale(timeout time.Duration) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if !d.online || time.Since(d.lastSeen) <= timeout {
+		return false
+	}
+
+	d.online = false
+	return true
+}
+
+// LastSeen Get time of last device report
+func (d *Device) LastSeen() time.Time {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.lastSeen
 }
 
 func (d *Device) Description() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
 	return d.desc
 }
 
 func (d *Device) SetDescription(desc string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	d.desc = desc
 }
 
 func (d *Device) Type() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
 	return d.devType
 }
 
 func (d *Device) SetType(value string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	d.devType = value
 }
+
+// Room Get device room or location
+func (d *Device) Room() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.room
+}
+
+func (d *Device) SetRoom(room string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.room = room
+}
+
+// Tags Get sorted copy of device tags
+func (d *Device) Tags() []string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var tags = make([]string, len(d.tags))
+	copy(tags, d.tags)
+	sort.Strings(tags)
+
+	return tags
+}
+
+func (d *Device) HasTag(tag string) bool {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	for _, t := range d.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag Add tag to device, existing tag is ignored
+func (d *Device) AddTag(tag string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, t := range d.tags {
+		if t == tag {
+			return
+		}
+	}
+	d.tags = append(d.tags, tag)
+}
+
+func (d *Device) RemoveTag(tag string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for i, t := range d.tags {
+		if t == tag {
+			d.tags = append(d.tags[:i], d.tags[i+1:]...)
+			return
+		}
+	}
+}
+
+// Firmware Get device reported firmware version
+func (d *Device) Firmware() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.firmware
+}
+
+func (d *Device) SetFirmware(version string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.firmware = version
+}
+
+// IP Get device reported IP address
+func (d *Device) IP() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.ip
+}
+
+func (d *Device) SetIP(ip string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.ip = ip
+}
+
+// MAC Get device reported MAC address
+func (d *Device) MAC() string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.mac
+}
+
+func (d *Device) SetMAC(mac string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.mac = mac
+}
+
+// Attribute Get custom attribute value
+func (d *Device) Attribute(key string) (string, bool) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var value, ok = d.attrs[key]
+	return value, ok
+}
+
+// Attributes Get copy of all custom attributes
+func (d *Device) Attributes() map[string]string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var attrs = make(map[string]string)
+	for key, value := range d.attrs {
+		attrs[key] = value
+	}
+
+	return attrs
+}
+
+func (d *Device) SetAttribute(key string, value string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.attrs == nil {
+		d.attrs = make(map[string]string)
+	}
+	d.attrs[key] = value
+}
+
+func (d *Device) RemoveAttribute(key string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	delete(d.attrs, key)
+}
@@ -0,0 +1,197 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Events default settings
+const (
+	EventsHistorySize = 4096
+	EventsBufferSize  = 256
+)
+
+// Event types. State event is published when device reports new state,
+// status event when new desired status is set by command
+const (
+	EventStateChanged    = "state"
+	EventOnline          = "online"
+	EventOffline         = "offline"
+	EventSync            = "sync"
+	EventFirmware        = "firmware"
+	EventScene           = "scene"
+	EventDeviceAdded     = "added"
+	EventDeviceRemoved   = "removed"
+	EventDeviceRenamed   = "renamed"
+	EventStatusRequested = "status"
+	EventProfile         = "profile"
+)
+
+// Events actors of controller modules. Users and devices actors are
+// made by UserActor and DeviceActor
+const (
+	ActorWatchdog   = "watchdog"
+	ActorReconciler = "reconciler"
+	ActorFirmware   = "firmware"
+	ActorScheduler  = "scheduler"
+	ActorRules      = "rules"
+	ActorTimers     = "timers"
+)
+
+// Event Single device or profile event
+type Event struct {
+	Time    time.Time
+	Type    string
+	Actor   string
+	Device  string
+	Profile string
+	Value   string
+}
+
+// Subscription Events subscriber with bounded buffer. Events are dropped
+// when buffer is full, so slow subscriber never blocks publisher
+type Subscription struct {
+	ch      chan Event
+	types   []string
+	dropped int64
+}
+
+// Events Events journal and subscribers
+type Events struct {
+	mtx    sync.Mutex
+	events []Event
+	pos    int
+	subs   []*Subscription
+}
+
+// NewEvents Make new events journal
+func NewEvents() *Events {
+	return &Events{
+		events: make([]Event, 0, EventsHistorySize),
+	}
+}
+
+// UserActor Make events actor of user profile
+func UserActor(profile string) string {
+	return "user:" + profile
+}
+
+// DeviceActor Make events actor of device reporting with own token
+func DeviceActor(device string) string {
+	return "device:" + device
+}
+
+// Subscribe Add new subscriber of events types, all events are received
+// if types are empty. Default buffer size is used if size is not positive
+func (e *Events) Subscribe(size int, types ...string) *Subscription {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if size <= 0 {
+		size = EventsBufferSize
+	}
+
+	var sub = &Subscription{
+		ch:    make(chan Event, size),
+		types: types,
+	}
+	e.subs = append(e.subs, sub)
+
+	return sub
+}
+
+// Unsubscribe Remove subscriber and close its channel
+func (e *Events) Unsubscribe(sub *Subscription) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for i, s := range e.subs {
+		if s == sub {
+			e.subs = append(e.subs[:i], e.subs[i+1:]...)
+			close(sub.ch)
+			return
+		}
+	}
+}
+
+// Publish Add new event to journal and send it to subscribers without
+// blocking. Zero event time is set to now
+func (e *Events) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if len(e.events) < EventsHistorySize {
+		e.events = append(e.events, event)
+	} else {
+		e.events[e.pos] = event
+		e.pos = (e.pos + 1) % EventsHistorySize
+	}
+
+	for _, sub := range e.subs {
+		if !sub.match(event.Type) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Events Get events from time range in chronological order
+func (e *Events) Events(from time.Time, to time.Time) []Event {
+	var list []Event
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for i := 0; i < len(e.events); i++ {
+		var event = e.events[(e.pos+i)%len(e.events)]
+		if event.Time.Before(from) || event.Time.After(to) {
+			continue
+		}
+		list = append(list, event)
+	}
+
+	return list
+}
+
+// Events Get subscriber events channel, it is closed on unsubscribe
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped Get count of events dropped because of full buffer
+func (s *Subscription) Dropped() int {
+	return int(atomic.LoadInt64(&s.dropped))
+}
+
+func (s *Subscription) match(typ string) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+
+	for _, t := range s.types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	var events = NewEvents()
+
+	// Slow subscriber doesn't block publisher
+	var slow = events.Subscribe(4)
+	var online = events.Subscribe(0, EventOnline)
+	for i := 0; i < 10; i++ {
+		events.Publish(Event{Type: EventOnline, Actor: ActorWatchdog, Device: "relay0"})
+	}
+	if len(slow.Events()) != 4 || slow.Dropped() != 6 {
+		t.Error("wrong bounded subscriber buffer:", len(slow.Events()), slow.Dropped())
+	}
+	events.Unsubscribe(slow)
+	for range slow.Events() {
+	}
+
+	// Subscriber gets only its types
+	events.Publish(Event{Type: EventOffline, Actor: ActorWatchdog, Device: "relay0"})
+	if len(online.Events()) != 10 || online.Dropped() != 0 {
+		t.Error("wrong filtered subscriber events:", len(online.Events()), online.Dropped())
+	}
+
+	// Journal keeps events in chronological order with time set
+	var from = time.Now()
+	events.Publish(Event{Type: EventSync, Actor: ActorReconciler, Device: "relay0", Value: "pending"})
+	events.Publish(Event{Type: EventSync, Actor: ActorReconciler, Device: "relay0", Value: "synced"})
+	var list = events.Events(from, time.Now())
+	if len(list) != 2 || list[0].Value != "pending" || list[1].Value != "synced" || list[0].Time.IsZero() {
+		t.Error("wrong events journal:", list)
+	}
+	if len(events.Events(time.Time{}, time.Now())) != 13 {
+		t.Error("wrong events journal size")
+	}
+}
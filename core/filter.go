@@ -0,0 +1,69 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"github.com/futcity/controller/core/devices"
+)
+
+// DeviceFilter Device list filter. Empty fields match any device
+type DeviceFilter struct {
+	Type       string
+	Room       string
+	Tags       []string
+	Firmware   string
+	IP         string
+	MAC        string
+	Online     *bool
+	Attributes map[string]string
+}
+
+// Match Check device matches all filter fields. Attribute with empty
+// value matches any device which has this attribute
+func (f *DeviceFilter) Match(dev devices.IDevice) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.Type != "" && dev.Type() != f.Type {
+		return false
+	}
+	if f.Room != "" && dev.Room() != f.Room {
+		return false
+	}
+	if f.Firmware != "" && dev.Firmware() != f.Firmware {
+		return false
+	}
+	if f.IP != "" && dev.IP() != f.IP {
+		return false
+	}
+	if f.MAC != "" && dev.MAC() != f.MAC {
+		return false
+	}
+	if f.Online != nil && dev.Online() != *f.Online {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !dev.HasTag(tag) {
+			return false
+		}
+	}
+
+	for key, value := range f.Attributes {
+		var attr, ok = dev.Attribute(key)
+		if !ok || (value != "" && attr != value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,379 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/utils"
+)
+
+// FirmwarePath Default firmware images directory
+const FirmwarePath = "firmware"
+
+// Firmware rollout states
+const (
+	RolloutAvailable   = "available"
+	RolloutDownloading = "downloading"
+	RolloutInstalled   = "installed"
+	RolloutFailed      = "failed"
+)
+
+// FirmwareImage Uploaded firmware binary info
+type FirmwareImage struct {
+	Type     string
+	Version  string
+	Size     int
+	MD5      string
+	SHA256   string
+	Uploaded time.Time
+}
+
+// FirmwareRollout Device firmware update progress
+type FirmwareRollout struct {
+	Device   int
+	From     string
+	Target   string
+	Reported string
+	State    string
+	Updated  time.Time
+}
+
+// Firmware Firmware images and devices updates tracker
+type Firmware struct {
+	storage *Storage
+	events  *Events
+	log     *utils.Log
+
+	mtx      sync.RWMutex
+	path     string
+	images   []FirmwareImage
+	rollouts map[int]*FirmwareRollout
+}
+
+// NewFirmware Make new firmware updates service
+func NewFirmware(s *Storage, e *Events, l *utils.Log) *Firmware {
+	return &Firmware{
+		storage:  s,
+		events:   e,
+		log:      l,
+		path:     FirmwarePath,
+		rollouts: make(map[int]*FirmwareRollout),
+	}
+}
+
+// SetPath Set firmware images directory
+func (f *Firmware) SetPath(path string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.path = path
+}
+
+// AddImage Save new firmware binary for device type
+func (f *Firmware) AddImage(devType string, version string, data []byte) (FirmwareImage, error) {
+	if !f.storage.IsType(devType) {
+		return FirmwareImage{}, errors.New("Unknown device type")
+	}
+	if version == "" || strings.ContainsAny(version, "/\\") {
+		return FirmwareImage{}, errors.New("Wrong firmware version")
+	}
+	if len(data) == 0 {
+		return FirmwareImage{}, errors.New("Empty firmware image")
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.image(devType, version) != nil {
+		return FirmwareImage{}, errors.New("Firmware already exists")
+	}
+
+	var md5sum = md5.Sum(data)
+	var sha256sum = sha256.Sum256(data)
+	var img = FirmwareImage{
+		Type:     devType,
+		Version:  version,
+		Size:     len(data),
+		MD5:      hex.EncodeToString(md5sum[:]),
+		SHA256:   hex.EncodeToString(sha256sum[:]),
+		Uploaded: time.Now(),
+	}
+
+	var err = os.MkdirAll(f.path, 0755)
+	if err != nil {
+		return FirmwareImage{}, err
+	}
+	err = ioutil.WriteFile(f.fileName(img), data, 0644)
+	if err != nil {
+		return FirmwareImage{}, err
+	}
+
+	f.images = append(f.images, img)
+	return img, nil
+}
+
+// RestoreImage Add saved firmware image info
+func (f *Firmware) RestoreImage(img FirmwareImage) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.image(img.Type, img.Version) == nil {
+		f.images = append(f.images, img)
+	}
+}
+
+// RemoveImage Delete firmware image and its binary
+func (f *Firmware) RemoveImage(devType string, version string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for i, img := range f.images {
+		if img.Type == devType && img.Version == version {
+			f.images = append(f.images[:i], f.images[i+1:]...)
+
+			var err = os.Remove(f.fileName(img))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+	}
+	return errors.New("Firmware not found")
+}
+
+// Images Get all firmware images sorted by type and version
+func (f *Firmware) Images() []FirmwareImage {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	var images = make([]FirmwareImage, len(f.images))
+	copy(images, f.images)
+
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Type != images[j].Type {
+			return images[i].Type < images[j].Type
+		}
+		return CompareVersions(images[i].Version, images[j].Version) < 0
+	})
+
+	return images
+}
+
+// Latest Get newest firmware image for device type
+func (f *Firmware) Latest(devType string) (FirmwareImage, bool) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.latest(devType)
+}
+
+// Check Store version reported by device and offer newer firmware
+func (f *Firmware) Check(dev devices.IDevice, version string) (FirmwareImage, bool) {
+	f.Report(dev, version)
+
+	f.mtx.Lock()
+	var state string
+	var img, ok = f.latest(dev.Type())
+	if ok && CompareVersions(img.Version, version) > 0 {
+		var rollout = f.rollouts[dev.ID()]
+		if rollout == nil || rollout.Target != img.Version || rollout.State == RolloutInstalled {
+			state = f.setRollout(dev, &FirmwareRollout{
+				Device:   dev.ID(),
+				From:     version,
+				Target:   img.Version,
+				Reported: version,
+			}, RolloutAvailable)
+		}
+	} else {
+		ok = false
+	}
+	f.mtx.Unlock()
+
+	f.publish(dev, state)
+
+	return img, ok
+}
+
+// Download Read newest firmware binary for device
+func (f *Firmware) Download(dev devices.IDevice) (FirmwareImage, []byte, error) {
+	f.mtx.Lock()
+	var img, ok = f.latest(dev.Type())
+	if !ok {
+		f.mtx.Unlock()
+		return FirmwareImage{}, nil, errors.New("Firmware not found")
+	}
+
+	var data, err = ioutil.ReadFile(f.fileName(img))
+	if err != nil {
+		f.mtx.Unlock()
+		return FirmwareImage{}, nil, err
+	}
+
+	var rollout = f.rollouts[dev.ID()]
+	if rollout == nil || rollout.Target != img.Version {
+		rollout = &FirmwareRollout{
+			Device:   dev.ID(),
+			From:     dev.Firmware(),
+			Target:   img.Version,
+			Reported: dev.Firmware(),
+		}
+	}
+	var state = f.setRollout(dev, rollout, RolloutDownloading)
+	f.mtx.Unlock()
+
+	f.publish(dev, state)
+
+	return img, data, nil
+}
+
+// Report Update device firmware version and its rollout state. Device
+// which downloaded firmware and reports other version is failed
+func (f *Firmware) Report(dev devices.IDevice, version string) {
+	dev.SetFirmware(version)
+
+	f.mtx.Lock()
+	var state string
+	var rollout = f.rollouts[dev.ID()]
+	if rollout != nil {
+		rollout.Reported = version
+
+		if version == rollout.Target && rollout.State != RolloutInstalled {
+			state = f.setRollout(dev, rollout, RolloutInstalled)
+		} else if version != rollout.Target && rollout.State == RolloutDownloading {
+			state = f.setRollout(dev, rollout, RolloutFailed)
+		}
+	}
+	f.mtx.Unlock()
+
+	f.publish(dev, state)
+}
+
+// RestoreRollout Add saved device rollout
+func (f *Firmware) RestoreRollout(rollout FirmwareRollout) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.rollouts[rollout.Device] = &rollout
+}
+
+// Rollouts Get all devices rollouts sorted by device ID
+func (f *Firmware) Rollouts() []FirmwareRollout {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	var rollouts []FirmwareRollout
+
+	for _, rollout := range f.rollouts {
+		rollouts = append(rollouts, *rollout)
+	}
+
+	sort.Slice(rollouts, func(i, j int) bool {
+		return rollouts[i].Device < rollouts[j].Device
+	})
+
+	return rollouts
+}
+
+func (f *Firmware) setRollout(dev devices.IDevice, rollout *FirmwareRollout, state string) string {
+	rollout.State = state
+	rollout.Updated = time.Now()
+	f.rollouts[dev.ID()] = rollout
+
+	f.log.Info("FIRMWARE", "Device \""+dev.Name()+"\" firmware \""+rollout.Target+"\" "+state)
+	return state
+}
+
+func (f *Firmware) publish(dev devices.IDevice, state string) {
+	if state != "" {
+		f.events.Publish(Event{Type: EventFirmware, Actor: ActorFirmware, Device: dev.Name(), Value: state})
+	}
+}
+
+func (f *Firmware) image(devType string, version string) *FirmwareImage {
+	for i := range f.images {
+		if f.images[i].Type == devType && f.images[i].Version == version {
+			return &f.images[i]
+		}
+	}
+	return nil
+}
+
+func (f *Firmware) latest(devType string) (FirmwareImage, bool) {
+	var latest FirmwareImage
+	var found bool
+
+	for _, img := range f.images {
+		if img.Type == devType && (!found || CompareVersions(img.Version, latest.Version) > 0) {
+			latest = img
+			found = true
+		}
+	}
+
+	return latest, found
+}
+
+func (f *Firmware) fileName(img FirmwareImage) string {
+	return filepath.Join(f.path, img.Type+"-"+img.Version+".bin")
+}
+
+// CompareVersions Compare dotted versions like "1.2.10", numeric parts
+// are compared as numbers. Returns -1, 0 or 1
+func CompareVersions(a string, b string) int {
+	var pa = strings.Split(strings.TrimPrefix(a, "v"), ".")
+	var pb = strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var sa, sb string
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+
+		var na, erra = strconv.Atoi(sa)
+		var nb, errb = strconv.Atoi(sb)
+		if sa == "" {
+			na, erra = 0, nil
+		}
+		if sb == "" {
+			nb, errb = 0, nil
+		}
+
+		switch {
+		case erra == nil && errb == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (erra != nil || errb != nil) && sa != sb:
+			if sa < sb {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,202 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Group Named ordered list of devices
+type Group struct {
+	Name    string
+	Devices []string
+}
+
+// Groups All devices groups
+type Groups struct {
+	mtx    sync.RWMutex
+	groups map[string]*Group
+}
+
+// NewGroups Make new groups list
+func NewGroups() *Groups {
+	return &Groups{
+		groups: make(map[string]*Group),
+	}
+}
+
+// Add Add new empty group
+func (g *Groups) Add(name string) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if name == "" {
+		return errors.New("Wrong group name")
+	}
+	if g.groups[name] != nil {
+		return errors.New("Group already exists")
+	}
+
+	g.groups[name] = &Group{Name: name}
+	return nil
+}
+
+// Remove Delete group
+func (g *Groups) Remove(name string) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.groups[name] == nil {
+		return errors.New("Group not found")
+	}
+
+	delete(g.groups, name)
+	return nil
+}
+
+// Group Get copy of group by name
+func (g *Groups) Group(name string) (Group, bool) {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	var grp = g.groups[name]
+	if grp == nil {
+		return Group{}, false
+	}
+
+	return grp.copy(), true
+}
+
+// Groups Get copy of all groups sorted by name
+func (g *Groups) Groups() []Group {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	var list []Group
+
+	for _, grp := range g.groups {
+		list = append(list, grp.copy())
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// AddDevice Add device to the end of group
+func (g *Groups) AddDevice(name string, device string) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var grp = g.groups[name]
+	if grp == nil {
+		return errors.New("Group not found")
+	}
+	if grp.index(device) >= 0 {
+		return errors.New("Device already in group")
+	}
+
+	grp.Devices = append(grp.Devices, device)
+	return nil
+}
+
+// MoveDevice Move group device to new position
+func (g *Groups) MoveDevice(name string, device string, pos int) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var grp = g.groups[name]
+	if grp == nil {
+		return errors.New("Group not found")
+	}
+
+	var i = grp.index(device)
+	if i < 0 {
+		return errors.New("Device not in group")
+	}
+	if pos < 0 || pos >= len(grp.Devices) {
+		return errors.New("Wrong device position")
+	}
+
+	grp.Devices = append(grp.Devices[:i], grp.Devices[i+1:]...)
+	grp.Devices = append(grp.Devices[:pos], append([]string{device}, grp.Devices[pos:]...)...)
+	return nil
+}
+
+// RemoveDevice Remove device from group
+func (g *Groups) RemoveDevice(name string, device string) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var grp = g.groups[name]
+	if grp == nil {
+		return errors.New("Group not found")
+	}
+
+	var i = grp.index(device)
+	if i < 0 {
+		return errors.New("Device not in group")
+	}
+
+	grp.Devices = append(grp.Devices[:i], grp.Devices[i+1:]...)
+	return nil
+}
+
+// RenameDevice Change device name in all groups
+func (g *Groups) RenameDevice(device string, newName string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	for _, grp := range g.groups {
+		var i = grp.index(device)
+		if i >= 0 {
+			grp.Devices[i] = newName
+		}
+	}
+}
+
+// ForgetDevice Remove device from all groups
+func (g *Groups) ForgetDevice(device string) bool {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var found bool
+
+	for _, grp := range g.groups {
+		var i = grp.index(device)
+		if i >= 0 {
+			grp.Devices = append(grp.Devices[:i], grp.Devices[i+1:]...)
+			found = true
+		}
+	}
+
+	return found
+}
+
+func (g *Group) index(device string) int {
+	for i, dev := range g.Devices {
+		if dev == device {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *Group) copy() Group {
+	var devs = make([]string, len(g.Devices))
+	copy(devs, g.Devices)
+
+	return Group{Name: g.Name, Devices: devs}
+}
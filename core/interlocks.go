@@ -0,0 +1,273 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Interlock policies. Relay turning on is refused while other member
+// is on or other members are switched off first. In both cases relay is
+// not turned on until other members report off, forced turning on is
+// completed when they report off. State of offline member is ignored
+const (
+	InterlockRefuse = "refuse"
+	InterlockForce  = "force"
+)
+
+// InterlockMember Interlocked relay or multi relay channel. Channel is
+// -1 for relay
+type InterlockMember struct {
+	Device  string
+	Channel int
+}
+
+// Interlock Relays and channels which must never be on together
+type Interlock struct {
+	Name    string
+	Policy  string
+	Members []InterlockMember
+}
+
+// Interlocks All interlock groups
+type Interlocks struct {
+	mtx   sync.RWMutex
+	locks map[string]*Interlock
+}
+
+// NewInterlocks Make new interlocks list
+func NewInterlocks() *Interlocks {
+	return &Interlocks{
+		locks: make(map[string]*Interlock),
+	}
+}
+
+// IsInterlockPolicy Check interlock policy name
+func IsInterlockPolicy(policy string) bool {
+	return policy == InterlockRefuse || policy == InterlockForce
+}
+
+// Add Add new empty interlock
+func (i *Interlocks) Add(name string, policy string) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if name == "" {
+		return errors.New("Wrong interlock name")
+	}
+	if !IsInterlockPolicy(policy) {
+		return errors.New("Unknown interlock policy")
+	}
+	if i.locks[name] != nil {
+		return errors.New("Interlock already exists")
+	}
+
+	i.locks[name] = &Interlock{Name: name, Policy: policy}
+	return nil
+}
+
+// Remove Delete interlock
+func (i *Interlocks) Remove(name string) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if i.locks[name] == nil {
+		return errors.New("Interlock not found")
+	}
+
+	delete(i.locks, name)
+	return nil
+}
+
+// SetPolicy Change interlock policy
+func (i *Interlocks) SetPolicy(name string, policy string) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var lock = i.locks[name]
+	if lock == nil {
+		return errors.New("Interlock not found")
+	}
+	if !IsInterlockPolicy(policy) {
+		return errors.New("Unknown interlock policy")
+	}
+
+	lock.Policy = policy
+	return nil
+}
+
+// AddDevice Add relay or multi relay channel to interlock. Channel is
+// -1 for relay
+func (i *Interlocks) AddDevice(name string, device string, channel int) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var lock = i.locks[name]
+	if lock == nil {
+		return errors.New("Interlock not found")
+	}
+	if lock.index(device, channel) >= 0 {
+		return errors.New("Device already in interlock")
+	}
+
+	lock.Members = append(lock.Members, InterlockMember{Device: device, Channel: channel})
+	return nil
+}
+
+// RemoveDevice Remove relay or multi relay channel from interlock
+func (i *Interlocks) RemoveDevice(name string, device string, channel int) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var lock = i.locks[name]
+	if lock == nil {
+		return errors.New("Interlock not found")
+	}
+
+	var n = lock.index(device, channel)
+	if n < 0 {
+		return errors.New("Device not in interlock")
+	}
+
+	lock.Members = append(lock.Members[:n], lock.Members[n+1:]...)
+	return nil
+}
+
+// Interlock Get copy of interlock by name
+func (i *Interlocks) Interlock(name string) (Interlock, bool) {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	var lock = i.locks[name]
+	if lock == nil {
+		return Interlock{}, false
+	}
+
+	return lock.copy(), true
+}
+
+// Interlocks Get copy of all interlocks sorted by name
+func (i *Interlocks) Interlocks() []Interlock {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	var list []Interlock
+
+	for _, lock := range i.locks {
+		list = append(list, lock.copy())
+	}
+
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].Name < list[b].Name
+	})
+
+	return list
+}
+
+// MemberInterlocks Get copy of all interlocks with relay or channel
+// sorted by name
+func (i *Interlocks) MemberInterlocks(member InterlockMember) []Interlock {
+	var list []Interlock
+
+	for _, lock := range i.Interlocks() {
+		if lock.index(member.Device, member.Channel) >= 0 {
+			list = append(list, lock)
+		}
+	}
+
+	return list
+}
+
+// RenameDevice Change device name in all interlocks
+func (i *Interlocks) RenameDevice(device string, newName string) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	for _, lock := range i.locks {
+		for n := range lock.Members {
+			if lock.Members[n].Device == device {
+				lock.Members[n].Device = newName
+			}
+		}
+	}
+}
+
+// ForgetDevice Remove device and its channels from all interlocks
+func (i *Interlocks) ForgetDevice(device string) bool {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var found bool
+
+	for _, lock := range i.locks {
+		var members []InterlockMember
+		for _, m := range lock.Members {
+			if m.Device == device {
+				found = true
+				continue
+			}
+			members = append(members, m)
+		}
+		lock.Members = members
+	}
+
+	return found
+}
+
+// ForgetChannels Remove multi relay channels from count and above from
+// all interlocks
+func (i *Interlocks) ForgetChannels(device string, count int) bool {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var found bool
+
+	for _, lock := range i.locks {
+		var members []InterlockMember
+		for _, m := range lock.Members {
+			if m.Device == device && m.Channel >= count {
+				found = true
+				continue
+			}
+			members = append(members, m)
+		}
+		lock.Members = members
+	}
+
+	return found
+}
+
+// String Get member name for messages
+func (m InterlockMember) String() string {
+	if m.Channel < 0 {
+		return "relay \"" + m.Device + "\""
+	}
+	return "relay \"" + m.Device + "\" channel " + strconv.Itoa(m.Channel)
+}
+
+func (l *Interlock) index(device string, channel int) int {
+	for i, m := range l.Members {
+		if m.Device == device && m.Channel == channel {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *Interlock) copy() Interlock {
+	var members = make([]InterlockMember, len(l.Members))
+	copy(members, l.Members)
+
+	return Interlock{Name: l.Name, Policy: l.Policy, Members: members}
+}
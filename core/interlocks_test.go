@@ -0,0 +1,209 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+func TestInterlocks(t *testing.T) {
+	var cmd, storage, _ = newTestCommander(t)
+	var sched = NewScheduler(cmd, NewSun(), newTestLog(t))
+
+	var relays []*base.Relay
+	for i := 0; i < 4; i++ {
+		var err = storage.AddDevice("relay"+strconv.Itoa(i), "Relay", "relay")
+		if err != nil {
+			t.Fatal(err)
+		}
+		relays = append(relays, storage.Device("relay"+strconv.Itoa(i)).(*base.Relay))
+	}
+
+	// Motor direction relays refuse, heater and cooler force
+	var locks = []struct {
+		name    string
+		policy  string
+		devices []string
+	}{
+		{"motor", InterlockRefuse, []string{"relay0", "relay1"}},
+		{"climate", InterlockForce, []string{"relay2", "relay3"}},
+	}
+	for _, lock := range locks {
+		var err = cmd.locks.Add(lock.name, lock.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dev := range lock.devices {
+			err = cmd.locks.AddDevice(lock.name, dev, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if cmd.locks.Add("motor", InterlockForce) == nil || cmd.locks.Add("pump", "wait") == nil {
+		t.Error("wrong interlock is added")
+	}
+
+	var err = cmd.SetStatus(UserActor("admin"), relays[0], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Switch(UserActor("admin"), relays[1])
+	if err == nil || !strings.Contains(err.Error(), "relay0") || relays[1].Status() {
+		t.Error("interlocked relay was turned on:", err)
+	}
+
+	// Turning off is never interlocked
+	err = cmd.SetStatus(UserActor("admin"), relays[0], false)
+	if err != nil || relays[0].Status() {
+		t.Error("interlocked relay was not turned off:", err)
+	}
+
+	cmd.SetStatus(UserActor("admin"), relays[2], true)
+	err = cmd.SetStatus(UserActor("admin"), relays[3], true)
+	if err != nil || relays[2].Status() || !relays[3].Status() {
+		t.Error("interlocked relay was not switched off:", err)
+	}
+
+	// Scheduled commands are checked too
+	cmd.SetStatus(UserActor("admin"), relays[0], true)
+	sched.Restore(Job{ID: 1, At: time.Now().Add(-time.Second), Target: "relay1", Action: ActionOn, Enabled: true})
+	sched.Restore(Job{ID: 2, At: time.Now().Add(-time.Second), Target: "relay2", Action: ActionOn, Enabled: true})
+	sched.Check(time.Now())
+	if relays[1].Status() {
+		t.Error("scheduled command turned on interlocked relay")
+	}
+	if !relays[2].Status() || relays[3].Status() {
+		t.Error("scheduled command did not force interlock")
+	}
+
+	// Forced relay is turned on only when other relay reports off
+	relays[2].Update(true)
+	err = cmd.SetStatus(UserActor("admin"), relays[3], true)
+	if err == nil || !strings.Contains(err.Error(), "switching off") || relays[2].Status() || relays[3].Status() {
+		t.Error("forced relay was turned on before other relay reported off:", err)
+	}
+	err = cmd.SetStatus(UserActor("admin"), relays[3], true)
+	if err == nil || relays[3].Status() {
+		t.Error("forced relay was turned on while other relay is pending:", err)
+	}
+	cmd.HandleEvent(Event{Type: EventStateChanged, Device: "relay1"})
+	if relays[3].Status() {
+		t.Error("forced relay was turned on by other device report")
+	}
+	relays[2].Update(false)
+	cmd.HandleEvent(Event{Type: EventStateChanged, Device: "relay2", Value: "off"})
+	if !relays[3].Status() {
+		t.Error("pending forced relay was not turned on")
+	}
+
+	// Newer command cancels pending turning on
+	relays[3].Update(true)
+	cmd.SetStatus(UserActor("admin"), relays[2], true)
+	cmd.SetStatus(UserActor("admin"), relays[2], false)
+	relays[3].Update(false)
+	cmd.HandleEvent(Event{Type: EventStateChanged, Device: "relay3", Value: "off"})
+	if relays[2].Status() {
+		t.Error("canceled pending relay was turned on")
+	}
+
+	// Refusing interlock waits for other relay report too
+	cmd.SetStatus(UserActor("admin"), relays[0], false)
+	relays[0].Update(true)
+	err = cmd.SetStatus(UserActor("admin"), relays[1], true)
+	if err == nil || relays[1].Status() {
+		t.Error("refused relay was turned on before other relay reported off:", err)
+	}
+
+	// State of offline relay is stale and doesn't block others
+	relays[0].SetOnline(false)
+	err = cmd.SetStatus(UserActor("admin"), relays[1], true)
+	if err != nil || !relays[1].Status() {
+		t.Error("relay was refused by offline relay state:", err)
+	}
+}
+
+func TestChannelInterlocks(t *testing.T) {
+	var cmd, storage, _ = newTestCommander(t)
+
+	var err = storage.AddDevice("relay0", "Pump", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.AddDevice("board0", "Board", "multirelay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relay = storage.Device("relay0").(*base.Relay)
+	var board = storage.Device("board0").(*base.MultiRelay)
+	err = board.SetChannels(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Motor direction channels refuse, pump relay forces off channel 2
+	cmd.locks.Add("motor", InterlockRefuse)
+	cmd.locks.AddDevice("motor", "board0", 0)
+	cmd.locks.AddDevice("motor", "board0", 1)
+	cmd.locks.Add("pump", InterlockForce)
+	cmd.locks.AddDevice("pump", "relay0", -1)
+	cmd.locks.AddDevice("pump", "board0", 2)
+
+	err = cmd.SetChannelStatus(UserActor("admin"), board, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.SwitchChannel(UserActor("admin"), board, 1)
+	if err == nil || !strings.Contains(err.Error(), "channel 0") || board.Channel(1).Status() {
+		t.Error("interlocked channel was turned on:", err)
+	}
+
+	// Channels of one interlock can't be turned on together
+	cmd.SetChannelStatus(UserActor("admin"), board, 0, false)
+	err = cmd.SetStatus(UserActor("admin"), board, true)
+	if err == nil || board.Channel(0).Status() || board.Channel(1).Status() {
+		t.Error("interlocked channels were turned on together:", err)
+	}
+	err = cmd.Switch(UserActor("admin"), board)
+	if err == nil || board.Channel(0).Status() {
+		t.Error("interlocked channels were switched on together:", err)
+	}
+
+	// Relay forces channel off and channel forces relay off
+	cmd.SetChannelStatus(UserActor("admin"), board, 2, true)
+	err = cmd.SetStatus(UserActor("admin"), relay, true)
+	if err != nil || !relay.Status() || board.Channel(2).Status() {
+		t.Error("interlocked channel was not switched off:", err)
+	}
+	err = cmd.SetChannelStatus(UserActor("admin"), board, 2, true)
+	if err != nil || relay.Status() || !board.Channel(2).Status() {
+		t.Error("interlocked relay was not switched off:", err)
+	}
+
+	// Renamed and removed boards are followed
+	cmd.locks.RenameDevice("board0", "board1")
+	var lock, _ = cmd.locks.Interlock("pump")
+	if len(lock.Members) != 2 || lock.Members[1] != (InterlockMember{Device: "board1", Channel: 2}) {
+		t.Error("board channel is not renamed:", lock.Members)
+	}
+	if !cmd.locks.ForgetDevice("board1") {
+		t.Error("board is not forgotten")
+	}
+	lock, _ = cmd.locks.Interlock("motor")
+	if len(lock.Members) != 0 {
+		t.Error("board channels are kept:", lock.Members)
+	}
+}
@@ -0,0 +1,130 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// Reconciler default settings
+const (
+	ReconcileInterval = time.Second
+	ReconcileDeadline = 10 * time.Second
+)
+
+// Reconciler Relays desired and reported states tracker
+type Reconciler struct {
+	storage *Storage
+	events  *Events
+	log     *utils.Log
+
+	mtx      sync.Mutex
+	deadline time.Duration
+	syncs    map[int]string
+	stop     chan struct{}
+}
+
+// NewReconciler Make new relays reconciler
+func NewReconciler(s *Storage, e *Events, l *utils.Log) *Reconciler {
+	return &Reconciler{
+		storage:  s,
+		events:   e,
+		log:      l,
+		deadline: ReconcileDeadline,
+		syncs:    make(map[int]string),
+	}
+}
+
+// SetDeadline Set max time for device to report desired status
+func (r *Reconciler) SetDeadline(deadline time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.deadline = deadline
+}
+
+// Sync Get relay reconciliation state
+func (r *Reconciler) Sync(relay *base.Relay) string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return relay.Sync(r.deadline)
+}
+
+// Start Start relays checking in background
+func (r *Reconciler) Start(interval time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+
+	go r.run(interval, r.stop)
+}
+
+// Stop Stop relays checking
+func (r *Reconciler) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// Check Publish all relays reconciliation state transitions
+func (r *Reconciler) Check() {
+	for _, dev := range r.storage.DevicesByType("relay") {
+		r.CheckRelay(dev.(*base.Relay))
+	}
+}
+
+// CheckRelay Publish relay reconciliation state transition
+func (r *Reconciler) CheckRelay(relay *base.Relay) {
+	r.mtx.Lock()
+	var sync = relay.Sync(r.deadline)
+	var old, ok = r.syncs[relay.ID()]
+	if !ok {
+		old = base.RelaySynced
+	}
+	r.syncs[relay.ID()] = sync
+	r.mtx.Unlock()
+
+	if sync == old {
+		return
+	}
+
+	if sync == base.RelayMismatch {
+		r.log.Error("RECONCILER", "Relay \""+relay.Name()+"\" state mismatch", "Device reports wrong state")
+	}
+	r.events.Publish(Event{Type: EventSync, Actor: ActorReconciler, Device: relay.Name(), Value: sync})
+}
+
+func (r *Reconciler) run(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.Check()
+		}
+	}
+}
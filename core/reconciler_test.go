@@ -0,0 +1,112 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// newTestLog Make log writing to temporary directory
+func newTestLog(t *testing.T) *utils.Log {
+	var dir, err = ioutil.TempDir("", "futcity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	var log = utils.NewLog()
+	log.SetPath(dir + string(os.PathSeparator))
+
+	return log
+}
+
+// nextEvent Get published event without waiting
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	select {
+	case event := <-sub.Events():
+		return event
+	default:
+		t.Fatal("event is not published")
+	}
+	return Event{}
+}
+
+// noEvent Check that nothing was published
+func noEvent(t *testing.T, sub *Subscription) {
+	select {
+	case event := <-sub.Events():
+		t.Fatal("unexpected event:", event)
+	default:
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	var log = newTestLog(t)
+	var storage = newTestStorage(log)
+	var events = NewEvents()
+	var sub = events.Subscribe(0, EventSync)
+	var rec = NewReconciler(storage, events, log)
+
+	var err = storage.AddDevice("relay0", "Relay", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relay = storage.Device("relay0").(*base.Relay)
+	rec.SetDeadline(20 * time.Millisecond)
+
+	// Synced relay is not reported
+	rec.Check()
+	noEvent(t, sub)
+
+	// Changed status waits for device report
+	relay.SetStatus(true)
+	rec.Check()
+	var event = nextEvent(t, sub)
+	if event.Device != "relay0" || event.Value != base.RelayPending || event.Actor != ActorReconciler {
+		t.Error("wrong pending event:", event)
+	}
+	rec.Check()
+	noEvent(t, sub)
+
+	// Early wrong report is still pending
+	relay.Update(false)
+	rec.Check()
+	noEvent(t, sub)
+	if rec.Sync(relay) != base.RelayPending {
+		t.Error("early report is not pending:", rec.Sync(relay))
+	}
+
+	// Wrong report after deadline is a mismatch
+	time.Sleep(30 * time.Millisecond)
+	relay.Update(false)
+	rec.CheckRelay(relay)
+	event = nextEvent(t, sub)
+	if event.Value != base.RelayMismatch {
+		t.Error("wrong mismatch event:", event)
+	}
+
+	// Right report makes relay synced
+	relay.Update(true)
+	rec.CheckRelay(relay)
+	event = nextEvent(t, sub)
+	if event.Value != base.RelaySynced || rec.Sync(relay) != base.RelaySynced {
+		t.Error("wrong synced event:", event)
+	}
+	noEvent(t, sub)
+}
@@ -0,0 +1,226 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/futcity/controller/core/devices"
+)
+
+// DeviceFactory Make new device of registered type
+type DeviceFactory func(name string, desc string) devices.IDevice
+
+// DeviceType Registered device type
+type DeviceType struct {
+	Name    string
+	Factory DeviceFactory
+	Load    func() error
+	Save    func() error
+	mtx     sync.Mutex
+}
+
+// SaveError Database saving error of change which is already applied
+type SaveError struct {
+	Err error
+}
+
+func (e *SaveError) Error() string {
+	return "Change is applied but not saved: " + e.Err.Error()
+}
+
+// IsNotSaved Check that change was applied but its database was not saved
+func IsNotSaved(err error) bool {
+	var _, ok = err.(*SaveError)
+	return ok
+}
+
+// DeviceRefs Keeper of device references in own database
+type DeviceRefs interface {
+	RenameDevice(device string, newName string)
+	ForgetDevice(device string) bool
+}
+
+// ChannelRefs Keeper of multi relay channels references
+type ChannelRefs interface {
+	ForgetChannels(device string, count int) bool
+}
+
+// deviceRefs Registered device references keeper
+type deviceRefs struct {
+	name string
+	refs DeviceRefs
+	save func() error
+}
+
+// RegisterType Register new device type factory
+func (s *Storage) RegisterType(devType string, factory DeviceFactory) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.types[devType] = &DeviceType{
+		Name:    devType,
+		Factory: factory,
+	}
+}
+
+// RegisterBase Register device type persistence hooks
+func (s *Storage) RegisterBase(devType string, load func() error, save func() error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var typ = s.types[devType]
+	if typ == nil {
+		return errors.New("Unknown device type")
+	}
+
+	typ.Load = load
+	typ.Save = save
+
+	return nil
+}
+
+// RegisterRefs Register keeper of device references, its database is
+// saved when multi relay channels are removed
+func (s *Storage) RegisterRefs(name string, refs DeviceRefs, save func() error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.refs = append(s.refs, deviceRefs{
+		name: name,
+		refs: refs,
+		save: save,
+	})
+}
+
+// RenameReferences Change device name in all references keepers
+func (s *Storage) RenameReferences(device string, newName string) {
+	for _, r := range s.references() {
+		r.refs.RenameDevice(device, newName)
+	}
+}
+
+// ForgetReferences Remove removed device from all references keepers
+func (s *Storage) ForgetReferences(device string) {
+	for _, r := range s.references() {
+		r.refs.ForgetDevice(device)
+	}
+}
+
+// ForgetChannelReferences Remove multi relay channels from count and
+// above from references keepers and save changed databases
+func (s *Storage) ForgetChannelReferences(device string, count int) error {
+	for _, r := range s.references() {
+		var refs, ok = r.refs.(ChannelRefs)
+		if !ok || !refs.ForgetChannels(device, count) {
+			continue
+		}
+
+		var err = r.save()
+		if err != nil {
+			return errors.New("Fail to save \"" + r.name + "\" database: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Types Get all registered device types
+func (s *Storage) Types() []string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var list []string
+
+	for name := range s.types {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// IsType Check device type registration
+func (s *Storage) IsType(devType string) bool {
+	return s.deviceType(devType) != nil
+}
+
+// LoadBases Load all device types databases
+func (s *Storage) LoadBases() error {
+	for _, name := range s.Types() {
+		var typ = s.deviceType(name)
+		if typ.Load == nil {
+			continue
+		}
+
+		var err = typ.Load()
+		if err != nil {
+			return errors.New("Fail to load \"" + name + "\" database: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// SaveBase Save device type database
+func (s *Storage) SaveBase(devType string) error {
+	return s.Commit(devType, nil)
+}
+
+// Commit Apply device change and save device type database atomically,
+// changes of the same device type are serialized. Applied change is kept
+// when saving fails and SaveError is returned
+func (s *Storage) Commit(devType string, change func() error) error {
+	var typ = s.deviceType(devType)
+	if typ == nil {
+		return errors.New("Unknown device type")
+	}
+
+	typ.mtx.Lock()
+	defer typ.mtx.Unlock()
+
+	if change != nil {
+		var err = change()
+		if err != nil {
+			return err
+		}
+	}
+
+	if typ.Save == nil {
+		return nil
+	}
+
+	var err = typ.Save()
+	if err != nil {
+		return &SaveError{Err: err}
+	}
+
+	return nil
+}
+
+func (s *Storage) references() []deviceRefs {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var list = make([]deviceRefs, len(s.refs))
+	copy(list, s.refs)
+
+	return list
+}
+
+func (s *Storage) deviceType(devType string) *DeviceType {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.types[devType]
+}
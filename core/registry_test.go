@@ -0,0 +1,170 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// newTestStorage Make storage with relay, light, dimmer and multi relay
+// types
+func newTestStorage(log *utils.Log) *Storage {
+	var storage = NewStorage(log)
+
+	storage.RegisterType("relay", func(name string, desc string) devices.IDevice {
+		return base.NewRelay(name, desc)
+	})
+	storage.RegisterType("light", func(name string, desc string) devices.IDevice {
+		return base.NewLight(name, desc, false, nil)
+	})
+	storage.RegisterType("dimmer", func(name string, desc string) devices.IDevice {
+		return base.NewDimmer(name, desc)
+	})
+	storage.RegisterType("multirelay", func(name string, desc string) devices.IDevice {
+		return base.NewMultiRelay(name, desc)
+	})
+
+	return storage
+}
+
+func TestRegistry(t *testing.T) {
+	var storage = NewStorage(newTestLog(t))
+
+	// Unknown types are rejected
+	var err = storage.AddDevice("lamp0", "Lamp", "lamp")
+	if err == nil {
+		t.Error("device of unknown type is added")
+	}
+	err = storage.RegisterBase("lamp", nil, nil)
+	if err == nil {
+		t.Error("base of unknown type is registered")
+	}
+
+	// Registered type makes devices by factory
+	storage.RegisterType("lamp", func(name string, desc string) devices.IDevice {
+		return base.NewLight(name, desc, false, nil)
+	})
+	if !storage.IsType("lamp") {
+		t.Fatal("type is not registered")
+	}
+	err = storage.AddDevice("lamp0", "Lamp", "lamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lamp, ok = storage.Device("lamp0").(*base.Light)
+	if !ok || lamp.Type() != "light" || lamp.Description() != "Lamp" || lamp.ID() != 1 {
+		t.Error("wrong device made by factory:", storage.Device("lamp0"))
+	}
+	err = storage.AddDevice("lamp0", "Lamp", "lamp")
+	if err == nil {
+		t.Error("device with same name is added")
+	}
+
+	// Bases are loaded and saved by hooks
+	var loads, saves int
+	var fail = errors.New("disk is full")
+	err = storage.RegisterBase("lamp", func() error {
+		loads++
+		return nil
+	}, func() error {
+		saves++
+		if saves > 1 {
+			return fail
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.LoadBases()
+	if err != nil || loads != 1 {
+		t.Error("base is not loaded:", err, loads)
+	}
+
+	err = storage.Commit("lamp", func() error {
+		lamp.SetStatus(true)
+		return nil
+	})
+	if err != nil || saves != 1 || !lamp.Status() {
+		t.Error("change is not committed:", err, saves)
+	}
+
+	// Failed change is not saved
+	err = storage.Commit("lamp", func() error {
+		return errors.New("wrong change")
+	})
+	if err == nil || saves != 1 {
+		t.Error("failed change is saved:", err, saves)
+	}
+
+	// Change is kept when saving fails
+	err = storage.Commit("lamp", func() error {
+		lamp.SetStatus(false)
+		return nil
+	})
+	if !IsNotSaved(err) || err.(*SaveError).Err != fail || lamp.Status() {
+		t.Error("save error is not returned:", err)
+	}
+	if IsNotSaved(fail) {
+		t.Error("other error is save error")
+	}
+
+	// Types are listed sorted
+	var types = storage.Types()
+	for i := 1; i < len(types); i++ {
+		if types[i-1] > types[i] {
+			t.Fatal("types are not sorted:", types)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	var storage = NewStorage(newTestLog(t))
+	var groups = NewGroups()
+	var saves int
+
+	storage.RegisterRefs("group", groups, func() error {
+		saves++
+		return nil
+	})
+
+	var err = groups.Add("hall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = groups.AddDevice("hall", "relay0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renamed device is renamed in keepers
+	storage.RenameReferences("relay0", "relay1")
+	var hall, _ = groups.Group("hall")
+	if hall.Devices[0] != "relay1" {
+		t.Error("device is not renamed:", hall)
+	}
+
+	// Removed device is forgotten, databases are saved by caller
+	storage.ForgetReferences("relay1")
+	hall, _ = groups.Group("hall")
+	if len(hall.Devices) != 0 {
+		t.Error("removed device is not forgotten:", hall)
+	}
+	if saves != 0 {
+		t.Error("references are saved:", saves)
+	}
+}
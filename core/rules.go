@@ -0,0 +1,773 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// Rules default settings
+const (
+	RulesInterval   = time.Second
+	RulesLogSize    = 1024
+	RulesBufferSize = 4096
+)
+
+// Rules triggers
+const (
+	RuleTriggerState = "state"
+	RuleTriggerTime  = "time"
+)
+
+// Rules conditions operators
+const (
+	RuleOpEqual    = "eq"
+	RuleOpNotEqual = "ne"
+	RuleOpGreater  = "gt"
+	RuleOpLess     = "lt"
+)
+
+// RuleCondition Device state condition or time window condition if
+// device is empty. Time window is set as "HH:MM" and can cross midnight
+type RuleCondition struct {
+	Device string
+	Op     string
+	Value  string
+	After  string
+	Before string
+}
+
+// RuleAction Automation command
+type RuleAction struct {
+	Target string
+	Action string
+	Level  int
+}
+
+// Rule Automation rule. State rule is triggered by device state change
+// to State or to any state if State is empty, time rule is triggered
+// by cron expression. Actions run if all conditions are true
+type Rule struct {
+	ID         int
+	Name       string
+	Enabled    bool
+	Trigger    string
+	Device     string
+	State      string
+	Cron       string
+	Conditions []RuleCondition
+	Actions    []RuleAction
+
+	cron *Cron
+	next time.Time
+}
+
+// RuleLog Rule execution journal entry
+type RuleLog struct {
+	Time     time.Time
+	Rule     int
+	Name     string
+	Trigger  string
+	Executed bool
+	Error    string
+}
+
+// Rules Automation rules engine
+type Rules struct {
+	storage *Storage
+	cmd     *Commander
+	events  *Events
+	sun     *Sun
+	log     *utils.Log
+
+	mtx     sync.Mutex
+	rules   map[int]*Rule
+	lastID  int
+	journal []RuleLog
+	pos     int
+	states  map[string]string
+	sub     *Subscription
+	stop    chan struct{}
+}
+
+// NewRules Make new rules engine
+func NewRules(s *Storage, c *Commander, e *Events, sun *Sun, l *utils.Log) *Rules {
+	return &Rules{
+		storage: s,
+		cmd:     c,
+		events:  e,
+		sun:     sun,
+		log:     l,
+		rules:   make(map[int]*Rule),
+		journal: make([]RuleLog, 0, RulesLogSize),
+		states:  make(map[string]string),
+	}
+}
+
+// DeviceState Get device state for rules: "on" or "off" for relay and
+// light, state name for binary input, level for dimmer, channels states
+// as "0" and "1" chars for multi relay, temperature for sensor and
+// power for meter
+func DeviceState(dev devices.IDevice) string {
+	switch d := dev.(type) {
+	case *base.Relay:
+		return onOff(d.State())
+	case *base.Light:
+		return onOff(d.State())
+	case *base.Binary:
+		return d.StateName()
+	case *base.Dimmer:
+		return strconv.Itoa(d.State())
+	case *base.MultiRelay:
+		var states string
+		for _, ch := range d.Channels() {
+			if ch.State() {
+				states += "1"
+			} else {
+				states += "0"
+			}
+		}
+		return states
+	case *base.Sensor:
+		return strconv.FormatFloat(d.Data().Temperature, 'f', -1, 64)
+	case *base.Meter:
+		return strconv.FormatFloat(d.Power(), 'f', -1, 64)
+	}
+	return ""
+}
+
+// Add Add new rule with next free ID
+func (r *Rules) Add(rule Rule) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	rule.ID = r.lastID + 1
+
+	var err = r.addRule(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	r.lastID = rule.ID
+	return rule.ID, nil
+}
+
+// Restore Add saved rule
+func (r *Rules) Restore(rule Rule) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if rule.ID <= 0 || r.rules[rule.ID] != nil {
+		return errors.New("Wrong rule ID")
+	}
+
+	for _, cond := range rule.Conditions {
+		var err = checkCondition(cond)
+		if err != nil {
+			return err
+		}
+	}
+	for _, action := range rule.Actions {
+		if !IsAction(action.Action) {
+			return errors.New("Unknown action")
+		}
+	}
+
+	var err = r.addRule(rule)
+	if err != nil {
+		return err
+	}
+
+	if rule.ID > r.lastID {
+		r.lastID = rule.ID
+	}
+	return nil
+}
+
+// Remove Delete rule
+func (r *Rules) Remove(id int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.rules[id] == nil {
+		return errors.New("Rule not found")
+	}
+
+	delete(r.rules, id)
+	return nil
+}
+
+// SetEnabled Enable or disable rule
+func (r *Rules) SetEnabled(id int, enabled bool) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return errors.New("Rule not found")
+	}
+
+	rule.Enabled = enabled
+	if enabled && rule.cron != nil {
+		rule.next = rule.cron.Next(time.Now().In(r.sun.Location()))
+	}
+	return nil
+}
+
+// AddCondition Add condition to rule
+func (r *Rules) AddCondition(id int, cond RuleCondition) error {
+	var err = checkCondition(cond)
+	if err != nil {
+		return err
+	}
+	if cond.Device != "" && r.storage.Device(cond.Device) == nil {
+		return errors.New("Device not found")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return errors.New("Rule not found")
+	}
+
+	rule.Conditions = append(rule.Conditions, cond)
+	return nil
+}
+
+// RemoveCondition Remove rule condition by index
+func (r *Rules) RemoveCondition(id int, index int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return errors.New("Rule not found")
+	}
+	if index < 0 || index >= len(rule.Conditions) {
+		return errors.New("Condition not found")
+	}
+
+	rule.Conditions = append(rule.Conditions[:index], rule.Conditions[index+1:]...)
+	return nil
+}
+
+// AddAction Add action to rule
+func (r *Rules) AddAction(id int, action RuleAction) error {
+	var err = r.cmd.CheckTarget(action.Target, action.Action)
+	if err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return errors.New("Rule not found")
+	}
+
+	rule.Actions = append(rule.Actions, action)
+	return nil
+}
+
+// RemoveAction Remove rule action by index
+func (r *Rules) RemoveAction(id int, index int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return errors.New("Rule not found")
+	}
+	if index < 0 || index >= len(rule.Actions) {
+		return errors.New("Action not found")
+	}
+
+	rule.Actions = append(rule.Actions[:index], rule.Actions[index+1:]...)
+	return nil
+}
+
+// Rule Get copy of rule by ID
+func (r *Rules) Rule(id int) (Rule, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var rule = r.rules[id]
+	if rule == nil {
+		return Rule{}, false
+	}
+	return rule.copy(), true
+}
+
+// Rules Get copy of all rules sorted by ID
+func (r *Rules) Rules() []Rule {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var list []Rule
+
+	for _, rule := range r.rules {
+		list = append(list, rule.copy())
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
+
+// LastID Get last issued rule ID
+func (r *Rules) LastID() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.lastID
+}
+
+// SetLastID Set last issued rule ID, it can't be decreased
+func (r *Rules) SetLastID(id int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if id > r.lastID {
+		r.lastID = id
+	}
+}
+
+// Log Get rules execution journal in chronological order
+func (r *Rules) Log() []RuleLog {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var list []RuleLog
+
+	for i := 0; i < len(r.journal); i++ {
+		list = append(list, r.journal[(r.pos+i)%len(r.journal)])
+	}
+
+	return list
+}
+
+// RenameDevice Change device name in all rules
+func (r *Rules) RenameDevice(device string, newName string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, rule := range r.rules {
+		if rule.Device == device {
+			rule.Device = newName
+		}
+		for i := range rule.Conditions {
+			if rule.Conditions[i].Device == device {
+				rule.Conditions[i].Device = newName
+			}
+		}
+		for i := range rule.Actions {
+			if rule.Actions[i].Action != ActionScene && rule.Actions[i].Target == device {
+				rule.Actions[i].Target = newName
+			}
+		}
+	}
+}
+
+// ForgetDevice Remove device conditions and actions from all rules.
+// Rules triggered by device are disabled
+func (r *Rules) ForgetDevice(device string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var found bool
+
+	for _, rule := range r.rules {
+		if rule.Device == device && rule.Enabled {
+			rule.Enabled = false
+			found = true
+		}
+
+		var conds []RuleCondition
+		for _, cond := range rule.Conditions {
+			if cond.Device != device {
+				conds = append(conds, cond)
+			}
+		}
+		if len(conds) != len(rule.Conditions) {
+			rule.Conditions = conds
+			found = true
+		}
+
+		found = rule.removeActions(func(action RuleAction) bool {
+			return action.Action != ActionScene && action.Target == device
+		}) || found
+	}
+
+	return found
+}
+
+// ForgetScene Remove scene actions from all rules
+func (r *Rules) ForgetScene(scene string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var found bool
+
+	for _, rule := range r.rules {
+		found = rule.removeActions(func(action RuleAction) bool {
+			return action.Action == ActionScene && action.Target == scene
+		}) || found
+	}
+
+	return found
+}
+
+// Start Start rules processing in background. State changes are
+// received by events subscription with large buffer, state rules are
+// resynced with devices states when events were dropped
+func (r *Rules) Start(interval time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.sub = r.events.Subscribe(RulesBufferSize, EventStateChanged)
+
+	for _, dev := range r.storage.Devices() {
+		r.states[dev.Name()] = DeviceState(dev)
+	}
+
+	go r.run(interval, r.stop, r.sub)
+}
+
+// Stop Stop rules processing
+func (r *Rules) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		r.events.Unsubscribe(r.sub)
+		r.stop = nil
+		r.sub = nil
+	}
+}
+
+// HandleEvent Run state rules triggered by device state change event
+func (r *Rules) HandleEvent(e Event) {
+	if e.Type != EventStateChanged {
+		return
+	}
+
+	r.mtx.Lock()
+	r.states[e.Device] = e.Value
+	r.mtx.Unlock()
+
+	for _, rule := range r.Rules() {
+		if !rule.Enabled || rule.Trigger != RuleTriggerState || rule.Device != e.Device {
+			continue
+		}
+		if rule.State != "" && rule.State != e.Value {
+			continue
+		}
+
+		r.execute(rule, "Device \""+e.Device+"\" state \""+e.Value+"\"", e.Time)
+	}
+}
+
+// Resync Run state rules of devices which state differs from last
+// handled state. It is used when state events were dropped, state of
+// device which was not seen before is only remembered
+func (r *Rules) Resync(now time.Time) {
+	var devices = make(map[string]bool)
+	for _, rule := range r.Rules() {
+		if rule.Enabled && rule.Trigger == RuleTriggerState {
+			devices[rule.Device] = true
+		}
+	}
+
+	var changed []Event
+	r.mtx.Lock()
+	for device := range devices {
+		var dev = r.storage.Device(device)
+		if dev == nil {
+			continue
+		}
+
+		var state = DeviceState(dev)
+		var last, ok = r.states[device]
+		r.states[device] = state
+		if ok && last != state {
+			changed = append(changed, Event{Time: now, Type: EventStateChanged, Device: device, Value: state})
+		}
+	}
+	r.mtx.Unlock()
+
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Device < changed[j].Device
+	})
+	for _, e := range changed {
+		r.log.Info("RULES", "Device \""+e.Device+"\" state \""+e.Value+"\" is resynced")
+		r.HandleEvent(e)
+	}
+}
+
+// Check Run time rules planned before now
+func (r *Rules) Check(now time.Time) {
+	var run []Rule
+
+	r.mtx.Lock()
+	for _, rule := range r.rules {
+		if !rule.Enabled || rule.cron == nil || rule.next.IsZero() || rule.next.After(now) {
+			continue
+		}
+
+		run = append(run, rule.copy())
+		rule.next = rule.cron.Next(now.In(r.sun.Location()))
+	}
+	r.mtx.Unlock()
+
+	sort.Slice(run, func(i, j int) bool {
+		return run[i].ID < run[j].ID
+	})
+	for _, rule := range run {
+		r.execute(rule, "Time \""+rule.Cron+"\"", now)
+	}
+}
+
+// execute Check rule conditions and run actions
+func (r *Rules) execute(rule Rule, trigger string, now time.Time) {
+	var entry = RuleLog{
+		Time:    now,
+		Rule:    rule.ID,
+		Name:    rule.Name,
+		Trigger: trigger,
+	}
+
+	for _, cond := range rule.Conditions {
+		if !r.match(cond, now) {
+			entry.Error = "Condition " + cond.String() + " is false"
+			r.addLog(entry)
+			return
+		}
+	}
+
+	var errs []string
+	for _, action := range rule.Actions {
+		var err = r.cmd.Execute(ActorRules, action.Target, action.Action, action.Level)
+		if err != nil {
+			errs = append(errs, "\""+action.Action+"\" \""+action.Target+"\": "+err.Error())
+		}
+	}
+
+	entry.Executed = true
+	entry.Error = strings.Join(errs, "; ")
+	r.addLog(entry)
+
+	var name = "Rule " + strconv.Itoa(rule.ID) + " \"" + rule.Name + "\""
+	if entry.Error != "" {
+		r.log.Error("RULES", name, entry.Error)
+		return
+	}
+	r.log.Info("RULES", name+" done")
+}
+
+// match Check device state or time window condition
+func (r *Rules) match(cond RuleCondition, now time.Time) bool {
+	if cond.Device == "" {
+		var local = now.In(r.sun.Location())
+		var minutes = local.Hour()*60 + local.Minute()
+		var after, _ = parseDayTime(cond.After)
+		var before, _ = parseDayTime(cond.Before)
+
+		switch {
+		case cond.After != "" && cond.Before != "" && after > before:
+			return minutes >= after || minutes < before
+		case cond.After != "" && cond.Before != "":
+			return minutes >= after && minutes < before
+		case cond.After != "":
+			return minutes >= after
+		default:
+			return minutes < before
+		}
+	}
+
+	var dev = r.storage.Device(cond.Device)
+	if dev == nil {
+		return false
+	}
+	var state = DeviceState(dev)
+
+	var value, errValue = strconv.ParseFloat(cond.Value, 64)
+	var current, errState = strconv.ParseFloat(state, 64)
+	var numbers = errValue == nil && errState == nil
+
+	switch cond.Op {
+	case RuleOpEqual:
+		return state == cond.Value || numbers && current == value
+	case RuleOpNotEqual:
+		return state != cond.Value && !(numbers && current == value)
+	case RuleOpGreater:
+		return numbers && current > value
+	case RuleOpLess:
+		return numbers && current < value
+	}
+	return false
+}
+
+func (r *Rules) addLog(entry RuleLog) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if len(r.journal) < RulesLogSize {
+		r.journal = append(r.journal, entry)
+	} else {
+		r.journal[r.pos] = entry
+		r.pos = (r.pos + 1) % RulesLogSize
+	}
+}
+
+// addRule Validate rule trigger and plan time rule
+func (r *Rules) addRule(rule Rule) error {
+	rule.cron = nil
+	rule.next = time.Time{}
+
+	switch rule.Trigger {
+	case RuleTriggerState:
+		if rule.Device == "" {
+			return errors.New("Trigger device is empty")
+		}
+	case RuleTriggerTime:
+		var cron, err = ParseCron(rule.Cron)
+		if err != nil {
+			return err
+		}
+		rule.cron = cron
+		rule.next = cron.Next(time.Now().In(r.sun.Location()))
+	default:
+		return errors.New("Unknown rule trigger")
+	}
+
+	r.rules[rule.ID] = &rule
+	return nil
+}
+
+func (r *Rules) run(interval time.Duration, stop chan struct{}, sub *Subscription) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var dropped int
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			r.Check(now)
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			r.HandleEvent(e)
+		}
+
+		// Buffered events are handled before resync, so stale events
+		// don't follow it
+		if len(sub.Events()) == 0 && sub.Dropped() != dropped {
+			dropped = sub.Dropped()
+			r.Resync(time.Now())
+		}
+	}
+}
+
+// String Get human readable condition
+func (c RuleCondition) String() string {
+	if c.Device == "" {
+		return fmt.Sprintf("time after \"%s\" before \"%s\"", c.After, c.Before)
+	}
+	return fmt.Sprintf("\"%s\" %s \"%s\"", c.Device, c.Op, c.Value)
+}
+
+func (r *Rule) removeActions(match func(action RuleAction) bool) bool {
+	var actions []RuleAction
+
+	for _, action := range r.Actions {
+		if !match(action) {
+			actions = append(actions, action)
+		}
+	}
+
+	if len(actions) == len(r.Actions) {
+		return false
+	}
+
+	r.Actions = actions
+	return true
+}
+
+func (r *Rule) copy() Rule {
+	var rule = *r
+
+	rule.Conditions = make([]RuleCondition, len(r.Conditions))
+	copy(rule.Conditions, r.Conditions)
+	rule.Actions = make([]RuleAction, len(r.Actions))
+	copy(rule.Actions, r.Actions)
+
+	return rule
+}
+
+// checkCondition Check condition operator or time window
+func checkCondition(cond RuleCondition) error {
+	if cond.Device == "" {
+		if cond.After == "" && cond.Before == "" {
+			return errors.New("Condition time window is empty")
+		}
+		for _, t := range []string{cond.After, cond.Before} {
+			if _, err := parseDayTime(t); t != "" && err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch cond.Op {
+	case RuleOpEqual, RuleOpNotEqual, RuleOpGreater, RuleOpLess:
+		return nil
+	}
+	return errors.New("Unknown condition operator")
+}
+
+// parseDayTime Parse "HH:MM" to minutes from midnight
+func parseDayTime(t string) (int, error) {
+	var parsed, err = time.Parse("15:04", t)
+	if err != nil {
+		return 0, errors.New("Wrong time \"" + t + "\"")
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func onOff(state bool) string {
+	if state {
+		return "on"
+	}
+	return "off"
+}
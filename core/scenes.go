@@ -0,0 +1,190 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// SceneAction Target device state. Level is used for dimmers,
+// status for other devices
+type SceneAction struct {
+	Device string
+	Status bool
+	Level  int
+}
+
+// Scene Named list of target devices states
+type Scene struct {
+	Name    string
+	Actions []SceneAction
+}
+
+// Scenes All scenes
+type Scenes struct {
+	mtx    sync.RWMutex
+	scenes map[string]*Scene
+}
+
+// NewScenes Make new scenes list
+func NewScenes() *Scenes {
+	return &Scenes{
+		scenes: make(map[string]*Scene),
+	}
+}
+
+// Add Add new empty scene
+func (s *Scenes) Add(name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if name == "" {
+		return errors.New("Wrong scene name")
+	}
+	if s.scenes[name] != nil {
+		return errors.New("Scene already exists")
+	}
+
+	s.scenes[name] = &Scene{Name: name}
+	return nil
+}
+
+// Remove Delete scene
+func (s *Scenes) Remove(name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.scenes[name] == nil {
+		return errors.New("Scene not found")
+	}
+
+	delete(s.scenes, name)
+	return nil
+}
+
+// Scene Get copy of scene by name
+func (s *Scenes) Scene(name string) (Scene, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var scene = s.scenes[name]
+	if scene == nil {
+		return Scene{}, false
+	}
+
+	return scene.copy(), true
+}
+
+// Scenes Get copy of all scenes sorted by name
+func (s *Scenes) Scenes() []Scene {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var list []Scene
+
+	for _, scene := range s.scenes {
+		list = append(list, scene.copy())
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// SetAction Set device target state. Existing device action is replaced
+func (s *Scenes) SetAction(name string, action SceneAction) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var scene = s.scenes[name]
+	if scene == nil {
+		return errors.New("Scene not found")
+	}
+
+	var i = scene.index(action.Device)
+	if i >= 0 {
+		scene.Actions[i] = action
+	} else {
+		scene.Actions = append(scene.Actions, action)
+	}
+
+	return nil
+}
+
+// RemoveAction Remove device target state from scene
+func (s *Scenes) RemoveAction(name string, device string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var scene = s.scenes[name]
+	if scene == nil {
+		return errors.New("Scene not found")
+	}
+
+	var i = scene.index(device)
+	if i < 0 {
+		return errors.New("Device not in scene")
+	}
+
+	scene.Actions = append(scene.Actions[:i], scene.Actions[i+1:]...)
+	return nil
+}
+
+// RenameDevice Change device name in all scenes
+func (s *Scenes) RenameDevice(device string, newName string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, scene := range s.scenes {
+		var i = scene.index(device)
+		if i >= 0 {
+			scene.Actions[i].Device = newName
+		}
+	}
+}
+
+// ForgetDevice Remove device from all scenes
+func (s *Scenes) ForgetDevice(device string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var found bool
+
+	for _, scene := range s.scenes {
+		var i = scene.index(device)
+		if i >= 0 {
+			scene.Actions = append(scene.Actions[:i], scene.Actions[i+1:]...)
+			found = true
+		}
+	}
+
+	return found
+}
+
+func (s *Scene) index(device string) int {
+	for i, action := range s.Actions {
+		if action.Device == device {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Scene) copy() Scene {
+	var actions = make([]SceneAction, len(s.Actions))
+	copy(actions, s.Actions)
+
+	return Scene{Name: s.Name, Actions: actions}
+}
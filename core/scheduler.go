@@ -0,0 +1,408 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/utils"
+)
+
+// Scheduler default settings
+const (
+	SchedulerInterval = time.Second
+	SchedulerLateness = time.Minute
+)
+
+// Job Scheduled command. Cron job runs by cron expression, sun job
+// runs at sun event with offset, one-shot job runs once at time At
+// and is disabled after that. Target is device name or scene name
+// for scene action
+type Job struct {
+	ID      int
+	Cron    string
+	Sun     string
+	Offset  time.Duration
+	At      time.Time
+	Target  string
+	Action  string
+	Level   int
+	Enabled bool
+	CatchUp bool
+	Created time.Time
+	LastRun time.Time
+	Next    time.Time
+
+	cron *Cron
+}
+
+// Scheduler Time based devices commands. Missed runs are not repeated:
+// a job which is late more than lateness (after downtime or clock
+// change) runs once if catch-up is enabled, otherwise it is skipped
+type Scheduler struct {
+	cmd *Commander
+	sun *Sun
+	log *utils.Log
+
+	mtx      sync.Mutex
+	jobs     map[int]*Job
+	lastID   int
+	lateness time.Duration
+	save     func() error
+	stop     chan struct{}
+}
+
+// NewScheduler Make new devices commands scheduler
+func NewScheduler(c *Commander, sun *Sun, l *utils.Log) *Scheduler {
+	return &Scheduler{
+		cmd:      c,
+		sun:      sun,
+		log:      l,
+		jobs:     make(map[int]*Job),
+		lateness: SchedulerLateness,
+	}
+}
+
+// SetLateness Set max job delay after which run is treated as missed
+func (s *Scheduler) SetLateness(lateness time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.lateness = lateness
+}
+
+// SetSaver Set jobs persistence hook, it is called after jobs runs
+func (s *Scheduler) SetSaver(save func() error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.save = save
+}
+
+// Add Add new job with next free ID
+func (s *Scheduler) Add(job Job) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	job.ID = s.lastID + 1
+	job.Created = time.Now()
+
+	var err = s.addJob(job)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lastID = job.ID
+	return job.ID, nil
+}
+
+// Restore Add saved job
+func (s *Scheduler) Restore(job Job) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if job.ID <= 0 || s.jobs[job.ID] != nil {
+		return errors.New("Wrong job ID")
+	}
+
+	var err = s.addJob(job)
+	if err != nil {
+		return err
+	}
+
+	if job.ID > s.lastID {
+		s.lastID = job.ID
+	}
+	return nil
+}
+
+// Remove Delete job
+func (s *Scheduler) Remove(id int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.jobs[id] == nil {
+		return errors.New("Job not found")
+	}
+
+	delete(s.jobs, id)
+	return nil
+}
+
+// SetEnabled Enable or disable job. Enabled job is planned from now
+func (s *Scheduler) SetEnabled(id int, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var job = s.jobs[id]
+	if job == nil {
+		return errors.New("Job not found")
+	}
+
+	job.Enabled = enabled
+	if enabled && job.repeated() {
+		job.Next = s.next(job, time.Now())
+	}
+	if enabled && !job.repeated() && job.LastRun.IsZero() {
+		job.Next = job.At
+	}
+	return nil
+}
+
+// Job Get copy of job by ID
+func (s *Scheduler) Job(id int) (Job, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var job = s.jobs[id]
+	if job == nil {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs Get copy of all jobs sorted by ID
+func (s *Scheduler) Jobs() []Job {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var list []Job
+
+	for _, job := range s.jobs {
+		list = append(list, *job)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
+
+// LastID Get last issued job ID
+func (s *Scheduler) LastID() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.lastID
+}
+
+// SetLastID Set last issued job ID, it can't be decreased
+func (s *Scheduler) SetLastID(id int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if id > s.lastID {
+		s.lastID = id
+	}
+}
+
+// RenameDevice Change target device name in all jobs
+func (s *Scheduler) RenameDevice(device string, newName string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Action != ActionScene && job.Target == device {
+			job.Target = newName
+		}
+	}
+}
+
+// ForgetDevice Remove all jobs of device
+func (s *Scheduler) ForgetDevice(device string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.forget(func(job *Job) bool {
+		return job.Action != ActionScene && job.Target == device
+	})
+}
+
+// ForgetScene Remove all jobs of scene
+func (s *Scheduler) ForgetScene(scene string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.forget(func(job *Job) bool {
+		return job.Action == ActionScene && job.Target == scene
+	})
+}
+
+// Start Start jobs checking in background
+func (s *Scheduler) Start(interval time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+
+	go s.run(interval, s.stop)
+}
+
+// Stop Stop jobs checking
+func (s *Scheduler) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// Check Run jobs planned before now and plan next runs
+func (s *Scheduler) Check(now time.Time) {
+	var run []Job
+	var changed bool
+
+	s.mtx.Lock()
+	for _, job := range s.jobs {
+		if !job.Enabled || job.Next.IsZero() || job.Next.After(now) {
+			continue
+		}
+		changed = true
+
+		var missed = now.Sub(job.Next) > s.lateness
+		if missed && !job.CatchUp {
+			s.log.Info("SCHEDULER", "Job "+strconv.Itoa(job.ID)+" missed run at "+job.Next.Format(time.RFC3339)+
+				" is skipped")
+		} else {
+			job.LastRun = now
+			run = append(run, *job)
+		}
+
+		if job.repeated() {
+			job.Next = s.next(job, now)
+		} else {
+			job.Enabled = false
+			job.Next = time.Time{}
+		}
+	}
+	var save = s.save
+	s.mtx.Unlock()
+
+	sort.Slice(run, func(i, j int) bool {
+		return run[i].ID < run[j].ID
+	})
+	for _, job := range run {
+		s.execute(job)
+	}
+
+	if changed && save != nil {
+		var err = save()
+		if err != nil {
+			s.log.Error("SCHEDULER", "Fail to save jobs", err.Error())
+		}
+	}
+}
+
+// execute Run job command without user rights checking
+func (s *Scheduler) execute(job Job) {
+	var err = s.cmd.Execute(ActorScheduler, job.Target, job.Action, job.Level)
+
+	var name = "Job " + strconv.Itoa(job.ID) + " \"" + job.Action + "\" \"" + job.Target + "\""
+	if err != nil {
+		s.log.Error("SCHEDULER", name, err.Error())
+		return
+	}
+	s.log.Info("SCHEDULER", name+" done")
+}
+
+// addJob Validate job and plan next run from last run or creation time
+func (s *Scheduler) addJob(job Job) error {
+	if !IsAction(job.Action) {
+		return errors.New("Unknown job action")
+	}
+	if job.Target == "" {
+		return errors.New("Job target is empty")
+	}
+
+	job.cron = nil
+	job.Next = time.Time{}
+
+	if job.Cron != "" && job.Sun != "" {
+		return errors.New("Job can't have both cron and sun event")
+	}
+	if job.Cron != "" {
+		var cron, err = ParseCron(job.Cron)
+		if err != nil {
+			return err
+		}
+		job.cron = cron
+	}
+	if job.Sun != "" && !IsSunEvent(job.Sun) {
+		return errors.New("Unknown sun event")
+	}
+
+	if job.repeated() {
+		var from = job.Created
+		if job.LastRun.After(from) {
+			from = job.LastRun
+		}
+		job.Next = s.next(&job, from)
+	} else {
+		if job.At.IsZero() {
+			return errors.New("Job time is not set")
+		}
+		if job.LastRun.IsZero() {
+			job.Next = job.At
+		}
+	}
+
+	s.jobs[job.ID] = &job
+	return nil
+}
+
+// next Get repeated job run time after from in controller timezone
+func (s *Scheduler) next(job *Job, from time.Time) time.Time {
+	if job.cron != nil {
+		return job.cron.Next(from.In(s.sun.Location()))
+	}
+	return s.sun.Next(job.Sun, job.Offset, from)
+}
+
+func (j *Job) repeated() bool {
+	return j.cron != nil || j.Sun != ""
+}
+
+func (s *Scheduler) forget(match func(job *Job) bool) bool {
+	var found bool
+
+	for id, job := range s.jobs {
+		if match(job) {
+			delete(s.jobs, id)
+			found = true
+		}
+	}
+
+	return found
+}
+
+func (s *Scheduler) run(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.Check(now)
+		}
+	}
+}
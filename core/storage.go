@@ -12,15 +12,20 @@ package core
 
 import (
 	"errors"
+	"sort"
+	"sync"
 
 	"github.com/futcity/controller/core/devices"
-	"github.com/futcity/controller/core/devices/base"
 	"github.com/futcity/controller/utils"
 )
 
 // Storage All devices map
 type Storage struct {
+	mtx     sync.RWMutex
 	devices map[string]devices.IDevice
+	types   map[string]*DeviceType
+	refs    []deviceRefs
+	lastID  int
 	log     *utils.Log
 }
 
@@ -28,22 +33,102 @@ type Storage struct {
 func NewStorage(log *utils.Log) *Storage {
 	return &Storage{
 		devices: make(map[string]devices.IDevice),
+		types:   make(map[string]*DeviceType),
 		log:     log,
 	}
 }
 
-// AddDevice Add new device in storage
-func (s *Storage) AddDevice(name string, desc string, devType string) {
-	var device devices.IDevice
+// AddDevice Add new device in storage with next free ID
+func (s *Storage) AddDevice(name string, desc string, devType string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 
-	if devType == "relay" {
-		device = base.NewRelay(name, desc)
+	return s.addDevice(s.lastID+1, name, desc, devType)
+}
+
+// RestoreDevice Add device in storage with saved ID
+func (s *Storage) RestoreDevice(id int, name string, desc string, devType string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if id <= 0 {
+		return errors.New("Wrong device ID")
 	}
-	device.SetID(len(s.devices))
+
+	for _, dev := range s.devices {
+		if dev.ID() == id {
+			return errors.New("Device ID already exists")
+		}
+	}
+
+	return s.addDevice(id, name, desc, devType)
+}
+
+func (s *Storage) addDevice(id int, name string, desc string, devType string) error {
+	var typ = s.types[devType]
+	if typ == nil {
+		return errors.New("Unknown device type")
+	}
+
+	if s.devices[name] != nil {
+		return errors.New("Device already exists")
+	}
+
+	var device = typ.Factory(name, desc)
+	device.SetID(id)
 	s.devices[name] = device
+
+	if id > s.lastID {
+		s.lastID = id
+	}
+
+	return nil
+}
+
+// LastID Get last issued device ID
+func (s *Storage) LastID() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.lastID
 }
 
+// SetLastID Set last issued device ID. IDs are never moved back
+func (s *Storage) SetLastID(id int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if id > s.lastID {
+		s.lastID = id
+	}
+}
+
+// RenameDevice Change device name, ID and state are kept
+func (s *Storage) RenameDevice(name string, newName string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var device = s.devices[name]
+	if device == nil {
+		return errors.New("Device not found")
+	}
+
+	if s.devices[newName] != nil {
+		return errors.New("Device already exists")
+	}
+
+	device.SetName(newName)
+	delete(s.devices, name)
+	s.devices[newName] = device
+
+	return nil
+}
+
+// RemoveByID Remove device from storage by ID
 func (s *Storage) RemoveByID(id int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
 	for _, dev := range s.devices {
 		if dev.ID() == id {
 			delete(s.devices, dev.Name())
@@ -55,15 +140,23 @@ func (s *Storage) RemoveByID(id int) error {
 
 // Device Get device by name
 func (s *Storage) Device(name string) devices.IDevice {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
 	return s.devices[name]
 }
 
 // DevicesByType Get all devices by type
 func (s *Storage) DevicesByType(devType string) []devices.IDevice {
+	return s.FindDevices(&DeviceFilter{Type: devType})
+}
+
+// FindDevices Get all devices matched by filter sorted by ID
+func (s *Storage) FindDevices(filter *DeviceFilter) []devices.IDevice {
 	var list []devices.IDevice
 
-	for _, dev := range s.devices {
-		if dev.Type() == devType {
+	for _, dev := range s.Devices() {
+		if filter.Match(dev) {
 			list = append(list, dev)
 		}
 	}
@@ -73,6 +166,9 @@ func (s *Storage) DevicesByType(devType string) []devices.IDevice {
 
 // DeviceByDescription Get device by description
 func (s *Storage) DeviceByDescription(desc string) (devices.IDevice, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
 	for _, dev := range s.devices {
 		if dev.Description() == desc {
 			return dev, nil
@@ -81,7 +177,11 @@ func (s *Storage) DeviceByDescription(desc string) (devices.IDevice, error) {
 	return nil, errors.New("Device not found")
 }
 
+// DeviceByID Get device by ID
 func (s *Storage) DeviceByID(id int) (devices.IDevice, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
 	for _, dev := range s.devices {
 		if dev.ID() == id {
 			return dev, nil
@@ -90,13 +190,20 @@ func (s *Storage) DeviceByID(id int) (devices.IDevice, error) {
 	return nil, errors.New("Device not found")
 }
 
-// Devices Get all devices
+// Devices Get all devices sorted by ID
 func (s *Storage) Devices() []devices.IDevice {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
 	var list []devices.IDevice
 
 	for _, dev := range s.devices {
 		list = append(list, dev)
 	}
 
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID() < list[j].ID()
+	})
+
 	return list
 }
@@ -0,0 +1,220 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Sun events
+const (
+	SunDawn    = "dawn"
+	SunSunrise = "sunrise"
+	SunSunset  = "sunset"
+	SunDusk    = "dusk"
+)
+
+// Sun zenith angles in degrees
+const (
+	sunZenithOfficial = 90.833
+	sunZenithCivil    = 96.0
+)
+
+// SunMaxDays Max days to search next sun event, polar day and
+// night can last for months
+const SunMaxDays = 366
+
+// SunTimes Sun events of one day. Event time is zero if there is no
+// such event this day
+type SunTimes struct {
+	Date    time.Time
+	Dawn    time.Time
+	Sunrise time.Time
+	Sunset  time.Time
+	Dusk    time.Time
+}
+
+// Sun Offline sunrise and sunset calculator for controller location.
+// Dawn and dusk are civil twilight begin and end
+type Sun struct {
+	mtx        sync.RWMutex
+	lat        float64
+	lon        float64
+	tz         *time.Location
+	configured bool
+}
+
+// NewSun Make new sun calculator with local timezone
+func NewSun() *Sun {
+	return &Sun{
+		tz: time.Local,
+	}
+}
+
+// IsSunEvent Check sun event name
+func IsSunEvent(event string) bool {
+	switch event {
+	case SunDawn, SunSunrise, SunSunset, SunDusk:
+		return true
+	}
+	return false
+}
+
+// SetLocation Set latitude and longitude in degrees and timezone
+func (s *Sun) SetLocation(lat float64, lon float64, tz *time.Location) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.lat = lat
+	s.lon = lon
+	s.tz = tz
+	s.configured = true
+}
+
+// Configured Check location is set
+func (s *Sun) Configured() bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.configured
+}
+
+// Location Get controller timezone
+func (s *Sun) Location() *time.Location {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.tz
+}
+
+// Times Get sun events of date in controller timezone
+func (s *Sun) Times(date time.Time) SunTimes {
+	var tz = s.Location()
+	var year, month, day = date.In(tz).Date()
+
+	return SunTimes{
+		Date:    time.Date(year, month, day, 0, 0, 0, 0, tz),
+		Dawn:    s.Event(SunDawn, date),
+		Sunrise: s.Event(SunSunrise, date),
+		Sunset:  s.Event(SunSunset, date),
+		Dusk:    s.Event(SunDusk, date),
+	}
+}
+
+// Event Get sun event time of date in controller timezone
+func (s *Sun) Event(event string, date time.Time) time.Time {
+	s.mtx.RLock()
+	var lat, lon, tz = s.lat, s.lon, s.tz
+	s.mtx.RUnlock()
+
+	var zenith, rising float64
+	switch event {
+	case SunDawn:
+		zenith, rising = sunZenithCivil, 1
+	case SunSunrise:
+		zenith, rising = sunZenithOfficial, 1
+	case SunSunset:
+		zenith, rising = sunZenithOfficial, 0
+	case SunDusk:
+		zenith, rising = sunZenithCivil, 0
+	default:
+		return time.Time{}
+	}
+
+	var year, month, day = date.In(tz).Date()
+	var local = time.Date(year, month, day, 0, 0, 0, 0, tz)
+
+	var ut, ok = sunEventUT(local.YearDay(), lat, lon, zenith, rising == 1)
+	if !ok {
+		return time.Time{}
+	}
+
+	// Move event to requested local date
+	var t = time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Add(time.Duration(ut * float64(time.Hour))).In(tz)
+	if t.Before(local) {
+		t = t.Add(24 * time.Hour)
+	} else if !t.Before(local.AddDate(0, 0, 1)) {
+		t = t.Add(-24 * time.Hour)
+	}
+
+	return t.Truncate(time.Second)
+}
+
+// Next Get first sun event time with offset after from
+func (s *Sun) Next(event string, offset time.Duration, from time.Time) time.Time {
+	var tz = s.Location()
+	var year, month, day = from.In(tz).Date()
+
+	for i := -1; i <= SunMaxDays; i++ {
+		var t = s.Event(event, time.Date(year, month, day+i, 12, 0, 0, 0, tz))
+		if t.IsZero() {
+			continue
+		}
+
+		t = t.Add(offset)
+		if t.After(from) {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// sunEventUT Calculate sun event UTC hour by "Almanac for Computers"
+// algorithm. False is returned if sun doesn't reach zenith this day
+func sunEventUT(yearDay int, lat float64, lon float64, zenith float64, rising bool) (float64, bool) {
+	var rad = math.Pi / 180
+	var lonHour = lon / 15
+
+	// Approximate event time
+	var t = float64(yearDay) + (18-lonHour)/24
+	if rising {
+		t = float64(yearDay) + (6-lonHour)/24
+	}
+
+	// Sun mean anomaly and true longitude
+	var m = 0.9856*t - 3.289
+	var l = normalize(m+1.916*math.Sin(m*rad)+0.020*math.Sin(2*m*rad)+282.634, 360)
+
+	// Sun right ascension in hours, same quadrant as longitude
+	var ra = normalize(math.Atan(0.91764*math.Tan(l*rad))/rad, 360)
+	ra += math.Floor(l/90)*90 - math.Floor(ra/90)*90
+	ra /= 15
+
+	// Sun declination and local hour angle
+	var sinDec = 0.39782 * math.Sin(l*rad)
+	var cosDec = math.Cos(math.Asin(sinDec))
+	var cosH = (math.Cos(zenith*rad) - sinDec*math.Sin(lat*rad)) / (cosDec * math.Cos(lat*rad))
+	if cosH > 1 || cosH < -1 {
+		return 0, false
+	}
+
+	var h = math.Acos(cosH) / rad
+	if rising {
+		h = 360 - h
+	}
+	h /= 15
+
+	// Local mean time to UTC
+	var lmt = h + ra - 0.06571*t - 6.622
+	return normalize(lmt-lonHour, 24), true
+}
+
+// normalize Put value in [0, max) range
+func normalize(value float64, max float64) float64 {
+	value = math.Mod(value, max)
+	if value < 0 {
+		value += max
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSun(t *testing.T) {
+	var sun = NewSun()
+	if sun.Configured() {
+		t.Error("new sun location is configured")
+	}
+
+	var tz = time.FixedZone("MSK", 3*60*60)
+	sun.SetLocation(55.75, 37.62, tz)
+
+	// Known Moscow sun times with 2 minutes precision
+	var times = sun.Times(time.Date(2021, 6, 21, 12, 0, 0, 0, tz))
+	var tests = []struct {
+		got  time.Time
+		want time.Time
+	}{
+		{times.Dawn, time.Date(2021, 6, 21, 2, 43, 0, 0, tz)},
+		{times.Sunrise, time.Date(2021, 6, 21, 3, 44, 0, 0, tz)},
+		{times.Sunset, time.Date(2021, 6, 21, 21, 18, 0, 0, tz)},
+		{times.Dusk, time.Date(2021, 6, 21, 22, 19, 0, 0, tz)},
+	}
+	for _, test := range tests {
+		var diff = test.got.Sub(test.want)
+		if diff < -2*time.Minute || diff > 2*time.Minute {
+			t.Error("wrong sun time", test.got, "want", test.want)
+		}
+	}
+
+	// Sun never sets in polar day
+	sun.SetLocation(69.65, 18.96, time.UTC)
+	if !sun.Event(SunSunset, time.Date(2021, 6, 21, 12, 0, 0, 0, time.UTC)).IsZero() {
+		t.Error("sunset in polar day")
+	}
+	var next = sun.Next(SunSunset, 0, time.Date(2021, 6, 21, 12, 0, 0, 0, time.UTC))
+	if next.Month() != time.July {
+		t.Error("wrong next sunset after polar day:", next)
+	}
+}
+
+func TestSunJobs(t *testing.T) {
+	var cmd, storage, _ = newTestCommander(t)
+	var sun = NewSun()
+	var sched = NewScheduler(cmd, sun, newTestLog(t))
+
+	var err = storage.AddDevice("relay0", "Relay", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sun job is planned from sunset with offset
+	var tz = time.FixedZone("MSK", 3*60*60)
+	sun.SetLocation(55.75, 37.62, tz)
+
+	var job = Job{ID: 1, Sun: SunSunset, Offset: -30 * time.Minute, Target: "relay0", Action: ActionOn,
+		Enabled: true, Created: time.Date(2021, 6, 21, 12, 0, 0, 0, tz)}
+	err = sched.Restore(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sunset = sun.Times(job.Created).Sunset
+	job, _ = sched.Job(1)
+	if !job.Next.Equal(sunset.Add(-30 * time.Minute)) {
+		t.Error("wrong sun job next run:", job.Next)
+	}
+}
@@ -0,0 +1,281 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// Timers default settings
+const (
+	TimersInterval   = time.Second
+	TimerMaxDuration = 24 * time.Hour
+)
+
+// Timer Relay countdown timer, relay is switched off at Until time
+type Timer struct {
+	Device   string
+	Duration time.Duration
+	Started  time.Time
+	Until    time.Time
+}
+
+// Timers Relays countdown timers
+type Timers struct {
+	storage *Storage
+	cmd     *Commander
+	log     *utils.Log
+
+	mtx    sync.Mutex
+	timers map[string]*Timer
+	save   func() error
+	stop   chan struct{}
+}
+
+// NewTimers Make new relays timers, commander cancels timers of relays
+// which are switched off
+func NewTimers(s *Storage, c *Commander, l *utils.Log) *Timers {
+	var t = &Timers{
+		storage: s,
+		cmd:     c,
+		log:     l,
+		timers:  make(map[string]*Timer),
+	}
+	c.setTimers(t)
+
+	return t
+}
+
+// SetSaver Set timers persistence hook, it is called after timers
+// changes
+func (t *Timers) SetSaver(save func() error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.save = save
+}
+
+// Set Switch relay on by actor and plan switching off after duration.
+// Active relay timer is replaced
+func (t *Timers) Set(actor string, relay *base.Relay, duration time.Duration) error {
+	if duration <= 0 || duration > TimerMaxDuration {
+		return errors.New("Wrong timer duration")
+	}
+	if relay.Mode() == base.RelayPulse {
+		return errors.New("Pulse relay can't have timer")
+	}
+
+	// Pending relay is turned on by commander later
+	var err = t.cmd.SetStatus(actor, relay, true)
+	if err != nil && !IsPending(err) {
+		return err
+	}
+
+	var now = time.Now()
+
+	t.mtx.Lock()
+	t.timers[relay.Name()] = &Timer{
+		Device:   relay.Name(),
+		Duration: duration,
+		Started:  now,
+		Until:    now.Add(duration),
+	}
+	t.mtx.Unlock()
+
+	t.log.Info("TIMERS", "Relay \""+relay.Name()+"\" timer for "+duration.String())
+	return t.saveTimers()
+}
+
+// Restore Add saved timer, expired timer switches relay off on next check
+func (t *Timers) Restore(timer Timer) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if _, ok := t.storage.Device(timer.Device).(*base.Relay); !ok {
+		return errors.New("Relay not found")
+	}
+	if t.timers[timer.Device] != nil {
+		return errors.New("Timer already exists")
+	}
+
+	t.timers[timer.Device] = &timer
+	return nil
+}
+
+// Cancel Remove relay timer without changing relay status
+func (t *Timers) Cancel(device string) (bool, error) {
+	t.mtx.Lock()
+	var _, found = t.timers[device]
+	delete(t.timers, device)
+	t.mtx.Unlock()
+
+	if !found {
+		return false, nil
+	}
+
+	t.log.Info("TIMERS", "Relay \""+device+"\" timer canceled")
+	return true, t.saveTimers()
+}
+
+// Timer Get relay timer
+func (t *Timers) Timer(device string) (Timer, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var timer = t.timers[device]
+	if timer == nil {
+		return Timer{}, false
+	}
+	return *timer, true
+}
+
+// Timers Get all timers sorted by device
+func (t *Timers) Timers() []Timer {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var list []Timer
+
+	for _, timer := range t.timers {
+		list = append(list, *timer)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Device < list[j].Device
+	})
+
+	return list
+}
+
+// Remaining Get time left before relay switching off, zero if relay
+// has no timer
+func (t *Timers) Remaining(device string, now time.Time) time.Duration {
+	var timer, ok = t.Timer(device)
+	if !ok || !timer.Until.After(now) {
+		return 0
+	}
+	return timer.Until.Sub(now)
+}
+
+// RenameDevice Move timer to new relay name
+func (t *Timers) RenameDevice(device string, newName string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var timer = t.timers[device]
+	if timer == nil {
+		return
+	}
+
+	delete(t.timers, device)
+	timer.Device = newName
+	t.timers[newName] = timer
+}
+
+// ForgetDevice Remove relay timer
+func (t *Timers) ForgetDevice(device string) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var _, found = t.timers[device]
+	delete(t.timers, device)
+
+	return found
+}
+
+// Start Start timers checking in background
+func (t *Timers) Start(interval time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.stop != nil {
+		return
+	}
+	t.stop = make(chan struct{})
+
+	go t.run(interval, t.stop)
+}
+
+// Stop Stop timers checking
+func (t *Timers) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+// Check Switch off relays with expired timers. Timer is removed by
+// commander when switching off is applied and saved, failed timer is
+// kept and switching off is retried on next check
+func (t *Timers) Check(now time.Time) {
+	var expired []Timer
+
+	t.mtx.Lock()
+	for _, timer := range t.timers {
+		if !timer.Until.After(now) {
+			expired = append(expired, *timer)
+		}
+	}
+	t.mtx.Unlock()
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].Device < expired[j].Device
+	})
+	for _, timer := range expired {
+		var relay, ok = t.storage.Device(timer.Device).(*base.Relay)
+		if !ok {
+			t.log.Error("TIMERS", "Relay \""+timer.Device+"\" timer", "Relay not found")
+			t.Cancel(timer.Device)
+			continue
+		}
+
+		var err = t.cmd.SetStatus(ActorTimers, relay, false)
+		if err != nil {
+			t.log.Error("TIMERS", "Relay \""+timer.Device+"\" timer, switching off is retried", err.Error())
+			continue
+		}
+		t.log.Info("TIMERS", "Relay \""+timer.Device+"\" switched off by timer")
+	}
+}
+
+func (t *Timers) saveTimers() error {
+	t.mtx.Lock()
+	var save = t.save
+	t.mtx.Unlock()
+
+	if save == nil {
+		return nil
+	}
+	return save()
+}
+
+func (t *Timers) run(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.Check(now)
+		}
+	}
+}
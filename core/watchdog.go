@@ -0,0 +1,154 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/utils"
+)
+
+// Watchdog default settings
+const (
+	WatchdogInterval = time.Second
+	WatchdogTimeout  = time.Minute
+)
+
+// Watchdog Devices offline detector
+type Watchdog struct {
+	storage *Storage
+	events  *Events
+	log     *utils.Log
+
+	mtx      sync.Mutex
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	online   map[int]bool
+	stop     chan struct{}
+}
+
+// NewWatchdog Make new devices watchdog
+func NewWatchdog(s *Storage, e *Events, l *utils.Log) *Watchdog {
+	return &Watchdog{
+		storage:  s,
+		events:   e,
+		log:      l,
+		timeout:  WatchdogTimeout,
+		timeouts: make(map[string]time.Duration),
+		online:   make(map[int]bool),
+	}
+}
+
+// SetTimeout Set default offline timeout
+func (w *Watchdog) SetTimeout(timeout time.Duration) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.timeout = timeout
+}
+
+// SetTypeTimeout Set offline timeout for device type
+func (w *Watchdog) SetTypeTimeout(devType string, timeout time.Duration) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.timeouts[devType] = timeout
+}
+
+// Timeout Get offline timeout for device type
+func (w *Watchdog) Timeout(devType string) time.Duration {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	var timeout, ok = w.timeouts[devType]
+	if !ok {
+		return w.timeout
+	}
+	return timeout
+}
+
+// Start Start devices checking in background
+func (w *Watchdog) Start(interval time.Duration) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stop != nil {
+		return
+	}
+	w.stop = make(chan struct{})
+
+	go w.run(interval, w.stop)
+}
+
+// Stop Stop devices checking
+func (w *Watchdog) Stop() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+// Check Mark expired devices offline and publish online transitions
+func (w *Watchdog) Check() {
+	var list = w.storage.Devices()
+
+	w.mtx.Lock()
+	var changed []devices.IDevice
+	var online = make(map[int]bool)
+
+	for _, dev := range list {
+		var timeout, ok = w.timeouts[dev.Type()]
+		if !ok {
+			timeout = w.timeout
+		}
+
+		if timeout > 0 {
+			dev.SetOfflineIfStale(timeout)
+		}
+
+		online[dev.ID()] = dev.Online()
+		if online[dev.ID()] != w.online[dev.ID()] {
+			changed = append(changed, dev)
+		}
+	}
+	w.online = online
+	w.mtx.Unlock()
+
+	for _, dev := range changed {
+		var name = dev.Name()
+		if online[dev.ID()] {
+			w.log.Info("WATCHDOG", "Device \""+name+"\" is online")
+			w.events.Publish(Event{Type: EventOnline, Actor: ActorWatchdog, Device: name})
+		} else {
+			w.log.Info("WATCHDOG", "Device \""+name+"\" is offline")
+			w.events.Publish(Event{Type: EventOffline, Actor: ActorWatchdog, Device: name})
+		}
+	}
+}
+
+func (w *Watchdog) run(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	var log = newTestLog(t)
+	var storage = newTestStorage(log)
+	var events = NewEvents()
+	var sub = events.Subscribe(0, EventOnline, EventOffline)
+	var dog = NewWatchdog(storage, events, log)
+
+	var err = storage.AddDevice("relay0", "Relay", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.AddDevice("light0", "Light", "light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relay = storage.Device("relay0")
+	var light = storage.Device("light0")
+
+	// Type timeout overrides default one, zero timeout disables checking
+	dog.SetTimeout(0)
+	dog.SetTypeTimeout("relay", 20*time.Millisecond)
+	if dog.Timeout("relay") != 20*time.Millisecond || dog.Timeout("light") != 0 {
+		t.Fatal("wrong timeouts:", dog.Timeout("relay"), dog.Timeout("light"))
+	}
+
+	// Offline devices are not reported
+	dog.Check()
+	noEvent(t, sub)
+
+	// Report makes device online
+	relay.SetOnline(true)
+	light.SetOnline(true)
+	dog.Check()
+	for i := 0; i < 2; i++ {
+		var event = nextEvent(t, sub)
+		if event.Type != EventOnline || event.Actor != ActorWatchdog {
+			t.Error("wrong online event:", event)
+		}
+	}
+	dog.Check()
+	noEvent(t, sub)
+
+	// Only expired device goes offline
+	time.Sleep(40 * time.Millisecond)
+	dog.Check()
+	var event = nextEvent(t, sub)
+	if event.Type != EventOffline || event.Device != "relay0" || relay.Online() {
+		t.Error("wrong offline event:", event, relay.Online())
+	}
+	noEvent(t, sub)
+	if !light.Online() {
+		t.Error("device without timeout is offline")
+	}
+}
+
+func TestOfflineIfStale(t *testing.T) {
+	var storage = newTestStorage(newTestLog(t))
+	var err = storage.AddDevice("relay0", "Relay", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relay = storage.Device("relay0")
+
+	// Offline device is not changed
+	if relay.SetOfflineIfStale(0) {
+		t.Error("offline device is set offline")
+	}
+
+	// Fresh report keeps device online
+	relay.SetOnline(true)
+	if relay.SetOfflineIfStale(time.Minute) || !relay.Online() {
+		t.Error("fresh device is set offline")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if !relay.SetOfflineIfStale(5*time.Millisecond) || relay.Online() {
+		t.Error("stale device is online")
+	}
+}
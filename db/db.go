@@ -11,7 +11,9 @@
 package db
 
 import (
-	"strconv"
+	"errors"
+	"os"
+	"sync"
 
 	"github.com/futcity/controller/auth"
 	"github.com/futcity/controller/core"
@@ -28,22 +30,50 @@ type Database struct {
 	cfg     *utils.Configs
 	aut     *auth.Authorization
 	storage *core.Storage
+	fw      *core.Firmware
+	groups  *core.Groups
+	scenes  *core.Scenes
+	locks   *core.Interlocks
+	sched   *core.Scheduler
+	rules   *core.Rules
+	timers  *core.Timers
 	log     *utils.Log
 
 	// Local variables
+	mtx       sync.Mutex
 	fileNames map[string]string
 	dbType    int
+	bases     map[string]func() interface{}
+	refBases  []string
+}
+
+// Batch Databases which are saved together
+type Batch struct {
+	d     *Database
+	bases []string
 }
 
 func NewDatabase(c *utils.Configs, a *auth.Authorization, s *core.Storage,
-	l *utils.Log) *Database {
-	return &Database{
+	fw *core.Firmware, g *core.Groups, sc *core.Scenes, il *core.Interlocks, sch *core.Scheduler,
+	r *core.Rules, t *core.Timers, l *utils.Log) *Database {
+	var d = &Database{
 		cfg:       c,
 		aut:       a,
 		storage:   s,
+		fw:        fw,
+		groups:    g,
+		scenes:    sc,
+		locks:     il,
+		sched:     sch,
+		rules:     r,
+		timers:    t,
 		log:       l,
 		fileNames: make(map[string]string),
 	}
+
+	d.registerBases()
+
+	return d
 }
 
 func (d *Database) SetDBType(typ string) {
@@ -56,6 +86,187 @@ func (d *Database) AddFilename(db string, fileName string) {
 	d.fileNames[db] = fileName
 }
 
+// fileName Get database file name. Database which is not configured is
+// stored in working directory
+func (d *Database) fileName(db string) string {
+	var fileName, ok = d.fileNames[db]
+	if !ok {
+		return db + ".json"
+	}
+	return fileName
+}
+
+// loadBase Load database file. Missing file is loaded as empty base, so
+// installations without new databases are started and the file is made
+// on first save
+func (d *Database) loadBase(db string, base interface{}) error {
+	var err = d.cfg.LoadFromFile(base, d.fileName(db))
+	if os.IsNotExist(err) {
+		d.log.Info("DB", "Database \""+db+"\" not found, empty base is used")
+		return nil
+	}
+	return err
+}
+
+// RegisterBase Register device type database, it is saved with device
+// type changes and in batches
+func (d *Database) RegisterBase(devType string, load func() error, base func() interface{}) error {
+	var err = d.storage.RegisterBase(devType, load, func() error {
+		return d.saveBase(devType)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.bases[devType] = base
+
+	return nil
+}
+
+// saveBase Save database files. All databases are saved under one lock,
+// so saving waits for running batch
+func (d *Database) saveBase(dbs ...string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, db := range dbs {
+		var err = d.cfg.SaveToFile(d.bases[db](), d.fileName(db))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Batch Save databases together. Change function applies changes and
+// adds changed databases to batch. Only these databases are written to
+// temporary files which are moved in place when all of them were
+// written, so failed saving keeps all old files
+func (d *Database) Batch(change func(b *Batch) error) error {
+	var b = &Batch{d: d}
+
+	var err = change(b)
+	if err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var files []string
+	var saved = make(map[string]bool)
+
+	for _, db := range b.bases {
+		if saved[db] {
+			continue
+		}
+		saved[db] = true
+
+		var base, ok = d.bases[db]
+		if !ok {
+			err = errors.New("Unknown database \"" + db + "\"")
+			break
+		}
+
+		var fileName = d.fileName(db)
+		err = d.cfg.SaveToFile(base(), fileName+".tmp")
+		if err != nil {
+			os.Remove(fileName + ".tmp")
+			break
+		}
+		files = append(files, fileName)
+	}
+
+	if err != nil {
+		for _, fileName := range files {
+			os.Remove(fileName + ".tmp")
+		}
+		return err
+	}
+
+	return d.moveBases(files)
+}
+
+// moveBases Move temporary files of batch in place. Replaced files are
+// kept until all files are moved and are restored when moving fails
+func (d *Database) moveBases(files []string) error {
+	for i, fileName := range files {
+		var err = moveBase(fileName)
+		if err == nil {
+			continue
+		}
+
+		d.log.Error("DB", "Fail to move database \""+fileName+"\"", err.Error())
+		for _, fileName := range files[i:] {
+			os.Remove(fileName + ".tmp")
+		}
+		for _, fileName := range files[:i] {
+			d.restoreBase(fileName)
+		}
+		return err
+	}
+
+	for _, fileName := range files {
+		os.Remove(fileName + ".old")
+	}
+
+	return nil
+}
+
+// moveBase Move temporary database file in place, replaced file is kept
+// as old one
+func moveBase(fileName string) error {
+	os.Remove(fileName + ".old")
+
+	var err = os.Rename(fileName, fileName+".old")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Rename(fileName+".tmp", fileName)
+	if err != nil {
+		os.Rename(fileName+".old", fileName)
+	}
+
+	return err
+}
+
+// restoreBase Put replaced database file back. File which was made by
+// batch is removed
+func (d *Database) restoreBase(fileName string) {
+	var err = os.Rename(fileName+".old", fileName)
+	if os.IsNotExist(err) {
+		err = os.Remove(fileName)
+	}
+	if err != nil {
+		d.log.Error("DB", "Fail to restore database \""+fileName+"\"", err.Error())
+	}
+}
+
+// Save Add databases to batch
+func (b *Batch) Save(dbs ...string) {
+	b.bases = append(b.bases, dbs...)
+}
+
+// SaveDevice Add devices list, device type database and databases of
+// all device references keepers to batch
+func (b *Batch) SaveDevice(devType string) {
+	b.Save("device")
+
+	b.d.mtx.Lock()
+	var _, ok = b.d.bases[devType]
+	b.d.mtx.Unlock()
+
+	if ok {
+		b.Save(devType)
+	}
+	b.Save(b.d.refBases...)
+}
+
 //
 // Main database managment
 //
@@ -64,41 +275,119 @@ func (d *Database) LoadDeviceBase() error {
 	var devices DeviceDB
 
 	if d.dbType == DbTextType {
-		var err = d.cfg.LoadFromFile(&devices, d.fileNames["device"])
+		var err = d.cfg.LoadFromFile(&devices, d.fileName("device"))
 		if err != nil {
 			return err
 		}
 
+		d.storage.SetLastID(devices.LastID)
+
+		// Multi relay boards of old types get channels count from type name
+		var boards = make(map[string]int)
+		for i, device := range devices.Devices {
+			if count, ok := multiRelayLegacyTypes[device.Type]; ok {
+				devices.Devices[i].Type = "multirelay"
+				boards[device.Name] = count
+			}
+		}
+
+		// Restore devices with saved IDs
+		var migrate []SingleDeviceDb
 		for _, device := range devices.Devices {
-			d.storage.AddDevice(device.Name, device.Description, device.Type)
+			if device.ID <= 0 {
+				migrate = append(migrate, device)
+				continue
+			}
+
+			err = d.storage.RestoreDevice(device.ID, device.Name, device.Description, device.Type)
+			if err != nil {
+				if d.storage.Device(device.Name) == nil && d.storage.IsType(device.Type) {
+					migrate = append(migrate, device)
+					continue
+				}
+				d.log.Error("DB", "Fail to add device \""+device.Name+"\" type \""+device.Type+"\"", err.Error())
+				continue
+			}
+			d.restoreDeviceMeta(device)
 			d.log.Info("DB", "Add new device \""+device.Name+"\" desc \""+device.Description+"\" type \""+device.Type+"\"")
 		}
+
+		// Give new IDs to devices from old bases
+		for _, device := range migrate {
+			err = d.storage.AddDevice(device.Name, device.Description, device.Type)
+			if err != nil {
+				d.log.Error("DB", "Fail to add device \""+device.Name+"\" type \""+device.Type+"\"", err.Error())
+				continue
+			}
+			d.restoreDeviceMeta(device)
+			d.log.Info("DB", "Add new device \""+device.Name+"\" desc \""+device.Description+"\" type \""+device.Type+"\" with new ID")
+		}
+
+		for name, count := range boards {
+			if relay, ok := d.storage.Device(name).(*base.MultiRelay); ok {
+				relay.SetChannels(count)
+			}
+		}
+
+		if len(migrate) > 0 || len(boards) > 0 {
+			return d.SaveDeviceBase()
+		}
 	}
 
 	return nil
 }
 
+// restoreDeviceMeta Set loaded metadata to storage device
+func (d *Database) restoreDeviceMeta(device SingleDeviceDb) {
+	var dev = d.storage.Device(device.Name)
+
+	dev.SetRoom(device.Room)
+	dev.SetFirmware(device.Firmware)
+	dev.SetIP(device.IP)
+	dev.SetMAC(device.MAC)
+
+	for _, tag := range device.Tags {
+		dev.AddTag(tag)
+	}
+	for key, value := range device.Attributes {
+		dev.SetAttribute(key, value)
+	}
+}
+
 func (d *Database) SaveDeviceBase() error {
+	return d.saveBase("device")
+}
+
+// deviceBase Make devices list database
+func (d *Database) deviceBase() interface{} {
 	var devices DeviceDB
 
 	if d.dbType == DbTextType {
+		devices.LastID = d.storage.LastID()
 		for _, device := range d.storage.Devices() {
 			devices.Devices = append(devices.Devices, SingleDeviceDb{
+				ID:          device.ID(),
 				Name:        device.Name(),
 				Description: device.Description(),
 				Type:        device.Type(),
+				Room:        device.Room(),
+				Tags:        device.Tags(),
+				Firmware:    device.Firmware(),
+				IP:          device.IP(),
+				MAC:         device.MAC(),
+				Attributes:  device.Attributes(),
 			})
 		}
 	}
 
-	return d.cfg.SaveToFile(&devices, d.fileNames["device"])
+	return &devices
 }
 
 func (d *Database) LoadProfileBase() error {
 	var profiles ProfileDB
 
 	if d.dbType == DbTextType {
-		var err = d.cfg.LoadFromFile(&profiles, d.fileNames["profile"])
+		var err = d.cfg.LoadFromFile(&profiles, d.fileName("profile"))
 		if err != nil {
 			return err
 		}
@@ -108,7 +397,11 @@ func (d *Database) LoadProfileBase() error {
 
 			var profile = auth.NewProfile(prof.Name, prof.Key, prof.Admin)
 			for _, pdev := range prof.Devices {
-				profile.AddDevice(auth.NewProfileDevice(pdev.Name, pdev.Read, pdev.Write))
+				var dev = auth.NewProfileDevice(pdev.Name, pdev.Read, pdev.Write)
+				for _, ch := range pdev.Channels {
+					dev.AddChannel(auth.NewProfileChannel(ch.Channel, ch.Read, ch.Write))
+				}
+				profile.AddDevice(dev)
 				d.log.Info("DB", "Add new profile \""+prof.Name+"\" device \""+pdev.Name+"\"")
 			}
 			for _, grp := range prof.Groups {
@@ -124,6 +417,11 @@ func (d *Database) LoadProfileBase() error {
 }
 
 func (d *Database) SaveProfileBase() error {
+	return d.saveBase("profile")
+}
+
+// profileBase Make profiles database
+func (d *Database) profileBase() interface{} {
 	var profiles ProfileDB
 
 	for _, profile := range d.aut.Profiles() {
@@ -133,58 +431,25 @@ func (d *Database) SaveProfileBase() error {
 			Admin: profile.Admin(),
 		}
 
-		p.Groups = make([]string, len(*profile.Groups()))
-		copy(p.Groups, *profile.Groups())
+		p.Groups = profile.Groups()
 
 		for _, dev := range profile.Devices() {
-			p.Devices = append(p.Devices, ProfileDeivceDB{
+			var pdev = ProfileDeivceDB{
 				Name:  dev.Name(),
 				Read:  dev.Read(),
 				Write: dev.Write(),
-			})
-		}
-		profiles.Profiles = append(profiles.Profiles, p)
-	}
-
-	return d.cfg.SaveToFile(&profiles, d.fileNames["profile"])
-}
-
-//
-// Devices database managment
-//
-
-func (d *Database) LoadRelayBase() error {
-	var relays RelaysDB
-
-	if d.dbType == DbTextType {
-		var err = d.cfg.LoadFromFile(&relays, d.fileNames["relay"])
-		if err != nil {
-			return err
-		}
-
-		for _, relay := range relays.Relays {
-			var r = d.storage.Device(relay.Name)
-			if r != nil {
-				r.(*base.Relay).SetStatus(relay.Status)
-				d.log.Info("DB", "Load relay status \""+relay.Name+"\" status \""+strconv.FormatBool(relay.Status)+"\"")
 			}
+			for _, ch := range dev.Channels() {
+				pdev.Channels = append(pdev.Channels, ProfileChannelDB{
+					Channel: ch.Channel(),
+					Read:    ch.Read(),
+					Write:   ch.Write(),
+				})
+			}
+			p.Devices = append(p.Devices, pdev)
 		}
+		profiles.Profiles = append(profiles.Profiles, p)
 	}
 
-	return nil
-}
-
-func (d *Database) SaveRelayBase(name string, status bool) error {
-	var relays RelaysDB
-
-	if d.dbType == DbTextType {
-		for _, relay := range d.storage.DevicesByType("relay") {
-			relays.Relays = append(relays.Relays, SingleRelayDB{
-				Name:   relay.Name(),
-				Status: relay.(*base.Relay).Status(),
-			})
-		}
-	}
-
-	return d.cfg.SaveToFile(&relays, d.fileNames["relay"])
+	return &profiles
 }
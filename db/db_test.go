@@ -0,0 +1,270 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/utils"
+)
+
+// newTestDatabase Make database with all dependencies in temporary
+// directory
+func newTestDatabase(t *testing.T) (*Database, string) {
+	var dir, err = ioutil.TempDir("", "futcity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	var log = utils.NewLog()
+	log.SetPath(dir + string(os.PathSeparator))
+
+	var storage = core.NewStorage(log)
+	storage.RegisterType("light", func(name string, desc string) devices.IDevice {
+		return base.NewLight(name, desc, false, nil)
+	})
+	storage.RegisterType("multirelay", func(name string, desc string) devices.IDevice {
+		return base.NewMultiRelay(name, desc)
+	})
+	var events = core.NewEvents()
+	var scenes = core.NewScenes()
+	var locks = core.NewInterlocks()
+	var cmd = core.NewCommander(storage, core.NewReconciler(storage, events, log), scenes, locks, events, log)
+	var sun = core.NewSun()
+
+	var d = NewDatabase(utils.NewConfigs(), auth.NewAuthorization(), storage, core.NewFirmware(storage, events, log),
+		core.NewGroups(), scenes, locks, core.NewScheduler(cmd, sun, log), core.NewRules(storage, cmd, events, sun, log),
+		core.NewTimers(storage, cmd, log), log)
+	d.SetDBType("text")
+
+	err = d.RegisterBase("light", d.LoadLightBase, d.LightBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.RegisterBase("multirelay", d.LoadMultiRelayBase, d.MultiRelayBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d, dir
+}
+
+func TestMissingBases(t *testing.T) {
+	var d, dir = newTestDatabase(t)
+
+	// Upgraded installation has no new bases files
+	var lightFile = filepath.Join(dir, "light.json")
+	d.AddFilename("light", lightFile)
+	d.AddFilename("group", filepath.Join(dir, "group.json"))
+
+	var loads = []func() error{
+		d.LoadLightBase,
+		d.LoadDimmerBase,
+		d.LoadMeterBase,
+		d.LoadFirmwareBase,
+		d.LoadTokenBase,
+		d.LoadGroupBase,
+		d.LoadSceneBase,
+		d.LoadInterlockBase,
+		d.LoadScheduleBase,
+		d.LoadRuleBase,
+		d.LoadTimerBase,
+	}
+	for i, load := range loads {
+		var err = load()
+		if err != nil {
+			t.Errorf("missing base %d is not loaded: %s", i, err)
+		}
+	}
+
+	// Not configured base is stored in working directory
+	if d.fileName("dimmer") != "dimmer.json" || d.fileName("light") != lightFile {
+		t.Error("wrong base file names:", d.fileName("dimmer"), d.fileName("light"))
+	}
+
+	// Missing file is made on first save
+	var err = d.storage.AddDevice("light0", "Light", "light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.storage.Device("light0").(*base.Light).SetStatus(true)
+	err = d.storage.SaveBase("light")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.storage.Device("light0").(*base.Light).SetStatus(false)
+	err = d.LoadLightBase()
+	if err != nil || !d.storage.Device("light0").(*base.Light).Status() {
+		t.Error("saved base is not loaded:", err)
+	}
+
+	// Broken base still fails loading
+	err = ioutil.WriteFile(lightFile, []byte("{"), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.LoadLightBase()
+	if err == nil {
+		t.Error("broken base is loaded")
+	}
+}
+
+func TestMultiRelayMigration(t *testing.T) {
+	var d, dir = newTestDatabase(t)
+
+	var deviceFile = filepath.Join(dir, "device.json")
+	d.AddFilename("device", deviceFile)
+	d.AddFilename("multirelay", filepath.Join(dir, "multirelay.json"))
+
+	var err = ioutil.WriteFile(deviceFile, []byte(`{"lastid":2,"devices":[`+
+		`{"id":1,"name":"board0","description":"Garage","type":"relay4"},`+
+		`{"id":2,"name":"board1","description":"Yard","type":"relay8"}]}`), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "multirelay.json"), []byte(`{"multirelays":[`+
+		`{"name":"board0","channels":[{"description":"Gate","status":true},{},{},{}]}]}`), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.LoadDeviceBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.LoadMultiRelayBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Old board types are loaded as multi relays with channels count
+	for name, count := range map[string]int{"board0": 4, "board1": 8} {
+		var board, ok = d.storage.Device(name).(*base.MultiRelay)
+		if !ok || board.Type() != "multirelay" || len(board.Channels()) != count {
+			t.Fatal("wrong migrated board:", name, d.storage.Device(name))
+		}
+	}
+	var gate = d.storage.Device("board0").(*base.MultiRelay).Channel(0)
+	if gate.Description() != "Gate" || !gate.Status() {
+		t.Error("board channels are not loaded")
+	}
+
+	// Migrated types are saved
+	var devices DeviceDB
+	err = d.cfg.LoadFromFile(&devices, deviceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, device := range devices.Devices {
+		if device.Type != "multirelay" {
+			t.Error("old board type is saved:", device.Name, device.Type)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var d, dir = newTestDatabase(t)
+
+	var deviceFile = filepath.Join(dir, "device.json")
+	var groupFile = filepath.Join(dir, "group.json")
+	d.AddFilename("device", deviceFile)
+	d.AddFilename("group", groupFile)
+	d.AddFilename("scene", filepath.Join(dir, "scene.json"))
+
+	var err = d.SaveDeviceBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.SaveGroupBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed moving restores already moved bases
+	err = os.MkdirAll(filepath.Join(groupFile+".old", "busy"), 0770)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var change = func(b *Batch) error {
+		var err = d.storage.AddDevice("light0", "Light", "light")
+		if err != nil {
+			return err
+		}
+		err = d.groups.Add("hall")
+		if err != nil {
+			return err
+		}
+
+		b.Save("device", "group")
+		return nil
+	}
+	err = d.Batch(change)
+	if err == nil {
+		t.Fatal("failed batch is saved")
+	}
+
+	var devices DeviceDB
+	err = d.cfg.LoadFromFile(&devices, deviceFile)
+	if err != nil || len(devices.Devices) != 0 {
+		t.Error("moved base is not restored:", err, devices)
+	}
+	var tmp, _ = filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(tmp) != 0 {
+		t.Error("temporary bases are kept:", tmp)
+	}
+
+	// Only bases added to batch are saved
+	os.RemoveAll(groupFile + ".old")
+	err = d.Batch(func(b *Batch) error {
+		b.Save("device", "group")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.cfg.LoadFromFile(&devices, deviceFile)
+	if err != nil || len(devices.Devices) != 1 {
+		t.Error("batch base is not saved:", err, devices)
+	}
+	var groups GroupDB
+	err = d.cfg.LoadFromFile(&groups, groupFile)
+	if err != nil || len(groups.Groups) != 1 {
+		t.Error("batch base is not saved:", err, groups)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "scene.json")); !os.IsNotExist(err) {
+		t.Error("base out of batch is saved:", err)
+	}
+	var old, _ = filepath.Glob(filepath.Join(dir, "*.old"))
+	if len(old) != 0 {
+		t.Error("replaced bases are kept:", old)
+	}
+
+	// Unknown base fails batch
+	err = d.Batch(func(b *Batch) error {
+		b.Save("device", "unknown")
+		return nil
+	})
+	if err == nil {
+		t.Error("unknown base is saved")
+	}
+}
@@ -11,11 +11,19 @@
 package db
 
 type SingleDeviceDb struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
+	ID          int               `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Type        string            `json:"type"`
+	Room        string            `json:"room,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Firmware    string            `json:"firmware,omitempty"`
+	IP          string            `json:"ip,omitempty"`
+	MAC         string            `json:"mac,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
 }
 
 type DeviceDB struct {
+	LastID  int              `json:"lastid,omitempty"`
 	Devices []SingleDeviceDb `json:"devices"`
 }
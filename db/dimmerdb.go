@@ -0,0 +1,63 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+type SingleDimmerDB struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+type DimmersDB struct {
+	Dimmers []SingleDimmerDB `json:"dimmers"`
+}
+
+func (d *Database) LoadDimmerBase() error {
+	var dimmers DimmersDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("dimmer", &dimmers)
+		if err != nil {
+			return err
+		}
+
+		for _, dimmer := range dimmers.Dimmers {
+			var dim, ok = d.storage.Device(dimmer.Name).(*base.Dimmer)
+			if ok {
+				dim.SetLevel(dimmer.Level, 0)
+				d.log.Info("DB", "Load dimmer level \""+dimmer.Name+"\" level \""+strconv.Itoa(dimmer.Level)+"\"")
+			}
+		}
+	}
+
+	return nil
+}
+
+// DimmerBase Make dimmers database
+func (d *Database) DimmerBase() interface{} {
+	var dimmers DimmersDB
+
+	if d.dbType == DbTextType {
+		for _, dimmer := range d.storage.DevicesByType("dimmer") {
+			dimmers.Dimmers = append(dimmers.Dimmers, SingleDimmerDB{
+				Name:  dimmer.Name(),
+				Level: dimmer.(*base.Dimmer).Target(),
+			})
+		}
+	}
+
+	return &dimmers
+}
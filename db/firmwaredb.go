@@ -0,0 +1,114 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+type FirmwareImageDB struct {
+	Type     string `json:"type"`
+	Version  string `json:"version"`
+	Size     int    `json:"size"`
+	MD5      string `json:"md5"`
+	SHA256   string `json:"sha256"`
+	Uploaded int64  `json:"uploaded"`
+}
+
+type FirmwareRolloutDB struct {
+	Device   int    `json:"device"`
+	From     string `json:"from"`
+	Target   string `json:"target"`
+	Reported string `json:"reported"`
+	State    string `json:"state"`
+	Updated  int64  `json:"updated"`
+}
+
+type FirmwareDB struct {
+	Images   []FirmwareImageDB   `json:"images"`
+	Rollouts []FirmwareRolloutDB `json:"rollouts"`
+}
+
+func (d *Database) LoadFirmwareBase() error {
+	var firmware FirmwareDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("firmware", &firmware)
+		if err != nil {
+			return err
+		}
+
+		for _, img := range firmware.Images {
+			d.fw.RestoreImage(core.FirmwareImage{
+				Type:     img.Type,
+				Version:  img.Version,
+				Size:     img.Size,
+				MD5:      img.MD5,
+				SHA256:   img.SHA256,
+				Uploaded: time.Unix(img.Uploaded, 0),
+			})
+			d.log.Info("DB", "Load firmware type \""+img.Type+"\" version \""+img.Version+"\"")
+		}
+
+		for _, rollout := range firmware.Rollouts {
+			if _, err = d.storage.DeviceByID(rollout.Device); err != nil {
+				continue
+			}
+			d.fw.RestoreRollout(core.FirmwareRollout{
+				Device:   rollout.Device,
+				From:     rollout.From,
+				Target:   rollout.Target,
+				Reported: rollout.Reported,
+				State:    rollout.State,
+				Updated:  time.Unix(rollout.Updated, 0),
+			})
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveFirmwareBase() error {
+	return d.saveBase("firmware")
+}
+
+// firmwareBase Make firmware database
+func (d *Database) firmwareBase() interface{} {
+	var firmware FirmwareDB
+
+	if d.dbType == DbTextType {
+		for _, img := range d.fw.Images() {
+			firmware.Images = append(firmware.Images, FirmwareImageDB{
+				Type:     img.Type,
+				Version:  img.Version,
+				Size:     img.Size,
+				MD5:      img.MD5,
+				SHA256:   img.SHA256,
+				Uploaded: img.Uploaded.Unix(),
+			})
+		}
+
+		for _, rollout := range d.fw.Rollouts() {
+			firmware.Rollouts = append(firmware.Rollouts, FirmwareRolloutDB{
+				Device:   rollout.Device,
+				From:     rollout.From,
+				Target:   rollout.Target,
+				Reported: rollout.Reported,
+				State:    rollout.State,
+				Updated:  rollout.Updated.Unix(),
+			})
+		}
+	}
+
+	return &firmware
+}
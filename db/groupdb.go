@@ -0,0 +1,87 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+type SingleGroupDB struct {
+	Name    string   `json:"name"`
+	Devices []string `json:"devices"`
+}
+
+type GroupDB struct {
+	Groups []SingleGroupDB `json:"groups"`
+}
+
+// LoadGroupBase Load groups. Groups which exist only in profiles
+// are added as empty groups
+func (d *Database) LoadGroupBase() error {
+	var groups GroupDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("group", &groups)
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups.Groups {
+			err = d.groups.Add(group.Name)
+			if err != nil {
+				d.log.Error("DB", "Fail to add group \""+group.Name+"\"", err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new group \""+group.Name+"\"")
+
+			for _, dev := range group.Devices {
+				if d.storage.Device(dev) == nil {
+					d.log.Error("DB", "Fail to add group \""+group.Name+"\" device \""+dev+"\"", "Device not found")
+					continue
+				}
+				d.groups.AddDevice(group.Name, dev)
+			}
+		}
+
+		var migrated bool
+		for _, prof := range d.aut.Profiles() {
+			for _, grp := range prof.Groups() {
+				if _, ok := d.groups.Group(grp); !ok {
+					d.groups.Add(grp)
+					d.log.Info("DB", "Add new group \""+grp+"\" from profile \""+prof.Name()+"\"")
+					migrated = true
+				}
+			}
+		}
+
+		if migrated {
+			return d.SaveGroupBase()
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveGroupBase() error {
+	return d.saveBase("group")
+}
+
+// groupBase Make groups database
+func (d *Database) groupBase() interface{} {
+	var groups GroupDB
+
+	if d.dbType == DbTextType {
+		for _, group := range d.groups.Groups() {
+			groups.Groups = append(groups.Groups, SingleGroupDB{
+				Name:    group.Name,
+				Devices: group.Devices,
+			})
+		}
+	}
+
+	return &groups
+}
@@ -0,0 +1,101 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+type InterlockChannelDB struct {
+	Device  string `json:"device"`
+	Channel int    `json:"channel"`
+}
+
+type SingleInterlockDB struct {
+	Name     string               `json:"name"`
+	Policy   string               `json:"policy"`
+	Devices  []string             `json:"devices"`
+	Channels []InterlockChannelDB `json:"channels"`
+}
+
+type InterlockDB struct {
+	Interlocks []SingleInterlockDB `json:"interlocks"`
+}
+
+func (d *Database) LoadInterlockBase() error {
+	var locks InterlockDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("interlock", &locks)
+		if err != nil {
+			return err
+		}
+
+		for _, lock := range locks.Interlocks {
+			err = d.locks.Add(lock.Name, lock.Policy)
+			if err != nil {
+				d.log.Error("DB", "Fail to add interlock \""+lock.Name+"\"", err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new interlock \""+lock.Name+"\" policy \""+lock.Policy+"\"")
+
+			for _, dev := range lock.Devices {
+				if _, ok := d.storage.Device(dev).(*base.Relay); !ok {
+					d.log.Error("DB", "Fail to add interlock \""+lock.Name+"\" device \""+dev+"\"", "Relay not found")
+					continue
+				}
+				d.locks.AddDevice(lock.Name, dev, -1)
+			}
+
+			for _, ch := range lock.Channels {
+				var relay, ok = d.storage.Device(ch.Device).(*base.MultiRelay)
+				if !ok || relay.Channel(ch.Channel) == nil {
+					d.log.Error("DB", "Fail to add interlock \""+lock.Name+"\" device \""+ch.Device+"\" channel \""+
+						strconv.Itoa(ch.Channel)+"\"", "Channel not found")
+					continue
+				}
+				d.locks.AddDevice(lock.Name, ch.Device, ch.Channel)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveInterlockBase() error {
+	return d.saveBase("interlock")
+}
+
+// interlockBase Make interlocks database
+func (d *Database) interlockBase() interface{} {
+	var locks InterlockDB
+
+	if d.dbType == DbTextType {
+		for _, lock := range d.locks.Interlocks() {
+			var single = SingleInterlockDB{
+				Name:   lock.Name,
+				Policy: lock.Policy,
+			}
+			for _, m := range lock.Members {
+				if m.Channel < 0 {
+					single.Devices = append(single.Devices, m.Device)
+				} else {
+					single.Channels = append(single.Channels, InterlockChannelDB{Device: m.Device, Channel: m.Channel})
+				}
+			}
+			locks.Interlocks = append(locks.Interlocks, single)
+		}
+	}
+
+	return &locks
+}
@@ -0,0 +1,63 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+type SingleLightDB struct {
+	Name   string `json:"name"`
+	Status bool   `json:"status"`
+}
+
+type LightsDB struct {
+	Lights []SingleLightDB `json:"lights"`
+}
+
+func (d *Database) LoadLightBase() error {
+	var lights LightsDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("light", &lights)
+		if err != nil {
+			return err
+		}
+
+		for _, light := range lights.Lights {
+			var l, ok = d.storage.Device(light.Name).(*base.Light)
+			if ok {
+				l.SetStatus(light.Status)
+				d.log.Info("DB", "Load light status \""+light.Name+"\" status \""+strconv.FormatBool(light.Status)+"\"")
+			}
+		}
+	}
+
+	return nil
+}
+
+// LightBase Make lights database
+func (d *Database) LightBase() interface{} {
+	var lights LightsDB
+
+	if d.dbType == DbTextType {
+		for _, light := range d.storage.DevicesByType("light") {
+			lights.Lights = append(lights.Lights, SingleLightDB{
+				Name:   light.Name(),
+				Status: light.(*base.Light).Status(),
+			})
+		}
+	}
+
+	return &lights
+}
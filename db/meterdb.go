@@ -0,0 +1,89 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+type MeterTotalDB struct {
+	Period string  `json:"period"`
+	Energy float64 `json:"energy"`
+}
+
+type SingleMeterDB struct {
+	Name    string         `json:"name"`
+	Counter float64        `json:"counter"`
+	Total   float64        `json:"total"`
+	Daily   []MeterTotalDB `json:"daily"`
+	Monthly []MeterTotalDB `json:"monthly"`
+}
+
+type MetersDB struct {
+	Meters []SingleMeterDB `json:"meters"`
+}
+
+func (d *Database) LoadMeterBase() error {
+	var meters MetersDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("meter", &meters)
+		if err != nil {
+			return err
+		}
+
+		for _, meter := range meters.Meters {
+			var m, ok = d.storage.Device(meter.Name).(*base.Meter)
+			if !ok {
+				continue
+			}
+
+			var daily, monthly []base.MeterTotal
+			for _, day := range meter.Daily {
+				daily = append(daily, base.MeterTotal{Period: day.Period, Energy: day.Energy})
+			}
+			for _, month := range meter.Monthly {
+				monthly = append(monthly, base.MeterTotal{Period: month.Period, Energy: month.Energy})
+			}
+			m.Restore(meter.Counter, meter.Total, daily, monthly)
+			d.log.Info("DB", "Load meter \""+meter.Name+"\" total \""+strconv.FormatFloat(meter.Total, 'f', 3, 64)+"\"")
+		}
+	}
+
+	return nil
+}
+
+// MeterBase Make meters database
+func (d *Database) MeterBase() interface{} {
+	var meters MetersDB
+
+	if d.dbType == DbTextType {
+		for _, device := range d.storage.DevicesByType("meter") {
+			var meter = device.(*base.Meter)
+			var m = SingleMeterDB{
+				Name:    meter.Name(),
+				Counter: meter.Counter(),
+				Total:   meter.Total(),
+			}
+			for _, day := range meter.Daily() {
+				m.Daily = append(m.Daily, MeterTotalDB{Period: day.Period, Energy: day.Energy})
+			}
+			for _, month := range meter.Monthly() {
+				m.Monthly = append(m.Monthly, MeterTotalDB{Period: month.Period, Energy: month.Energy})
+			}
+			meters.Meters = append(meters.Meters, m)
+		}
+	}
+
+	return &meters
+}
@@ -0,0 +1,101 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
+// multiRelayLegacyTypes Old multi relay board types with channels count
+// in type name, they are loaded as "multirelay" boards
+var multiRelayLegacyTypes = map[string]int{
+	"relay2":  2,
+	"relay4":  4,
+	"relay8":  8,
+	"relay16": 16,
+}
+
+type RelayChannelDB struct {
+	Description string `json:"description"`
+	Status      bool   `json:"status"`
+}
+
+type SingleMultiRelayDB struct {
+	Name     string           `json:"name"`
+	Channels []RelayChannelDB `json:"channels"`
+}
+
+type MultiRelaysDB struct {
+	MultiRelays []SingleMultiRelayDB `json:"multirelays"`
+}
+
+// LoadMultiRelayBase Load channels of all boards, channels count is
+// restored from saved channels
+func (d *Database) LoadMultiRelayBase() error {
+	var relays MultiRelaysDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("multirelay", &relays)
+		if err != nil {
+			return err
+		}
+
+		for _, relay := range relays.MultiRelays {
+			var r, ok = d.storage.Device(relay.Name).(*base.MultiRelay)
+			if !ok {
+				continue
+			}
+
+			err = r.SetChannels(len(relay.Channels))
+			if err != nil {
+				d.log.Error("DB", "Fail to load multi relay \""+relay.Name+"\" channels", err.Error())
+				continue
+			}
+			for i, channel := range relay.Channels {
+				var ch = r.Channel(i)
+				ch.SetDescription(channel.Description)
+				ch.SetStatus(channel.Status)
+			}
+			d.log.Info("DB", "Load multi relay \""+relay.Name+"\" channels \""+strconv.Itoa(len(relay.Channels))+"\"")
+		}
+	}
+
+	return nil
+}
+
+// MultiRelayBase Make multi relay boards channels database
+func (d *Database) MultiRelayBase() interface{} {
+	var relays MultiRelaysDB
+
+	if d.dbType == DbTextType {
+		for _, device := range d.storage.Devices() {
+			var relay, ok = device.(*base.MultiRelay)
+			if !ok {
+				continue
+			}
+
+			var r = SingleMultiRelayDB{
+				Name: relay.Name(),
+			}
+			for _, channel := range relay.Channels() {
+				r.Channels = append(r.Channels, RelayChannelDB{
+					Description: channel.Description(),
+					Status:      channel.Status(),
+				})
+			}
+			relays.MultiRelays = append(relays.MultiRelays, r)
+		}
+	}
+
+	return &relays
+}
@@ -1,9 +1,16 @@
 package db
 
+type ProfileChannelDB struct {
+	Channel int  `json:"channel"`
+	Read    bool `json:"read"`
+	Write   bool `json:"write"`
+}
+
 type ProfileDeivceDB struct {
-	Name  string `json:"name"`
-	Read  bool   `json:"read"`
-	Write bool   `json:"write"`
+	Name     string             `json:"name"`
+	Read     bool               `json:"read"`
+	Write    bool               `json:"write"`
+	Channels []ProfileChannelDB `json:"channels,omitempty"`
 }
 
 type SingleProfileDB struct {
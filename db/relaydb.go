@@ -10,11 +10,78 @@
 
 package db
 
+import (
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/core/devices/base"
+)
+
 type SingleRelayDB struct {
 	Name   string `json:"name"`
 	Status bool   `json:"status"`
+	Mode   string `json:"mode"`
+	Width  int64  `json:"width"`
+	Pulse  int    `json:"pulse"`
 }
 
 type RelaysDB struct {
 	Relays []SingleRelayDB `json:"relays"`
 }
+
+func (d *Database) LoadRelayBase() error {
+	var relays RelaysDB
+
+	if d.dbType == DbTextType {
+		var err = d.cfg.LoadFromFile(&relays, d.fileName("relay"))
+		if err != nil {
+			return err
+		}
+
+		for _, relay := range relays.Relays {
+			var r, ok = d.storage.Device(relay.Name).(*base.Relay)
+			if ok {
+				// Relays from old bases are latching
+				var mode = relay.Mode
+				var width = time.Duration(relay.Width) * time.Millisecond
+				if mode == "" {
+					mode = base.RelayLatching
+				}
+				if !base.IsRelayMode(mode) {
+					d.log.Error("DB", "Fail to set relay \""+relay.Name+"\" mode \""+mode+"\"", "Unknown relay mode")
+					mode = base.RelayLatching
+				}
+				if width <= 0 {
+					width = base.RelayPulseWidth
+				}
+				r.SetMode(mode, width)
+				r.SetPulse(relay.Pulse)
+
+				r.SetStatus(relay.Status)
+				d.log.Info("DB", "Load relay status \""+relay.Name+"\" status \""+strconv.FormatBool(relay.Status)+"\"")
+			}
+		}
+	}
+
+	return nil
+}
+
+// RelayBase Make relays database
+func (d *Database) RelayBase() interface{} {
+	var relays RelaysDB
+
+	if d.dbType == DbTextType {
+		for _, relay := range d.storage.DevicesByType("relay") {
+			var r = relay.(*base.Relay)
+			relays.Relays = append(relays.Relays, SingleRelayDB{
+				Name:   r.Name(),
+				Status: r.Status(),
+				Mode:   r.Mode(),
+				Width:  int64(r.PulseWidth() / time.Millisecond),
+				Pulse:  r.Pulse(),
+			})
+		}
+	}
+
+	return &relays
+}
@@ -0,0 +1,142 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/core"
+)
+
+type RuleConditionDB struct {
+	Device string `json:"device,omitempty"`
+	Op     string `json:"op,omitempty"`
+	Value  string `json:"value,omitempty"`
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+}
+
+type RuleActionDB struct {
+	Target string `json:"target"`
+	Action string `json:"action"`
+	Level  int    `json:"level"`
+}
+
+type SingleRuleDB struct {
+	ID         int               `json:"id"`
+	Name       string            `json:"name"`
+	Enabled    bool              `json:"enabled"`
+	Trigger    string            `json:"trigger"`
+	Device     string            `json:"device,omitempty"`
+	State      string            `json:"state,omitempty"`
+	Cron       string            `json:"cron,omitempty"`
+	Conditions []RuleConditionDB `json:"conditions"`
+	Actions    []RuleActionDB    `json:"actions"`
+}
+
+type RuleDB struct {
+	LastID int            `json:"lastid"`
+	Rules  []SingleRuleDB `json:"rules"`
+}
+
+func (d *Database) LoadRuleBase() error {
+	var rules RuleDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("rule", &rules)
+		if err != nil {
+			return err
+		}
+
+		d.rules.SetLastID(rules.LastID)
+
+		for _, rule := range rules.Rules {
+			var r = core.Rule{
+				ID:      rule.ID,
+				Name:    rule.Name,
+				Enabled: rule.Enabled,
+				Trigger: rule.Trigger,
+				Device:  rule.Device,
+				State:   rule.State,
+				Cron:    rule.Cron,
+			}
+			for _, cond := range rule.Conditions {
+				r.Conditions = append(r.Conditions, core.RuleCondition{
+					Device: cond.Device,
+					Op:     cond.Op,
+					Value:  cond.Value,
+					After:  cond.After,
+					Before: cond.Before,
+				})
+			}
+			for _, action := range rule.Actions {
+				r.Actions = append(r.Actions, core.RuleAction{
+					Target: action.Target,
+					Action: action.Action,
+					Level:  action.Level,
+				})
+			}
+
+			err = d.rules.Restore(r)
+			if err != nil {
+				d.log.Error("DB", "Fail to add rule "+strconv.Itoa(rule.ID), err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new rule "+strconv.Itoa(rule.ID)+" \""+rule.Name+"\"")
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveRuleBase() error {
+	return d.saveBase("rule")
+}
+
+// ruleBase Make rules database
+func (d *Database) ruleBase() interface{} {
+	var rules RuleDB
+
+	if d.dbType == DbTextType {
+		rules.LastID = d.rules.LastID()
+
+		for _, rule := range d.rules.Rules() {
+			var r = SingleRuleDB{
+				ID:      rule.ID,
+				Name:    rule.Name,
+				Enabled: rule.Enabled,
+				Trigger: rule.Trigger,
+				Device:  rule.Device,
+				State:   rule.State,
+				Cron:    rule.Cron,
+			}
+			for _, cond := range rule.Conditions {
+				r.Conditions = append(r.Conditions, RuleConditionDB{
+					Device: cond.Device,
+					Op:     cond.Op,
+					Value:  cond.Value,
+					After:  cond.After,
+					Before: cond.Before,
+				})
+			}
+			for _, action := range rule.Actions {
+				r.Actions = append(r.Actions, RuleActionDB{
+					Target: action.Target,
+					Action: action.Action,
+					Level:  action.Level,
+				})
+			}
+			rules.Rules = append(rules.Rules, r)
+		}
+	}
+
+	return &rules
+}
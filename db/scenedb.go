@@ -0,0 +1,87 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import "github.com/futcity/controller/core"
+
+type SceneActionDB struct {
+	Device string `json:"device"`
+	Status bool   `json:"status"`
+	Level  int    `json:"level"`
+}
+
+type SingleSceneDB struct {
+	Name    string          `json:"name"`
+	Actions []SceneActionDB `json:"actions"`
+}
+
+type SceneDB struct {
+	Scenes []SingleSceneDB `json:"scenes"`
+}
+
+func (d *Database) LoadSceneBase() error {
+	var scenes SceneDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("scene", &scenes)
+		if err != nil {
+			return err
+		}
+
+		for _, scene := range scenes.Scenes {
+			err = d.scenes.Add(scene.Name)
+			if err != nil {
+				d.log.Error("DB", "Fail to add scene \""+scene.Name+"\"", err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new scene \""+scene.Name+"\"")
+
+			for _, action := range scene.Actions {
+				if d.storage.Device(action.Device) == nil {
+					d.log.Error("DB", "Fail to add scene \""+scene.Name+"\" device \""+action.Device+"\"", "Device not found")
+					continue
+				}
+				d.scenes.SetAction(scene.Name, core.SceneAction{
+					Device: action.Device,
+					Status: action.Status,
+					Level:  action.Level,
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveSceneBase() error {
+	return d.saveBase("scene")
+}
+
+// sceneBase Make scenes database
+func (d *Database) sceneBase() interface{} {
+	var scenes SceneDB
+
+	if d.dbType == DbTextType {
+		for _, scene := range d.scenes.Scenes() {
+			var sc = SingleSceneDB{Name: scene.Name}
+			for _, action := range scene.Actions {
+				sc.Actions = append(sc.Actions, SceneActionDB{
+					Device: action.Device,
+					Status: action.Status,
+					Level:  action.Level,
+				})
+			}
+			scenes.Scenes = append(scenes.Scenes, sc)
+		}
+	}
+
+	return &scenes
+}
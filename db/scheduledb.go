@@ -0,0 +1,123 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+type SingleJobDB struct {
+	ID      int    `json:"id"`
+	Cron    string `json:"cron,omitempty"`
+	Sun     string `json:"sun,omitempty"`
+	Offset  int    `json:"offset,omitempty"`
+	At      int64  `json:"at,omitempty"`
+	Target  string `json:"target"`
+	Action  string `json:"action"`
+	Level   int    `json:"level"`
+	Enabled bool   `json:"enabled"`
+	CatchUp bool   `json:"catchup"`
+	Created int64  `json:"created"`
+	LastRun int64  `json:"lastrun"`
+}
+
+type ScheduleDB struct {
+	LastID int           `json:"lastid"`
+	Jobs   []SingleJobDB `json:"jobs"`
+}
+
+func (d *Database) LoadScheduleBase() error {
+	var schedule ScheduleDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("schedule", &schedule)
+		if err != nil {
+			return err
+		}
+
+		d.sched.SetLastID(schedule.LastID)
+
+		for _, job := range schedule.Jobs {
+			err = d.sched.Restore(core.Job{
+				ID:      job.ID,
+				Cron:    job.Cron,
+				Sun:     job.Sun,
+				Offset:  time.Duration(job.Offset) * time.Minute,
+				At:      unixToTime(job.At),
+				Target:  job.Target,
+				Action:  job.Action,
+				Level:   job.Level,
+				Enabled: job.Enabled,
+				CatchUp: job.CatchUp,
+				Created: unixToTime(job.Created),
+				LastRun: unixToTime(job.LastRun),
+			})
+			if err != nil {
+				d.log.Error("DB", "Fail to add job "+strconv.Itoa(job.ID), err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new job "+strconv.Itoa(job.ID)+" \""+job.Action+"\" \""+job.Target+"\"")
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveScheduleBase() error {
+	return d.saveBase("schedule")
+}
+
+// scheduleBase Make scheduler jobs database
+func (d *Database) scheduleBase() interface{} {
+	var schedule ScheduleDB
+
+	if d.dbType == DbTextType {
+		schedule.LastID = d.sched.LastID()
+
+		for _, job := range d.sched.Jobs() {
+			schedule.Jobs = append(schedule.Jobs, SingleJobDB{
+				ID:      job.ID,
+				Cron:    job.Cron,
+				Sun:     job.Sun,
+				Offset:  int(job.Offset / time.Minute),
+				At:      timeToUnix(job.At),
+				Target:  job.Target,
+				Action:  job.Action,
+				Level:   job.Level,
+				Enabled: job.Enabled,
+				CatchUp: job.CatchUp,
+				Created: timeToUnix(job.Created),
+				LastRun: timeToUnix(job.LastRun),
+			})
+		}
+	}
+
+	return &schedule
+}
+
+// unixToTime Convert unix seconds to time, zero is converted to zero time
+func unixToTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// timeToUnix Convert time to unix seconds, zero time is converted to zero
+func timeToUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,79 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+type SingleTimerDB struct {
+	Device   string `json:"device"`
+	Duration int64  `json:"duration"`
+	Started  int64  `json:"started"`
+	Until    int64  `json:"until"`
+}
+
+type TimerDB struct {
+	Timers []SingleTimerDB `json:"timers"`
+}
+
+// LoadTimerBase Load relays timers. Timers which expired while
+// controller was stopped switch relays off on first check
+func (d *Database) LoadTimerBase() error {
+	var timers TimerDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("timer", &timers)
+		if err != nil {
+			return err
+		}
+
+		for _, timer := range timers.Timers {
+			err = d.timers.Restore(core.Timer{
+				Device:   timer.Device,
+				Duration: time.Duration(timer.Duration) * time.Second,
+				Started:  unixToTime(timer.Started),
+				Until:    unixToTime(timer.Until),
+			})
+			if err != nil {
+				d.log.Error("DB", "Fail to add relay \""+timer.Device+"\" timer", err.Error())
+				continue
+			}
+			d.log.Info("DB", "Add new relay \""+timer.Device+"\" timer")
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveTimerBase() error {
+	return d.saveBase("timer")
+}
+
+// timerBase Make relay timers database
+func (d *Database) timerBase() interface{} {
+	var timers TimerDB
+
+	if d.dbType == DbTextType {
+		for _, timer := range d.timers.Timers() {
+			timers.Timers = append(timers.Timers, SingleTimerDB{
+				Device:   timer.Device,
+				Duration: int64(timer.Duration / time.Second),
+				Started:  timeToUnix(timer.Started),
+				Until:    timeToUnix(timer.Until),
+			})
+		}
+	}
+
+	return &timers
+}
@@ -0,0 +1,74 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"time"
+
+	"github.com/futcity/controller/auth"
+)
+
+type DeviceTokenDB struct {
+	Hash    string `json:"hash"`
+	Device  string `json:"device"`
+	Created int64  `json:"created"`
+}
+
+type TokenDB struct {
+	Tokens []DeviceTokenDB `json:"tokens"`
+}
+
+func (d *Database) LoadTokenBase() error {
+	var tokens TokenDB
+
+	if d.dbType == DbTextType {
+		var err = d.loadBase("token", &tokens)
+		if err != nil {
+			return err
+		}
+
+		for _, token := range tokens.Tokens {
+			if d.storage.Device(token.Device) == nil {
+				continue
+			}
+
+			d.aut.RestoreDeviceToken(auth.DeviceToken{
+				Hash:    token.Hash,
+				Device:  token.Device,
+				Created: time.Unix(token.Created, 0),
+			})
+			d.log.Info("DB", "Load device \""+token.Device+"\" token")
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) SaveTokenBase() error {
+	return d.saveBase("token")
+}
+
+// tokenBase Make device tokens database
+func (d *Database) tokenBase() interface{} {
+	var tokens TokenDB
+
+	if d.dbType == DbTextType {
+		for _, token := range d.aut.DeviceTokens() {
+			tokens.Tokens = append(tokens.Tokens, DeviceTokenDB{
+				Hash:    token.Hash,
+				Device:  token.Device,
+				Created: token.Created.Unix(),
+			})
+		}
+	}
+
+	return &tokens
+}
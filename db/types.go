@@ -0,0 +1,61 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package db
+
+import (
+	"github.com/futcity/controller/core"
+)
+
+// refsBase Device references keeper databases
+type refsBase struct {
+	name  string
+	refs  core.DeviceRefs
+	bases []string
+}
+
+// registerBases Register main databases, device references keepers,
+// scheduler and timers. Device types databases are registered by device
+// types
+func (d *Database) registerBases() {
+	d.bases = map[string]func() interface{}{
+		"device":    d.deviceBase,
+		"profile":   d.profileBase,
+		"token":     d.tokenBase,
+		"firmware":  d.firmwareBase,
+		"group":     d.groupBase,
+		"scene":     d.sceneBase,
+		"interlock": d.interlockBase,
+		"schedule":  d.scheduleBase,
+		"rule":      d.ruleBase,
+		"timer":     d.timerBase,
+	}
+
+	var refs = []refsBase{
+		{"group", d.groups, []string{"group"}},
+		{"scene", d.scenes, []string{"scene"}},
+		{"interlock", d.locks, []string{"interlock"}},
+		{"schedule", d.sched, []string{"schedule"}},
+		{"rule", d.rules, []string{"rule"}},
+		{"timer", d.timers, []string{"timer"}},
+		{"token", d.aut, []string{"token", "profile"}},
+	}
+
+	for _, r := range refs {
+		var bases = r.bases
+		d.storage.RegisterRefs(r.name, r.refs, func() error {
+			return d.saveBase(bases...)
+		})
+		d.refBases = append(d.refBases, bases...)
+	}
+
+	d.sched.SetSaver(d.SaveScheduleBase)
+	d.timers.SetSaver(d.SaveTimerBase)
+}
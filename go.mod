@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/fasthttp/router v1.3.4
+	github.com/fasthttp/websocket v1.4.3
 	github.com/json-iterator/go v1.1.10
 	github.com/valyala/fasthttp v1.18.0
 	go.uber.org/dig v1.10.0
@@ -12,12 +12,20 @@ package main
 
 import (
 	"fmt"
+	_ "time/tzdata"
 
 	"github.com/futcity/controller/auth"
 	"github.com/futcity/controller/core"
 	"github.com/futcity/controller/db"
 	"github.com/futcity/controller/server"
 	"github.com/futcity/controller/server/handlers"
+	"github.com/futcity/controller/types/binary"
+	"github.com/futcity/controller/types/dimmer"
+	"github.com/futcity/controller/types/light"
+	"github.com/futcity/controller/types/meter"
+	"github.com/futcity/controller/types/multirelay"
+	"github.com/futcity/controller/types/relay"
+	"github.com/futcity/controller/types/sensor"
 	"github.com/futcity/controller/utils"
 	"go.uber.org/dig"
 )
@@ -31,15 +39,56 @@ func main() {
 
 	container.Provide(auth.NewAuthorization)
 	container.Provide(core.NewStorage)
+	container.Provide(core.NewEvents)
+	container.Provide(core.NewWatchdog)
+	container.Provide(core.NewReconciler)
+	container.Provide(core.NewFirmware)
+	container.Provide(core.NewCommander)
+	container.Provide(core.NewGroups)
+	container.Provide(core.NewScenes)
+	container.Provide(core.NewInterlocks)
+	container.Provide(core.NewScheduler)
+	container.Provide(core.NewSun)
+	container.Provide(core.NewRules)
+	container.Provide(core.NewTimers)
 
 	container.Provide(handlers.NewGroupHandler)
+	container.Provide(handlers.NewSceneHandler)
+	container.Provide(handlers.NewInterlockHandler)
+	container.Provide(handlers.NewScheduleHandler)
+	container.Provide(handlers.NewRuleHandler)
 	container.Provide(handlers.NewProfileHandler)
 	container.Provide(handlers.NewDeviceHandler)
-	container.Provide(handlers.NewRelayHandler)
+	container.Provide(handlers.NewEventHandler)
+	container.Provide(handlers.NewPushHandler)
+	container.Provide(handlers.NewFirmwareHandler)
+	container.Provide(handlers.NewPairingHandler)
+	container.Provide(handlers.NewDeviceTypeHandlers)
 	container.Provide(server.NewWebServer)
 
 	container.Provide(NewApp)
 
+	//
+	// Device types register own storage factories, databases and web API
+	//
+	var types = []interface{}{
+		relay.Register,
+		light.Register,
+		sensor.Register,
+		dimmer.Register,
+		binary.Register,
+		multirelay.Register,
+		meter.Register,
+	}
+
+	for _, register := range types {
+		err := container.Invoke(register)
+		if err != nil {
+			fmt.Printf("Fatal error: %s\n", err.Error())
+			return
+		}
+	}
+
 	err := container.Invoke(func(app *App) {
 		app.Start()
 	})
@@ -20,13 +20,73 @@ const (
 	HttpReqDevAdd    = "/user/{user}/device/add/name/{name}/desc/{desc}/type/{type}"
 	HttpReqDevByDesc = "/user/{user}/device/desc/{desc}"
 
-	HttpReqProfList      = "/user/{user}/profile"
-	HttpReqProfAdd       = "/user/{user}/profile/add/name/{name}/key/{key}/admin/{admin}"
-	HttpReqProfRemove    = "/user/{user}/profile/del/name/{name}"
-	HttpReqProfAddDev    = "/user/{user}/profile/name/{name}/add/device/{device}/read/{read}/write/{write}"
-	HttpReqProfAddGrp    = "/user/{user}/profile/name/{name}/add/group/{group}"
-	HttpReqProfDevRemove = "/user/{user}/profile/name/{name}/del/device/{device}"
-	HttpReqProfGrpRemove = "/user/{user}/profile/name/{name}/del/group/{group}"
+	HttpReqGroupDevList   = "/user/{user}/group"
+	HttpReqGroupAdd       = "/user/{user}/group/add/name/{name}"
+	HttpReqGroupRemove    = "/user/{user}/group/del/name/{name}"
+	HttpReqGroupAddDev    = "/user/{user}/group/name/{name}/add/device/{device}"
+	HttpReqGroupMoveDev   = "/user/{user}/group/name/{name}/move/device/{device}/position/{position}"
+	HttpReqGroupDevRemove = "/user/{user}/group/name/{name}/del/device/{device}"
+	HttpReqGroupSet       = "/user/{user}/group/name/{name}/set/{status}"
+
+	HttpReqSceneList      = "/user/{user}/scene"
+	HttpReqSceneAdd       = "/user/{user}/scene/add/name/{name}"
+	HttpReqSceneRemove    = "/user/{user}/scene/del/name/{name}"
+	HttpReqSceneSetDev    = "/user/{user}/scene/name/{name}/device/{device}/set/{status}"
+	HttpReqSceneSetLevel  = "/user/{user}/scene/name/{name}/device/{device}/level/{level}"
+	HttpReqSceneDevRemove = "/user/{user}/scene/name/{name}/del/device/{device}"
+	HttpReqSceneActivate  = "/user/{user}/scene/name/{name}/activate"
+
+	HttpReqInterlockList      = "/user/{user}/interlock"
+	HttpReqInterlockAdd       = "/user/{user}/interlock/add/name/{name}/policy/{policy}"
+	HttpReqInterlockRemove    = "/user/{user}/interlock/del/name/{name}"
+	HttpReqInterlockPolicy    = "/user/{user}/interlock/name/{name}/policy/{policy}"
+	HttpReqInterlockAddDev    = "/user/{user}/interlock/name/{name}/add/device/{device}"
+	HttpReqInterlockDevRemove = "/user/{user}/interlock/name/{name}/del/device/{device}"
+	HttpReqInterlockAddChan   = "/user/{user}/interlock/name/{name}/add/device/{device}/channel/{channel}"
+	HttpReqInterlockChRemove  = "/user/{user}/interlock/name/{name}/del/device/{device}/channel/{channel}"
+
+	HttpReqScheduleList    = "/user/{user}/schedule"
+	HttpReqScheduleAdd     = "/user/{user}/schedule/add/target/{target}/action/{action}"
+	HttpReqScheduleRemove  = "/user/{user}/schedule/del/id/{id}"
+	HttpReqScheduleEnable  = "/user/{user}/schedule/id/{id}/enable"
+	HttpReqScheduleDisable = "/user/{user}/schedule/id/{id}/disable"
+	HttpReqScheduleSun     = "/user/{user}/schedule/sun/days/{days}"
+
+	HttpReqRuleList         = "/user/{user}/rule"
+	HttpReqRuleLog          = "/user/{user}/rule/log"
+	HttpReqRuleAdd          = "/user/{user}/rule/add/name/{name}/trigger/{trigger}"
+	HttpReqRuleRemove       = "/user/{user}/rule/del/id/{id}"
+	HttpReqRuleEnable       = "/user/{user}/rule/id/{id}/enable"
+	HttpReqRuleDisable      = "/user/{user}/rule/id/{id}/disable"
+	HttpReqRuleAddCond      = "/user/{user}/rule/id/{id}/add/condition"
+	HttpReqRuleCondRemove   = "/user/{user}/rule/id/{id}/del/condition/{index}"
+	HttpReqRuleAddAction    = "/user/{user}/rule/id/{id}/add/action/{action}/target/{target}"
+	HttpReqRuleActionRemove = "/user/{user}/rule/id/{id}/del/action/{index}"
+
+	HttpReqDevSetDesc    = "/user/{user}/device/name/{name}/set/desc/{desc}"
+	HttpReqDevRename     = "/user/{user}/device/name/{name}/rename/{newname}"
+	HttpReqDevSetRoom    = "/user/{user}/device/name/{name}/set/room/{room}"
+	HttpReqDevRoomRemove = "/user/{user}/device/name/{name}/del/room"
+	HttpReqDevAddTag     = "/user/{user}/device/name/{name}/add/tag/{tag}"
+	HttpReqDevTagRemove  = "/user/{user}/device/name/{name}/del/tag/{tag}"
+	HttpReqDevSetAttr    = "/user/{user}/device/name/{name}/set/attr/{key}/value/{value}"
+	HttpReqDevAttrRemove = "/user/{user}/device/name/{name}/del/attr/{key}"
+	HttpReqDevUpdateInfo = "/user/{user}/device/name/{name}/update/firmware/{firmware}/ip/{ip}/mac/{mac}"
+
+	HttpReqEventList    = "/user/{user}/events/from/{from}/to/{to}"
+	HttpReqEventDevList = "/user/{user}/events/device/{id}/from/{from}/to/{to}"
+
+	HttpReqPush = "/user/{user}/push"
+
+	HttpReqProfList       = "/user/{user}/profile"
+	HttpReqProfAdd        = "/user/{user}/profile/add/name/{name}/key/{key}/admin/{admin}"
+	HttpReqProfRemove     = "/user/{user}/profile/del/name/{name}"
+	HttpReqProfAddDev     = "/user/{user}/profile/name/{name}/add/device/{device}/read/{read}/write/{write}"
+	HttpReqProfAddGrp     = "/user/{user}/profile/name/{name}/add/group/{group}"
+	HttpReqProfDevRemove  = "/user/{user}/profile/name/{name}/del/device/{device}"
+	HttpReqProfGrpRemove  = "/user/{user}/profile/name/{name}/del/group/{group}"
+	HttpReqProfAddChan    = "/user/{user}/profile/name/{name}/add/device/{device}/channel/{channel}/read/{read}/write/{write}"
+	HttpReqProfChanRemove = "/user/{user}/profile/name/{name}/del/device/{device}/channel/{channel}"
 
 	//
 	// Relay API
@@ -36,6 +96,86 @@ const (
 	HttpReqRelaySwitch = "/user/{user}/relay/{id}/switch"
 	HttpReqRelaySet    = "/user/{user}/relay/{id}/set/{status}"
 	HttpReqRelayUpdate = "/user/{user}/relay/{id}/update/state/{state}"
+
+	HttpReqRelayTimer       = "/user/{user}/relay/{id}/timer/set/{minutes}"
+	HttpReqRelayTimerCancel = "/user/{user}/relay/{id}/timer/cancel"
+	HttpReqRelayMode        = "/user/{user}/relay/{id}/mode/{mode}"
+
+	//
+	// Light API
+	//
+	HttpReqLightList   = "/user/{user}/light"
+	HttpReqLightStatus = "/user/{user}/light/{id}"
+	HttpReqLightSwitch = "/user/{user}/light/{id}/switch"
+	HttpReqLightSet    = "/user/{user}/light/{id}/set/{status}"
+	HttpReqLightUpdate = "/user/{user}/light/{id}/update/state/{state}"
+
+	//
+	// Sensor API
+	//
+	HttpReqSensorList        = "/user/{user}/sensor"
+	HttpReqSensorData        = "/user/{user}/sensor/{id}"
+	HttpReqSensorHistory     = "/user/{user}/sensor/{id}/history/from/{from}/to/{to}"
+	HttpReqSensorUpdate      = "/user/{user}/sensor/{id}/update/temp/{temp}/hum/{hum}"
+	HttpReqSensorUpdatePress = "/user/{user}/sensor/{id}/update/temp/{temp}/hum/{hum}/pres/{pres}"
+
+	//
+	// Dimmer API
+	//
+	HttpReqDimmerList         = "/user/{user}/dimmer"
+	HttpReqDimmerStatus       = "/user/{user}/dimmer/{id}"
+	HttpReqDimmerSet          = "/user/{user}/dimmer/{id}/set/{level}"
+	HttpReqDimmerSetTime      = "/user/{user}/dimmer/{id}/set/{level}/time/{time}"
+	HttpReqDimmerStepUp       = "/user/{user}/dimmer/{id}/up/{step}"
+	HttpReqDimmerStepUpTime   = "/user/{user}/dimmer/{id}/up/{step}/time/{time}"
+	HttpReqDimmerStepDown     = "/user/{user}/dimmer/{id}/down/{step}"
+	HttpReqDimmerStepDownTime = "/user/{user}/dimmer/{id}/down/{step}/time/{time}"
+	HttpReqDimmerUpdate       = "/user/{user}/dimmer/{id}/update/level/{level}"
+
+	//
+	// Binary input API
+	//
+	HttpReqBinaryList   = "/user/{user}/binary"
+	HttpReqBinaryStatus = "/user/{user}/binary/{id}"
+	HttpReqBinaryUpdate = "/user/{user}/binary/{id}/update/state/{state}"
+
+	//
+	// Multi-channel relay API
+	//
+	HttpReqMultiRelayList     = "/user/{user}/multirelay"
+	HttpReqMultiRelayStatus   = "/user/{user}/multirelay/{id}"
+	HttpReqMultiRelaySwitch   = "/user/{user}/multirelay/{id}/channel/{channel}/switch"
+	HttpReqMultiRelaySet      = "/user/{user}/multirelay/{id}/channel/{channel}/set/{status}"
+	HttpReqMultiRelayDesc     = "/user/{user}/multirelay/{id}/channel/{channel}/desc/{desc}"
+	HttpReqMultiRelayChannels = "/user/{user}/multirelay/{id}/channels/{count}"
+	HttpReqMultiRelayUpdate   = "/user/{user}/multirelay/{id}/update/states/{states}"
+
+	//
+	// Energy meter API
+	//
+	HttpReqMeterList    = "/user/{user}/meter"
+	HttpReqMeterStatus  = "/user/{user}/meter/{id}"
+	HttpReqMeterDaily   = "/user/{user}/meter/{id}/daily"
+	HttpReqMeterMonthly = "/user/{user}/meter/{id}/monthly"
+	HttpReqMeterUpdate  = "/user/{user}/meter/{id}/update/voltage/{voltage}/current/{current}/power/{power}/energy/{energy}"
+
+	//
+	// Firmware API
+	//
+	HttpReqFirmwareList     = "/user/{user}/firmware"
+	HttpReqFirmwareUpload   = "/user/{user}/firmware/upload/type/{type}/version/{version}"
+	HttpReqFirmwareRemove   = "/user/{user}/firmware/del/type/{type}/version/{version}"
+	HttpReqFirmwareRollout  = "/user/{user}/firmware/rollout"
+	HttpReqFirmwareCheck    = "/user/{user}/firmware/device/{id}/check/version/{version}"
+	HttpReqFirmwareDownload = "/user/{user}/firmware/device/{id}/download"
+
+	//
+	// Pairing API
+	//
+	HttpReqPairingList   = "/user/{user}/pairing"
+	HttpReqPairingCode   = "/user/{user}/pairing/device/{id}/code"
+	HttpReqPairingRevoke = "/user/{user}/pairing/device/{id}/revoke"
+	HttpReqPair          = "/pair/{code}"
 )
 
 //
@@ -56,11 +196,23 @@ type DeviceResponse struct {
 }
 
 type DeviceSingleResponse struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Online      bool   `json:"online"`
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Type        string            `json:"type"`
+	Online      bool              `json:"online"`
+	LastSeen    int64             `json:"lastseen"`
+	Room        string            `json:"room"`
+	Tags        []string          `json:"tags"`
+	Firmware    string            `json:"firmware"`
+	IP          string            `json:"ip"`
+	MAC         string            `json:"mac"`
+	Attributes  []DeviceAttribute `json:"attributes"`
+}
+
+type DeviceAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 type DeviceListResponse struct {
@@ -79,12 +231,220 @@ type GroupResponse struct {
 	Groups    []string `json:"groups"`
 }
 
+type GroupSingleResponse struct {
+	Name    string   `json:"name"`
+	Devices []string `json:"devices"`
+}
+
+type GroupListResponse struct {
+	Operation string                `json:"operation"`
+	Result    bool                  `json:"result"`
+	Error     string                `json:"error"`
+	Groups    []GroupSingleResponse `json:"groups"`
+}
+
+type GroupDeviceResult struct {
+	Device string `json:"device"`
+	Result bool   `json:"result"`
+	Error  string `json:"error"`
+}
+
+type GroupCommandResponse struct {
+	Operation string              `json:"operation"`
+	Result    bool                `json:"result"`
+	Error     string              `json:"error"`
+	Devices   []GroupDeviceResult `json:"devices"`
+}
+
+// Scenes responses
+
+type SceneActionResponse struct {
+	Device string `json:"device"`
+	Status bool   `json:"status"`
+	Level  int    `json:"level"`
+}
+
+type SceneSingleResponse struct {
+	Name    string                `json:"name"`
+	Actions []SceneActionResponse `json:"actions"`
+}
+
+type SceneListResponse struct {
+	Operation string                `json:"operation"`
+	Result    bool                  `json:"result"`
+	Error     string                `json:"error"`
+	Scenes    []SceneSingleResponse `json:"scenes"`
+}
+
+type SceneCommandResponse struct {
+	Operation string              `json:"operation"`
+	Result    bool                `json:"result"`
+	Error     string              `json:"error"`
+	Devices   []GroupDeviceResult `json:"devices"`
+}
+
+// Schedule responses
+
+type JobSingleResponse struct {
+	ID      int    `json:"id"`
+	Cron    string `json:"cron"`
+	Sun     string `json:"sun"`
+	Offset  int    `json:"offset"`
+	At      int64  `json:"at"`
+	Target  string `json:"target"`
+	Action  string `json:"action"`
+	Level   int    `json:"level"`
+	Enabled bool   `json:"enabled"`
+	CatchUp bool   `json:"catchup"`
+	LastRun int64  `json:"lastrun"`
+	Next    int64  `json:"next"`
+}
+
+type JobListResponse struct {
+	Operation string              `json:"operation"`
+	Result    bool                `json:"result"`
+	Error     string              `json:"error"`
+	Jobs      []JobSingleResponse `json:"jobs"`
+}
+
+type SunSingleResponse struct {
+	Date    string `json:"date"`
+	Dawn    int64  `json:"dawn"`
+	Sunrise int64  `json:"sunrise"`
+	Sunset  int64  `json:"sunset"`
+	Dusk    int64  `json:"dusk"`
+}
+
+type SunListResponse struct {
+	Operation string              `json:"operation"`
+	Result    bool                `json:"result"`
+	Error     string              `json:"error"`
+	Timezone  string              `json:"timezone"`
+	Days      []SunSingleResponse `json:"days"`
+}
+
+// Interlocks responses
+
+type InterlockChannelResponse struct {
+	Device  string `json:"device"`
+	Channel int    `json:"channel"`
+}
+
+type InterlockSingleResponse struct {
+	Name     string                     `json:"name"`
+	Policy   string                     `json:"policy"`
+	Devices  []string                   `json:"devices"`
+	Channels []InterlockChannelResponse `json:"channels"`
+}
+
+type InterlockListResponse struct {
+	Operation  string                    `json:"operation"`
+	Result     bool                      `json:"result"`
+	Error      string                    `json:"error"`
+	Interlocks []InterlockSingleResponse `json:"interlocks"`
+}
+
+// Rules responses
+
+type RuleConditionResponse struct {
+	Device string `json:"device"`
+	Op     string `json:"op"`
+	Value  string `json:"value"`
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+type RuleActionResponse struct {
+	Target string `json:"target"`
+	Action string `json:"action"`
+	Level  int    `json:"level"`
+}
+
+type RuleSingleResponse struct {
+	ID         int                     `json:"id"`
+	Name       string                  `json:"name"`
+	Enabled    bool                    `json:"enabled"`
+	Trigger    string                  `json:"trigger"`
+	Device     string                  `json:"device"`
+	State      string                  `json:"state"`
+	Cron       string                  `json:"cron"`
+	Conditions []RuleConditionResponse `json:"conditions"`
+	Actions    []RuleActionResponse    `json:"actions"`
+}
+
+type RuleListResponse struct {
+	Operation string               `json:"operation"`
+	Result    bool                 `json:"result"`
+	Error     string               `json:"error"`
+	Rules     []RuleSingleResponse `json:"rules"`
+}
+
+type RuleLogSingleResponse struct {
+	Time     int64  `json:"time"`
+	Rule     int    `json:"rule"`
+	Name     string `json:"name"`
+	Trigger  string `json:"trigger"`
+	Executed bool   `json:"executed"`
+	Error    string `json:"error"`
+}
+
+type RuleLogResponse struct {
+	Operation string                  `json:"operation"`
+	Result    bool                    `json:"result"`
+	Error     string                  `json:"error"`
+	Log       []RuleLogSingleResponse `json:"log"`
+}
+
+// Events responses
+
+type EventSingleResponse struct {
+	Time    int64  `json:"time"`
+	Type    string `json:"type"`
+	Actor   string `json:"actor"`
+	Device  string `json:"device"`
+	Profile string `json:"profile"`
+	Value   string `json:"value"`
+}
+
+type EventListResponse struct {
+	Operation string                `json:"operation"`
+	Result    bool                  `json:"result"`
+	Error     string                `json:"error"`
+	Events    []EventSingleResponse `json:"events"`
+}
+
+// Push responses
+
+type PushDeviceResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Online      bool   `json:"online"`
+	Status      string `json:"status"`
+	State       string `json:"state"`
+}
+
+type PushResponse struct {
+	Operation string                `json:"operation"`
+	Result    bool                  `json:"result"`
+	Error     string                `json:"error"`
+	Events    []EventSingleResponse `json:"events"`
+	Devices   []PushDeviceResponse  `json:"devices"`
+}
+
 // Profiles responses
 
+type ProfileChannelResponse struct {
+	Channel int  `json:"channel"`
+	Read    bool `json:"read"`
+	Write   bool `json:"write"`
+}
+
 type ProfileDeviceResponse struct {
-	Name  string `json:"name"`
-	Read  bool   `json:"read"`
-	Write bool   `json:"write"`
+	Name     string                   `json:"name"`
+	Read     bool                     `json:"read"`
+	Write    bool                     `json:"write"`
+	Channels []ProfileChannelResponse `json:"channels"`
 }
 
 type ProfileSingleResponse struct {
@@ -112,14 +472,29 @@ type RelayResponse struct {
 	Error     string `json:"error"`
 	Status    bool   `json:"status"`
 	State     bool   `json:"state"`
+	Sync      string `json:"sync"`
+	Changed   int64  `json:"changed"`
+	Remaining int64  `json:"remaining"`
+	Mode      string `json:"mode"`
+	Width     int64  `json:"width"`
+	Pulse     int    `json:"pulse"`
+	Output    bool   `json:"output"`
 }
 
 type RelaySingleDevResponse struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Online      bool   `json:"online"`
+	LastSeen    int64  `json:"lastseen"`
 	Status      bool   `json:"status"`
 	State       bool   `json:"state"`
+	Sync        string `json:"sync"`
+	Changed     int64  `json:"changed"`
+	Remaining   int64  `json:"remaining"`
+	Mode        string `json:"mode"`
+	Width       int64  `json:"width"`
+	Pulse       int    `json:"pulse"`
+	Output      bool   `json:"output"`
 }
 
 type RelayDevResponse struct {
@@ -128,3 +503,295 @@ type RelayDevResponse struct {
 	Error     string                   `json:"error"`
 	Relays    []RelaySingleDevResponse `json:"relays"`
 }
+
+//
+// Light responses
+//
+
+type LightResponse struct {
+	Operation string `json:"operation"`
+	Result    bool   `json:"result"`
+	Error     string `json:"error"`
+	Status    bool   `json:"status"`
+	State     bool   `json:"state"`
+}
+
+type LightSingleDevResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Online      bool   `json:"online"`
+	Status      bool   `json:"status"`
+	State       bool   `json:"state"`
+}
+
+type LightDevResponse struct {
+	Operation string                   `json:"operation"`
+	Result    bool                     `json:"result"`
+	Error     string                   `json:"error"`
+	Lights    []LightSingleDevResponse `json:"lights"`
+}
+
+//
+// Sensor responses
+//
+
+type SensorDataResponse struct {
+	Time        int64   `json:"time"`
+	Temperature float64 `json:"temperature"`
+	Humidity    float64 `json:"humidity"`
+	Pressure    float64 `json:"pressure"`
+	HasPressure bool    `json:"haspressure"`
+}
+
+type SensorResponse struct {
+	Operation string             `json:"operation"`
+	Result    bool               `json:"result"`
+	Error     string             `json:"error"`
+	Online    bool               `json:"online"`
+	Data      SensorDataResponse `json:"data"`
+}
+
+type SensorSingleDevResponse struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Online      bool               `json:"online"`
+	Data        SensorDataResponse `json:"data"`
+}
+
+type SensorDevResponse struct {
+	Operation string                    `json:"operation"`
+	Result    bool                      `json:"result"`
+	Error     string                    `json:"error"`
+	Sensors   []SensorSingleDevResponse `json:"sensors"`
+}
+
+type SensorHistoryResponse struct {
+	Operation string               `json:"operation"`
+	Result    bool                 `json:"result"`
+	Error     string               `json:"error"`
+	History   []SensorDataResponse `json:"history"`
+}
+
+//
+// Dimmer responses
+//
+
+type DimmerResponse struct {
+	Operation  string `json:"operation"`
+	Result     bool   `json:"result"`
+	Error      string `json:"error"`
+	Level      int    `json:"level"`
+	Target     int    `json:"target"`
+	Transition bool   `json:"transition"`
+	State      int    `json:"state"`
+}
+
+type DimmerSingleDevResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Online      bool   `json:"online"`
+	Level       int    `json:"level"`
+	Target      int    `json:"target"`
+	Transition  bool   `json:"transition"`
+	State       int    `json:"state"`
+}
+
+type DimmerDevResponse struct {
+	Operation string                    `json:"operation"`
+	Result    bool                      `json:"result"`
+	Error     string                    `json:"error"`
+	Dimmers   []DimmerSingleDevResponse `json:"dimmers"`
+}
+
+//
+// Binary input responses
+//
+
+type BinaryResponse struct {
+	Operation string `json:"operation"`
+	Result    bool   `json:"result"`
+	Error     string `json:"error"`
+	Type      string `json:"type"`
+	State     bool   `json:"state"`
+	StateName string `json:"statename"`
+	Changed   int64  `json:"changed"`
+}
+
+type BinarySingleDevResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Online      bool   `json:"online"`
+	State       bool   `json:"state"`
+	StateName   string `json:"statename"`
+	Changed     int64  `json:"changed"`
+}
+
+type BinaryDevResponse struct {
+	Operation string                    `json:"operation"`
+	Result    bool                      `json:"result"`
+	Error     string                    `json:"error"`
+	Binaries  []BinarySingleDevResponse `json:"binaries"`
+}
+
+//
+// Multi-channel relay responses
+//
+
+type RelayChannelResponse struct {
+	Channel     int    `json:"channel"`
+	Description string `json:"description"`
+	Status      bool   `json:"status"`
+	State       bool   `json:"state"`
+}
+
+type MultiRelayResponse struct {
+	Operation string                 `json:"operation"`
+	Result    bool                   `json:"result"`
+	Error     string                 `json:"error"`
+	Channels  []RelayChannelResponse `json:"channels"`
+}
+
+type MultiRelaySingleDevResponse struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Type        string                 `json:"type"`
+	Online      bool                   `json:"online"`
+	Channels    []RelayChannelResponse `json:"channels"`
+}
+
+type MultiRelayDevResponse struct {
+	Operation   string                        `json:"operation"`
+	Result      bool                          `json:"result"`
+	Error       string                        `json:"error"`
+	MultiRelays []MultiRelaySingleDevResponse `json:"multirelays"`
+}
+
+//
+// Energy meter responses
+//
+
+type MeterResponse struct {
+	Operation string  `json:"operation"`
+	Result    bool    `json:"result"`
+	Error     string  `json:"error"`
+	Voltage   float64 `json:"voltage"`
+	Current   float64 `json:"current"`
+	Power     float64 `json:"power"`
+	Counter   float64 `json:"counter"`
+	Total     float64 `json:"total"`
+	Today     float64 `json:"today"`
+	Month     float64 `json:"month"`
+}
+
+type MeterSingleDevResponse struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Online      bool    `json:"online"`
+	Voltage     float64 `json:"voltage"`
+	Current     float64 `json:"current"`
+	Power       float64 `json:"power"`
+	Total       float64 `json:"total"`
+	Today       float64 `json:"today"`
+	Month       float64 `json:"month"`
+}
+
+type MeterDevResponse struct {
+	Operation string                   `json:"operation"`
+	Result    bool                     `json:"result"`
+	Error     string                   `json:"error"`
+	Meters    []MeterSingleDevResponse `json:"meters"`
+}
+
+type MeterTotalResponse struct {
+	Period string  `json:"period"`
+	Energy float64 `json:"energy"`
+}
+
+type MeterTotalsResponse struct {
+	Operation string               `json:"operation"`
+	Result    bool                 `json:"result"`
+	Error     string               `json:"error"`
+	Totals    []MeterTotalResponse `json:"totals"`
+}
+
+//
+// Firmware responses
+//
+
+type FirmwareImageResponse struct {
+	Type     string `json:"type"`
+	Version  string `json:"version"`
+	Size     int    `json:"size"`
+	MD5      string `json:"md5"`
+	SHA256   string `json:"sha256"`
+	Uploaded int64  `json:"uploaded"`
+}
+
+type FirmwareListResponse struct {
+	Operation string                  `json:"operation"`
+	Result    bool                    `json:"result"`
+	Error     string                  `json:"error"`
+	Images    []FirmwareImageResponse `json:"images"`
+}
+
+type FirmwareCheckResponse struct {
+	Operation string `json:"operation"`
+	Result    bool   `json:"result"`
+	Error     string `json:"error"`
+	Available bool   `json:"available"`
+	Version   string `json:"version"`
+	Size      int    `json:"size"`
+	MD5       string `json:"md5"`
+	SHA256    string `json:"sha256"`
+}
+
+type FirmwareRolloutResponse struct {
+	Device   string `json:"device"`
+	Type     string `json:"type"`
+	From     string `json:"from"`
+	Target   string `json:"target"`
+	Reported string `json:"reported"`
+	State    string `json:"state"`
+	Updated  int64  `json:"updated"`
+}
+
+type FirmwareRolloutListResponse struct {
+	Operation string                    `json:"operation"`
+	Result    bool                      `json:"result"`
+	Error     string                    `json:"error"`
+	Rollouts  []FirmwareRolloutResponse `json:"rollouts"`
+}
+
+//
+// Pairing responses
+//
+
+type PairingResponse struct {
+	Operation string `json:"operation"`
+	Result    bool   `json:"result"`
+	Error     string `json:"error"`
+	Device    string `json:"device"`
+	Code      string `json:"code,omitempty"`
+	Expires   int64  `json:"expires,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+type PairingCodeResponse struct {
+	Device  string `json:"device"`
+	Code    string `json:"code"`
+	Expires int64  `json:"expires"`
+}
+
+type DeviceTokenResponse struct {
+	Device  string `json:"device"`
+	Created int64  `json:"created"`
+}
+
+type PairingListResponse struct {
+	Operation string                `json:"operation"`
+	Result    bool                  `json:"result"`
+	Error     string                `json:"error"`
+	Codes     []PairingCodeResponse `json:"codes"`
+	Tokens    []DeviceTokenResponse `json:"tokens"`
+}
@@ -0,0 +1,180 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type BinaryHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewBinaryHandler(s *core.Storage, a *auth.Authorization, e *core.Events, l *utils.Log) *BinaryHandler {
+	return &BinaryHandler{
+		storage: s,
+		aut:     a,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Binary input web API routes
+func (b *BinaryHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqBinaryStatus, b.Status},
+		{api.HttpReqBinaryUpdate, b.Update},
+		{api.HttpReqBinaryList, b.Devices},
+	}
+}
+
+func (b *BinaryHandler) Status(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var binary, ok = b.storage.Device(ctx.UserValue("id").(string)).(*base.Binary)
+	if !ok {
+		b.response(ctx, "Get binary input status", false, "Binary input not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = b.aut.Validation(ctx.UserValue("user").(string), binary.Name())
+	if !read {
+		b.response(ctx, "Get binary input status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	b.response(ctx, "Get binary input status", true, "", binary)
+}
+
+func (b *BinaryHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var binaries []*base.Binary
+	for _, device := range b.storage.FindDevices(deviceFilter(ctx, "")) {
+		var binary, ok = device.(*base.Binary)
+		if !ok {
+			continue
+		}
+
+		var read, _ = b.aut.Validation(ctx.UserValue("user").(string), device.Name())
+		if read {
+			binaries = append(binaries, binary)
+		}
+	}
+
+	// Send response
+	b.responseList(ctx, "Get binary inputs list", true, "", binaries)
+}
+
+func (b *BinaryHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var binary, ok = b.storage.Device(ctx.UserValue("id").(string)).(*base.Binary)
+	if !ok {
+		b.response(ctx, "Update binary input", false, "Binary input not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = b.aut.ReportValidation(ctx.UserValue("user").(string), binary.Name())
+	if !report {
+		b.response(ctx, "Update binary input", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var state, err = strconv.ParseBool(ctx.UserValue("state").(string))
+	if err != nil {
+		b.response(ctx, "Update binary input", false, "Fail to convert state", binary)
+		return
+	}
+	if binary.Update(state) {
+		b.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(b.aut, ctx.UserValue("user").(string)),
+			Device: binary.Name(),
+			Value:  binary.StateName(),
+		})
+	}
+
+	// Send response
+	b.response(ctx, "Update binary input", true, "", binary)
+}
+
+func (b *BinaryHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, binary *base.Binary) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.BinaryResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if binary != nil {
+		resp.Type = binary.Type()
+		resp.State = binary.State()
+		resp.StateName = binary.StateName()
+		resp.Changed = unixTime(binary.Changed())
+	}
+
+	if result && oper != "Update binary input" {
+		b.log.Info("BINARYH", oper)
+	} else if !result {
+		b.log.Error("BINARYH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (b *BinaryHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, binaries []*base.Binary) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var binResp = api.BinaryDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, binary := range binaries {
+		binResp.Binaries = append(binResp.Binaries, api.BinarySingleDevResponse{
+			Name:        binary.Name(),
+			Description: binary.Description(),
+			Type:        binary.Type(),
+			Online:      binary.Online(),
+			State:       binary.State(),
+			StateName:   binary.StateName(),
+			Changed:     unixTime(binary.Changed()),
+		})
+	}
+
+	if result {
+		b.log.Info("BINARYH", oper)
+	} else {
+		b.log.Error("BINARYH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(binResp)
+
+	ctx.Write(bytes)
+}
@@ -0,0 +1,62 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestBinaryJournal(t *testing.T) {
+	var env = newTestEnv(t)
+	var binh = NewBinaryHandler(env.storage, env.aut, env.events, env.log)
+
+	var err = env.storage.AddDevice("motion0", "Hall", "motion")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only transitions are recorded as events
+	var from = time.Now().Add(-time.Second)
+	for _, state := range []string{"false", "false", "true", "true", "false"} {
+		var resp api.BinaryResponse
+		var ctx = request(binh.Update, map[string]string{"user": testAdminKey, "id": "motion0", "state": state})
+		jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+		if !resp.Result {
+			t.Fatal("binary input is not updated:", resp.Error)
+		}
+	}
+
+	var events []core.Event
+	for _, event := range env.events.Events(from, time.Now().Add(time.Second)) {
+		if event.Device == "motion0" {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 2 || events[0].Value != "detected" || events[1].Value != "clear" {
+		t.Fatal("wrong binary input journal:", events)
+	}
+	if events[0].Type != core.EventStateChanged || events[0].Actor != core.UserActor("admin") {
+		t.Error("wrong binary input event:", events[0])
+	}
+
+	// Reports need device rights
+	var resp api.BinaryResponse
+	var ctx = request(binh.Update, map[string]string{"user": testUserKey, "id": "motion0", "state": "true"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("binary input is updated without rights")
+	}
+}
@@ -12,7 +12,9 @@ package handlers
 
 import (
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/futcity/controller/auth"
 	"github.com/futcity/controller/core"
@@ -27,15 +29,19 @@ import (
 type DeviceHandler struct {
 	aut     *auth.Authorization
 	storage *core.Storage
+	fw      *core.Firmware
+	events  *core.Events
 	db      *db.Database
 	log     *utils.Log
 }
 
-func NewDeviceHandler(s *core.Storage, a *auth.Authorization, db *db.Database,
-	l *utils.Log) *DeviceHandler {
+func NewDeviceHandler(s *core.Storage, a *auth.Authorization, f *core.Firmware, e *core.Events,
+	db *db.Database, l *utils.Log) *DeviceHandler {
 	return &DeviceHandler{
 		aut:     a,
 		storage: s,
+		fw:      f,
+		events:  e,
 		db:      db,
 		log:     l,
 	}
@@ -85,6 +91,25 @@ func (d *DeviceHandler) RemoveDevice(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// Remove device from groups, scenes, jobs, profiles and other
+	// references and save all changed bases together
+	err = d.db.Batch(func(b *db.Batch) error {
+		d.storage.ForgetReferences(device.Name())
+		b.SaveDevice(device.Type())
+		return nil
+	})
+	if err != nil {
+		d.response(ctx, "Save device", false, err.Error(), device.Name())
+		return
+	}
+
+	d.events.Publish(core.Event{
+		Type:   core.EventDeviceRemoved,
+		Actor:  actor(d.aut, ctx.UserValue("user").(string)),
+		Device: device.Name(),
+		Value:  device.Type(),
+	})
+
 	// Send response
 	d.response(ctx, "Remove device", true, "", device.Name())
 }
@@ -99,15 +124,26 @@ func (d *DeviceHandler) AddDevice(ctx *fasthttp.RequestCtx) {
 
 	// Add device to storage
 	var desc, _ = url.QueryUnescape(ctx.UserValue("desc").(string))
-	d.storage.AddDevice(ctx.UserValue("name").(string), desc, ctx.UserValue("type").(string))
+	var err = d.storage.AddDevice(ctx.UserValue("name").(string), desc, ctx.UserValue("type").(string))
+	if err != nil {
+		d.response(ctx, "Add device", false, err.Error(), ctx.UserValue("name").(string))
+		return
+	}
 
 	// Save new devices list
-	var err = d.db.SaveDeviceBase()
+	err = d.db.SaveDeviceBase()
 	if err != nil {
 		d.response(ctx, "Save device", false, err.Error(), ctx.UserValue("name").(string))
 		return
 	}
 
+	d.events.Publish(core.Event{
+		Type:   core.EventDeviceAdded,
+		Actor:  actor(d.aut, ctx.UserValue("user").(string)),
+		Device: ctx.UserValue("name").(string),
+		Value:  ctx.UserValue("type").(string),
+	})
+
 	// Send response
 	d.response(ctx, "Add device", true, "", ctx.UserValue("name").(string))
 }
@@ -116,7 +152,7 @@ func (d *DeviceHandler) DeviceList(ctx *fasthttp.RequestCtx) {
 	var devices []devices.IDevice
 
 	// Check user rights and add device to list
-	for _, device := range d.storage.Devices() {
+	for _, device := range d.storage.FindDevices(deviceFilter(ctx, "")) {
 		var _, write = d.aut.Validation(ctx.UserValue("user").(string), device.Name())
 		if write {
 			devices = append(devices, device)
@@ -127,6 +163,205 @@ func (d *DeviceHandler) DeviceList(ctx *fasthttp.RequestCtx) {
 	d.responseList(ctx, "Devices list", true, "", devices)
 }
 
+func (d *DeviceHandler) SetDescription(ctx *fasthttp.RequestCtx) {
+	var desc, _ = url.QueryUnescape(ctx.UserValue("desc").(string))
+
+	d.editDevice(ctx, "Set device description", func(device devices.IDevice) {
+		device.SetDescription(desc)
+	})
+}
+
+// RenameDevice Change device name and move its status and profiles
+// rights to the new name
+func (d *DeviceHandler) RenameDevice(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = d.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		d.response(ctx, "Rename device", false, "Authorization failed", "")
+		return
+	}
+
+	// Find device in storage
+	var name = ctx.UserValue("name").(string)
+	var device = d.storage.Device(name)
+	if device == nil {
+		d.response(ctx, "Rename device", false, "Device not found", "")
+		return
+	}
+
+	// Process operation
+	var newName, err = url.QueryUnescape(ctx.UserValue("newname").(string))
+	if err != nil {
+		d.response(ctx, "Rename device", false, "Fail to convert new name", name)
+		return
+	}
+	if strings.TrimSpace(newName) == "" {
+		d.response(ctx, "Rename device", false, "Empty new name", name)
+		return
+	}
+
+	err = d.rename(device.Type(), name, newName)
+	if err != nil {
+		d.response(ctx, "Rename device", false, err.Error(), name)
+		return
+	}
+
+	d.events.Publish(core.Event{
+		Type:   core.EventDeviceRenamed,
+		Actor:  actor(d.aut, ctx.UserValue("user").(string)),
+		Device: newName,
+		Value:  name,
+	})
+
+	// Send response
+	d.response(ctx, "Rename device", true, "", newName)
+}
+
+// rename Rename device in storage and all references keepers. Changed
+// bases are saved together, rename is rolled back when saving fails
+func (d *DeviceHandler) rename(devType string, name string, newName string) error {
+	var renamed bool
+
+	var err = d.db.Batch(func(b *db.Batch) error {
+		var err = d.storage.RenameDevice(name, newName)
+		if err != nil {
+			return err
+		}
+
+		d.storage.RenameReferences(name, newName)
+		renamed = true
+
+		b.SaveDevice(devType)
+		return nil
+	})
+	if err != nil && renamed {
+		// Old bases are kept, so only storage and keepers are restored
+		var rerr = d.storage.RenameDevice(newName, name)
+		if rerr != nil {
+			d.log.Error("DEVH", "Fail to restore device name \""+name+"\"", rerr.Error())
+		}
+		d.storage.RenameReferences(newName, name)
+	}
+
+	return err
+}
+
+func (d *DeviceHandler) SetRoom(ctx *fasthttp.RequestCtx) {
+	var room, _ = url.QueryUnescape(ctx.UserValue("room").(string))
+
+	d.editDevice(ctx, "Set device room", func(device devices.IDevice) {
+		device.SetRoom(room)
+	})
+}
+
+func (d *DeviceHandler) RemoveRoom(ctx *fasthttp.RequestCtx) {
+	d.editDevice(ctx, "Remove device room", func(device devices.IDevice) {
+		device.SetRoom("")
+	})
+}
+
+func (d *DeviceHandler) AddTag(ctx *fasthttp.RequestCtx) {
+	var tag, _ = url.QueryUnescape(ctx.UserValue("tag").(string))
+
+	d.editDevice(ctx, "Add device tag", func(device devices.IDevice) {
+		device.AddTag(tag)
+	})
+}
+
+func (d *DeviceHandler) RemoveTag(ctx *fasthttp.RequestCtx) {
+	var tag, _ = url.QueryUnescape(ctx.UserValue("tag").(string))
+
+	d.editDevice(ctx, "Remove device tag", func(device devices.IDevice) {
+		device.RemoveTag(tag)
+	})
+}
+
+func (d *DeviceHandler) SetAttribute(ctx *fasthttp.RequestCtx) {
+	var key, _ = url.QueryUnescape(ctx.UserValue("key").(string))
+	var value, _ = url.QueryUnescape(ctx.UserValue("value").(string))
+
+	d.editDevice(ctx, "Set device attribute", func(device devices.IDevice) {
+		device.SetAttribute(key, value)
+	})
+}
+
+func (d *DeviceHandler) RemoveAttribute(ctx *fasthttp.RequestCtx) {
+	var key, _ = url.QueryUnescape(ctx.UserValue("key").(string))
+
+	d.editDevice(ctx, "Remove device attribute", func(device devices.IDevice) {
+		device.RemoveAttribute(key)
+	})
+}
+
+// UpdateInfo Device reports own firmware version and network addresses
+func (d *DeviceHandler) UpdateInfo(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var device = d.storage.Device(ctx.UserValue("name").(string))
+	if device == nil {
+		d.response(ctx, "Update device info", false, "Device not found", "")
+		return
+	}
+
+	// Check user rights
+	var report = d.aut.ReportValidation(ctx.UserValue("user").(string), device.Name())
+	if !report {
+		d.response(ctx, "Update device info", false, "Authorization failed", "")
+		return
+	}
+
+	// Process operation
+	var firmware, _ = url.QueryUnescape(ctx.UserValue("firmware").(string))
+	d.fw.Report(device, firmware)
+	device.SetIP(ctx.UserValue("ip").(string))
+	device.SetMAC(ctx.UserValue("mac").(string))
+	device.SetOnline(true)
+
+	// Save devices list and firmware rollout state
+	var err = d.db.SaveDeviceBase()
+	if err != nil {
+		d.response(ctx, "Save device", false, err.Error(), device.Name())
+		return
+	}
+	err = d.db.SaveFirmwareBase()
+	if err != nil {
+		d.response(ctx, "Save firmware", false, err.Error(), device.Name())
+		return
+	}
+
+	// Send response
+	d.response(ctx, "Update device info", true, "", device.Name())
+}
+
+// editDevice Change device metadata and save devices base
+func (d *DeviceHandler) editDevice(ctx *fasthttp.RequestCtx, oper string, edit func(device devices.IDevice)) {
+	// Find device in storage
+	var device = d.storage.Device(ctx.UserValue("name").(string))
+	if device == nil {
+		d.response(ctx, oper, false, "Device not found", "")
+		return
+	}
+
+	// Check user rights
+	var _, write = d.aut.Validation(ctx.UserValue("user").(string), device.Name())
+	if !write {
+		d.response(ctx, oper, false, "Authorization failed", "")
+		return
+	}
+
+	// Process operation
+	edit(device)
+
+	// Save new devices list
+	var err = d.db.SaveDeviceBase()
+	if err != nil {
+		d.response(ctx, "Save device", false, err.Error(), device.Name())
+		return
+	}
+
+	// Send response
+	d.response(ctx, oper, true, "", device.Name())
+}
+
 func (d *DeviceHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, name string) {
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	ctx.Response.Header.SetContentType("application/json")
@@ -166,6 +401,13 @@ func (d *DeviceHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resu
 			Description: device.Description(),
 			Type:        device.Type(),
 			Online:      device.Online(),
+			LastSeen:    unixTime(device.LastSeen()),
+			Room:        device.Room(),
+			Tags:        device.Tags(),
+			Firmware:    device.Firmware(),
+			IP:          device.IP(),
+			MAC:         device.MAC(),
+			Attributes:  attributes(device),
 		})
 	}
 
@@ -179,3 +421,18 @@ func (d *DeviceHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resu
 
 	ctx.Write(bytes)
 }
+
+// attributes Get device custom attributes sorted by key
+func attributes(device devices.IDevice) []api.DeviceAttribute {
+	var attrs []api.DeviceAttribute
+
+	for key, value := range device.Attributes() {
+		attrs = append(attrs, api.DeviceAttribute{Key: key, Value: value})
+	}
+
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+
+	return attrs
+}
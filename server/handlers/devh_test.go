@@ -0,0 +1,320 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+func TestStorageUniqueDevices(t *testing.T) {
+	var env = newTestEnv(t)
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var added int
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if env.storage.AddDevice("same"+strconv.Itoa(i), "Test", "relay") == nil {
+					mtx.Lock()
+					added++
+					mtx.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if added != 100 {
+		t.Errorf("added %d devices, want 100", added)
+	}
+}
+
+func TestDeviceIDs(t *testing.T) {
+	var env = newTestEnv(t)
+
+	request(env.devh.AddDevice, map[string]string{"user": testAdminKey, "name": "first", "desc": "Test", "type": "relay"})
+	request(env.devh.AddDevice, map[string]string{"user": testAdminKey, "name": "second", "desc": "Test", "type": "relay"})
+
+	var second = env.storage.Device("second").ID()
+	request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey, "id": strconv.Itoa(second)})
+	request(env.devh.AddDevice, map[string]string{"user": testAdminKey, "name": "third", "desc": "Test", "type": "relay"})
+
+	var third = env.storage.Device("third").ID()
+	if third <= second {
+		t.Errorf("new device ID %d reuses removed ID %d", third, second)
+	}
+
+	// Restart with saved base
+	var storage = core.NewStorage(env.log)
+	var database = db.NewDatabase(env.cfg, env.aut, storage, env.fw, env.groups, env.scenes, env.locks, env.sched, env.rules, env.timers, env.log)
+	registerTypes(t, storage, database)
+	database.SetDBType("text")
+	database.AddFilename("device", filepath.Join(env.dir, "device.json"))
+
+	var err = database.LoadDeviceBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dev := range env.storage.Devices() {
+		var loaded = storage.Device(dev.Name())
+		if loaded == nil || loaded.ID() != dev.ID() {
+			t.Errorf("device %q changed ID after restart", dev.Name())
+		}
+	}
+	if storage.Device("second") != nil {
+		t.Error("removed device is loaded after restart")
+	}
+}
+
+func TestDeviceIDsMigration(t *testing.T) {
+	var env = newTestEnv(t)
+	var fileName = filepath.Join(env.dir, "old.json")
+
+	var err = ioutil.WriteFile(fileName, []byte(`{"devices":[
+		{"name":"old0","description":"Old","type":"relay"},
+		{"name":"old1","description":"Old","type":"relay","id":7},
+		{"name":"old2","description":"Old","type":"relay","id":7}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var storage = core.NewStorage(env.log)
+	var database = db.NewDatabase(env.cfg, env.aut, storage, env.fw, env.groups, env.scenes, env.locks, env.sched, env.rules, env.timers, env.log)
+	registerTypes(t, storage, database)
+	database.SetDBType("text")
+	database.AddFilename("device", fileName)
+
+	err = database.LoadDeviceBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids = make(map[int]bool)
+	for _, dev := range storage.Devices() {
+		if dev.ID() <= 0 || ids[dev.ID()] {
+			t.Errorf("device %q has wrong ID %d", dev.Name(), dev.ID())
+		}
+		ids[dev.ID()] = true
+	}
+	if len(ids) != 3 || storage.Device("old1").ID() != 7 {
+		t.Errorf("devices were not migrated: %v", ids)
+	}
+
+	var saved db.DeviceDB
+	err = env.cfg.LoadFromFile(&saved, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dev := range saved.Devices {
+		if dev.ID <= 0 {
+			t.Errorf("device %q saved without ID", dev.Name)
+		}
+	}
+}
+
+func TestDeviceFilter(t *testing.T) {
+	var env = newTestEnv(t)
+
+	request(env.devh.SetRoom, map[string]string{"user": testAdminKey, "name": "relay1", "room": "Kitchen"})
+	request(env.devh.AddTag, map[string]string{"user": testAdminKey, "name": "relay1", "tag": "light"})
+	request(env.devh.SetAttribute, map[string]string{"user": testAdminKey, "name": "relay1", "key": "floor", "value": "2"})
+	request(env.devh.UpdateInfo, map[string]string{
+		"user": testAdminKey, "name": "relay2", "firmware": "1.0", "ip": "10.0.0.2", "mac": "aa:bb"})
+
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"room=Kitchen", []string{"relay1"}},
+		{"tag=light&attr=floor:2", []string{"relay1"}},
+		{"attr=floor:3", nil},
+		{"firmware=1.0&online=true", []string{"relay2"}},
+		{"type=dimmer", []string{"dimmer0"}},
+	}
+
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/?" + test.query)
+
+		var names []string
+		for _, dev := range env.storage.FindDevices(deviceFilter(&ctx, "")) {
+			names = append(names, dev.Name())
+		}
+
+		if strings.Join(names, ",") != strings.Join(test.want, ",") {
+			t.Errorf("filter %q got %v, want %v", test.query, names, test.want)
+		}
+	}
+}
+
+func TestRenameDevice(t *testing.T) {
+	var env = newTestEnv(t)
+
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay0", "status": "true"})
+	var id = env.storage.Device("relay0").ID()
+
+	request(env.devh.RenameDevice, map[string]string{"user": testUserKey, "name": "relay0", "newname": "boiler"})
+	if env.storage.Device("relay0") == nil {
+		t.Fatal("device renamed without admin rights")
+	}
+
+	request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay0", "newname": "relay1"})
+	if env.storage.Device("relay0") == nil {
+		t.Fatal("device renamed to existing name")
+	}
+
+	for newName, want := range map[string]string{"%zz": "Fail to convert new name", "": "Empty new name",
+		"%20%09": "Empty new name"} {
+		var resp api.DeviceResponse
+		var ctx = request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay0",
+			"newname": newName})
+		jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+		if resp.Result || resp.Error != want || env.storage.Device("relay0") == nil {
+			t.Errorf("device renamed to %q: %v", newName, resp)
+		}
+	}
+
+	request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay0", "newname": "boiler"})
+
+	var relay, ok = env.storage.Device("boiler").(*base.Relay)
+	if !ok || relay.ID() != id || !relay.Status() {
+		t.Fatal("renamed relay lost ID or status")
+	}
+	if read, write := env.aut.Validation(testUserKey, "boiler"); !read || !write {
+		t.Error("profile rights were not renamed")
+	}
+	if read, _ := env.aut.Validation(testUserKey, "relay0"); read {
+		t.Error("profile rights for old name are kept")
+	}
+
+	// Check saved bases
+	var relays db.RelaysDB
+	var err = env.cfg.LoadFromFile(&relays, filepath.Join(env.dir, "relay.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, saved := range relays.Relays {
+		if saved.Name == "relay0" {
+			t.Error("old relay name is saved")
+		}
+		if saved.Name == "boiler" && saved.Status {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("renamed relay status is not saved")
+	}
+
+	var profiles db.ProfileDB
+	err = env.cfg.LoadFromFile(&profiles, filepath.Join(env.dir, "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prof := range profiles.Profiles {
+		for _, dev := range prof.Devices {
+			if dev.Name == "relay0" {
+				t.Error("old device name is saved in profile")
+			}
+		}
+	}
+
+	// Failed saving keeps old name in storage and all bases
+	env.db.AddFilename("timer", filepath.Join(env.dir, "missing", "timer.json"))
+	var ctx = request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "boiler", "newname": "pump"})
+	if env.storage.Device("boiler") != relay || env.storage.Device("pump") != nil {
+		t.Fatalf("failed rename is not rolled back: %s", ctx.Response.Body())
+	}
+	if read, _ := env.aut.Validation(testUserKey, "boiler"); !read {
+		t.Error("profile rights are not rolled back")
+	}
+
+	var devs db.DeviceDB
+	err = env.cfg.LoadFromFile(&devs, filepath.Join(env.dir, "device.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dev := range devs.Devices {
+		if dev.Name == "pump" {
+			t.Error("failed rename is saved")
+		}
+	}
+	var tmp, _ = filepath.Glob(filepath.Join(env.dir, "*.tmp"))
+	if len(tmp) != 0 {
+		t.Error("temporary bases are kept:", tmp)
+	}
+}
+
+func TestRemoveDevice(t *testing.T) {
+	var env = newTestEnv(t)
+
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay0", "status": "true"})
+	request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey,
+		"id": strconv.Itoa(env.storage.Device("relay0").ID())})
+	if env.storage.Device("relay0") != nil {
+		t.Fatal("device is not removed")
+	}
+
+	// Profile rights are removed with device
+	if env.aut.Profile("user").Device("relay0") != nil {
+		t.Error("profile rights of removed device are kept")
+	}
+
+	// Check saved bases
+	var relays db.RelaysDB
+	var err = env.cfg.LoadFromFile(&relays, filepath.Join(env.dir, "relay.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, saved := range relays.Relays {
+		if saved.Name == "relay0" {
+			t.Error("removed relay is saved")
+		}
+	}
+
+	var profiles db.ProfileDB
+	err = env.cfg.LoadFromFile(&profiles, filepath.Join(env.dir, "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prof := range profiles.Profiles {
+		for _, dev := range prof.Devices {
+			if dev.Name == "relay0" {
+				t.Error("removed device is saved in profile")
+			}
+		}
+	}
+
+	// New device with the same name gets no old rights
+	err = env.storage.AddDevice("relay0", "Relay", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read, _ := env.aut.Validation(testUserKey, "relay0"); read {
+		t.Error("new device got rights of removed one")
+	}
+}
@@ -0,0 +1,287 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type DimmerHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	cmd     *core.Commander
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewDimmerHandler(s *core.Storage, a *auth.Authorization, l *utils.Log, cmd *core.Commander,
+	e *core.Events) *DimmerHandler {
+	return &DimmerHandler{
+		storage: s,
+		aut:     a,
+		cmd:     cmd,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Dimmer web API routes
+func (d *DimmerHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqDimmerStatus, d.Status},
+		{api.HttpReqDimmerSet, d.SetLevel},
+		{api.HttpReqDimmerSetTime, d.SetLevel},
+		{api.HttpReqDimmerStepUp, d.StepUp},
+		{api.HttpReqDimmerStepUpTime, d.StepUp},
+		{api.HttpReqDimmerStepDown, d.StepDown},
+		{api.HttpReqDimmerStepDownTime, d.StepDown},
+		{api.HttpReqDimmerUpdate, d.Update},
+		{api.HttpReqDimmerList, d.Devices},
+	}
+}
+
+func (d *DimmerHandler) SetLevel(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var dimmer, ok = d.storage.Device(ctx.UserValue("id").(string)).(*base.Dimmer)
+	if !ok {
+		d.response(ctx, "Set dimmer level", false, "Dimmer not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = d.aut.Validation(ctx.UserValue("user").(string), dimmer.Name())
+	if !write {
+		d.response(ctx, "Set dimmer level", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var level, err = strconv.Atoi(ctx.UserValue("level").(string))
+	if err != nil {
+		d.response(ctx, "Set dimmer level", false, "Fail to convert level", dimmer)
+		return
+	}
+	duration, err := d.transitionTime(ctx)
+	if err != nil {
+		d.response(ctx, "Set dimmer level", false, err.Error(), dimmer)
+		return
+	}
+
+	// Apply changes and save to database
+	err = d.cmd.SetLevel(actor(d.aut, ctx.UserValue("user").(string)), dimmer, level, duration)
+	if err != nil {
+		d.response(ctx, "Set dimmer level", false, err.Error(), dimmer)
+		return
+	}
+
+	// Send response
+	d.response(ctx, "Set dimmer level", true, "", dimmer)
+}
+
+func (d *DimmerHandler) StepUp(ctx *fasthttp.RequestCtx) {
+	d.step(ctx, "Step dimmer up", 1)
+}
+
+func (d *DimmerHandler) StepDown(ctx *fasthttp.RequestCtx) {
+	d.step(ctx, "Step dimmer down", -1)
+}
+
+func (d *DimmerHandler) Status(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var dimmer, ok = d.storage.Device(ctx.UserValue("id").(string)).(*base.Dimmer)
+	if !ok {
+		d.response(ctx, "Get dimmer status", false, "Dimmer not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = d.aut.Validation(ctx.UserValue("user").(string), dimmer.Name())
+	if !read {
+		d.response(ctx, "Get dimmer status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	d.response(ctx, "Get dimmer status", true, "", dimmer)
+}
+
+func (d *DimmerHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var dimmers []*base.Dimmer
+	for _, device := range d.storage.FindDevices(deviceFilter(ctx, "dimmer")) {
+		var read, _ = d.aut.Validation(ctx.UserValue("user").(string), device.Name())
+		if read {
+			dimmers = append(dimmers, device.(*base.Dimmer))
+		}
+	}
+
+	// Send response
+	d.responseList(ctx, "Get dimmers list", true, "", dimmers)
+}
+
+func (d *DimmerHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var dimmer, ok = d.storage.Device(ctx.UserValue("id").(string)).(*base.Dimmer)
+	if !ok {
+		d.response(ctx, "Update dimmer", false, "Dimmer not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = d.aut.ReportValidation(ctx.UserValue("user").(string), dimmer.Name())
+	if !report {
+		d.response(ctx, "Update dimmer", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var level, err = strconv.Atoi(ctx.UserValue("level").(string))
+	if err != nil {
+		d.response(ctx, "Update dimmer", false, "Fail to convert level", dimmer)
+		return
+	}
+	var old = dimmer.State()
+	dimmer.Update(level)
+	if dimmer.State() != old {
+		d.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(d.aut, ctx.UserValue("user").(string)),
+			Device: dimmer.Name(),
+			Value:  core.DeviceState(dimmer),
+		})
+	}
+
+	// Send response
+	d.response(ctx, "Update dimmer", true, "", dimmer)
+}
+
+func (d *DimmerHandler) step(ctx *fasthttp.RequestCtx, oper string, sign int) {
+	// Find device in storage
+	var dimmer, ok = d.storage.Device(ctx.UserValue("id").(string)).(*base.Dimmer)
+	if !ok {
+		d.response(ctx, oper, false, "Dimmer not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = d.aut.Validation(ctx.UserValue("user").(string), dimmer.Name())
+	if !write {
+		d.response(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var step, err = strconv.Atoi(ctx.UserValue("step").(string))
+	if err != nil || step < 0 {
+		d.response(ctx, oper, false, "Fail to convert step", dimmer)
+		return
+	}
+	duration, err := d.transitionTime(ctx)
+	if err != nil {
+		d.response(ctx, oper, false, err.Error(), dimmer)
+		return
+	}
+
+	// Apply changes and save to database
+	err = d.cmd.StepLevel(actor(d.aut, ctx.UserValue("user").(string)), dimmer, sign*step, duration)
+	if err != nil {
+		d.response(ctx, oper, false, err.Error(), dimmer)
+		return
+	}
+
+	// Send response
+	d.response(ctx, oper, true, "", dimmer)
+}
+
+// transitionTime Get optional transition time in milliseconds
+func (d *DimmerHandler) transitionTime(ctx *fasthttp.RequestCtx) (time.Duration, error) {
+	var value, ok = ctx.UserValue("time").(string)
+	if !ok {
+		return 0, nil
+	}
+
+	var ms, err = strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return 0, errors.New("Fail to convert transition time")
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (d *DimmerHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, dimmer *base.Dimmer) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.DimmerResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if dimmer != nil {
+		resp.Level = dimmer.Level()
+		resp.Target = dimmer.Target()
+		resp.Transition = dimmer.Transition()
+		resp.State = dimmer.State()
+	}
+
+	if result && oper != "Update dimmer" {
+		d.log.Info("DIMMERH", oper)
+	} else if !result {
+		d.log.Error("DIMMERH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (d *DimmerHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, dimmers []*base.Dimmer) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var dimResp = api.DimmerDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, dimmer := range dimmers {
+		dimResp.Dimmers = append(dimResp.Dimmers, api.DimmerSingleDevResponse{
+			Name:        dimmer.Name(),
+			Description: dimmer.Description(),
+			Online:      dimmer.Online(),
+			Level:       dimmer.Level(),
+			Target:      dimmer.Target(),
+			Transition:  dimmer.Transition(),
+			State:       dimmer.State(),
+		})
+	}
+
+	if result {
+		d.log.Info("DIMMERH", oper)
+	} else {
+		d.log.Error("DIMMERH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(dimResp)
+
+	ctx.Write(bytes)
+}
@@ -0,0 +1,100 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type EventHandler struct {
+	aut    *auth.Authorization
+	events *core.Events
+	log    *utils.Log
+}
+
+func NewEventHandler(a *auth.Authorization, e *core.Events, l *utils.Log) *EventHandler {
+	return &EventHandler{
+		aut:    a,
+		events: e,
+		log:    l,
+	}
+}
+
+func (e *EventHandler) Events(ctx *fasthttp.RequestCtx) {
+	// Process operation
+	var from, err = strconv.ParseInt(ctx.UserValue("from").(string), 10, 64)
+	if err != nil {
+		e.responseList(ctx, "Events list", false, "Fail to convert time range", nil)
+		return
+	}
+	to, err := strconv.ParseInt(ctx.UserValue("to").(string), 10, 64)
+	if err != nil {
+		e.responseList(ctx, "Events list", false, "Fail to convert time range", nil)
+		return
+	}
+
+	// Check user rights and add event to list
+	var device, _ = ctx.UserValue("id").(string)
+	var events []core.Event
+	for _, event := range e.events.Events(time.Unix(from, 0), time.Unix(to, 0)) {
+		if device != "" && event.Device != device {
+			continue
+		}
+
+		var read, _ = e.aut.Validation(ctx.UserValue("user").(string), event.Device)
+		if read {
+			events = append(events, event)
+		}
+	}
+
+	// Send response
+	e.responseList(ctx, "Events list", true, "", events)
+}
+
+func (e *EventHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, events []core.Event) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var evResp = api.EventListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, event := range events {
+		evResp.Events = append(evResp.Events, api.EventSingleResponse{
+			Time:    event.Time.Unix(),
+			Type:    event.Type,
+			Actor:   event.Actor,
+			Device:  event.Device,
+			Profile: event.Profile,
+			Value:   event.Value,
+		})
+	}
+
+	if result {
+		e.log.Info("EVENTH", oper)
+	} else {
+		e.log.Error("EVENTH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(evResp)
+
+	ctx.Write(bytes)
+}
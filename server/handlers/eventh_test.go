@@ -0,0 +1,53 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+func TestHandlerEvents(t *testing.T) {
+	var env = newTestEnv(t)
+
+	var sub = env.events.Subscribe(0, core.EventDeviceAdded, core.EventStatusRequested, core.EventStateChanged,
+		core.EventProfile)
+
+	request(env.devh.AddDevice, map[string]string{"user": testAdminKey, "name": "relay4", "desc": "Test", "type": "relay"})
+	request(env.relayh.SetStatus, map[string]string{"user": testUserKey, "id": "relay0", "status": "true"})
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay0", "state": "true"})
+	request(env.profh.AddProfile, map[string]string{"user": testAdminKey, "name": "guest", "key": "guestkey", "admin": "false"})
+	env.sched.Restore(core.Job{ID: 1, At: time.Now().Add(-time.Second), Target: "relay1", Action: core.ActionOn, Enabled: true})
+	env.sched.Check(time.Now())
+
+	var want = []core.Event{
+		{Type: core.EventDeviceAdded, Actor: core.UserActor("admin"), Device: "relay4", Value: "relay"},
+		{Type: core.EventStatusRequested, Actor: core.UserActor("user"), Device: "relay0", Value: "on"},
+		{Type: core.EventStateChanged, Actor: core.UserActor("admin"), Device: "relay0", Value: "on"},
+		{Type: core.EventProfile, Actor: core.UserActor("admin"), Profile: "guest", Value: "Add profile"},
+		{Type: core.EventStatusRequested, Actor: core.ActorScheduler, Device: "relay1", Value: "on"},
+	}
+	if len(sub.Events()) != len(want) {
+		t.Fatal("wrong events count:", len(sub.Events()))
+	}
+	for _, w := range want {
+		var e = <-sub.Events()
+		if e.Time.IsZero() {
+			t.Error("event time is not set:", e)
+		}
+		e.Time = time.Time{}
+		if e != w {
+			t.Error("wrong event:", e, "want:", w)
+		}
+	}
+}
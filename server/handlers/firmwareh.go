@@ -0,0 +1,298 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type FirmwareHandler struct {
+	fw      *core.Firmware
+	storage *core.Storage
+	aut     *auth.Authorization
+	db      *db.Database
+	log     *utils.Log
+}
+
+func NewFirmwareHandler(f *core.Firmware, s *core.Storage, a *auth.Authorization,
+	d *db.Database, l *utils.Log) *FirmwareHandler {
+	return &FirmwareHandler{
+		fw:      f,
+		storage: s,
+		aut:     a,
+		db:      d,
+		log:     l,
+	}
+}
+
+func (f *FirmwareHandler) Images(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = f.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		f.responseList(ctx, "Firmware list", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	f.responseList(ctx, "Firmware list", true, "", f.fw.Images())
+}
+
+// Upload Save firmware binary from request body
+func (f *FirmwareHandler) Upload(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = f.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		f.responseList(ctx, "Upload firmware", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var version, _ = url.QueryUnescape(ctx.UserValue("version").(string))
+	var _, err = f.fw.AddImage(ctx.UserValue("type").(string), version, ctx.PostBody())
+	if err != nil {
+		f.responseList(ctx, "Upload firmware", false, err.Error(), nil)
+		return
+	}
+
+	// Save firmware list
+	err = f.db.SaveFirmwareBase()
+	if err != nil {
+		f.responseList(ctx, "Save firmware", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	f.responseList(ctx, "Upload firmware", true, "", nil)
+}
+
+func (f *FirmwareHandler) RemoveImage(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = f.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		f.responseList(ctx, "Remove firmware", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var version, _ = url.QueryUnescape(ctx.UserValue("version").(string))
+	var err = f.fw.RemoveImage(ctx.UserValue("type").(string), version)
+	if err != nil {
+		f.responseList(ctx, "Remove firmware", false, err.Error(), nil)
+		return
+	}
+
+	// Save firmware list
+	err = f.db.SaveFirmwareBase()
+	if err != nil {
+		f.responseList(ctx, "Save firmware", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	f.responseList(ctx, "Remove firmware", true, "", nil)
+}
+
+func (f *FirmwareHandler) Rollouts(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = f.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		f.responseRollouts(ctx, "Firmware rollout", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	f.responseRollouts(ctx, "Firmware rollout", true, "", f.fw.Rollouts())
+}
+
+// Check Device reports current firmware version and asks for newer one
+func (f *FirmwareHandler) Check(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var dev = f.storage.Device(ctx.UserValue("id").(string))
+	if dev == nil {
+		f.responseCheck(ctx, "Check firmware", false, "Device not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = f.aut.ReportValidation(ctx.UserValue("user").(string), dev.Name())
+	if !report {
+		f.responseCheck(ctx, "Check firmware", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var version, _ = url.QueryUnescape(ctx.UserValue("version").(string))
+	dev.SetOnline(true)
+
+	var img, ok = f.fw.Check(dev, version)
+	f.save()
+
+	// Send response
+	if !ok {
+		f.responseCheck(ctx, "Check firmware", true, "", nil)
+		return
+	}
+	f.responseCheck(ctx, "Check firmware", true, "", &img)
+}
+
+// Download Send newest firmware binary for device. MD5 checksum is also
+// sent in x-MD5 header which is checked by ESP http updater
+func (f *FirmwareHandler) Download(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var dev = f.storage.Device(ctx.UserValue("id").(string))
+	if dev == nil {
+		f.responseList(ctx, "Download firmware", false, "Device not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = f.aut.ReportValidation(ctx.UserValue("user").(string), dev.Name())
+	if !report {
+		f.responseList(ctx, "Download firmware", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var img, data, err = f.fw.Download(dev)
+	if err != nil {
+		f.responseList(ctx, "Download firmware", false, err.Error(), nil)
+		return
+	}
+	f.save()
+
+	// Send response
+	ctx.Response.Header.SetContentType("application/octet-stream")
+	ctx.Response.Header.Set("Content-Disposition", "attachment; filename="+img.Type+"-"+img.Version+".bin")
+	ctx.Response.Header.Set("x-MD5", img.MD5)
+	ctx.Response.Header.Set("X-Firmware-Version", img.Version)
+	ctx.Response.Header.Set("X-Firmware-SHA256", img.SHA256)
+	ctx.Response.Header.Set("X-Firmware-Size", strconv.Itoa(img.Size))
+	ctx.Write(data)
+
+	f.log.Info("FIRMWAREH", "Download firmware")
+}
+
+// save Save rollouts and devices versions
+func (f *FirmwareHandler) save() {
+	var err = f.db.SaveFirmwareBase()
+	if err != nil {
+		f.log.Error("FIRMWAREH", "Fail to save firmware base", err.Error())
+	}
+	err = f.db.SaveDeviceBase()
+	if err != nil {
+		f.log.Error("FIRMWAREH", "Fail to save device base", err.Error())
+	}
+}
+
+func (f *FirmwareHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, images []core.FirmwareImage) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.FirmwareListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, img := range images {
+		resp.Images = append(resp.Images, api.FirmwareImageResponse{
+			Type:     img.Type,
+			Version:  img.Version,
+			Size:     img.Size,
+			MD5:      img.MD5,
+			SHA256:   img.SHA256,
+			Uploaded: unixTime(img.Uploaded),
+		})
+	}
+
+	if result {
+		f.log.Info("FIRMWAREH", oper)
+	} else {
+		f.log.Error("FIRMWAREH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (f *FirmwareHandler) responseCheck(ctx *fasthttp.RequestCtx, oper string, result bool, err string, img *core.FirmwareImage) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.FirmwareCheckResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if img != nil {
+		resp.Available = true
+		resp.Version = img.Version
+		resp.Size = img.Size
+		resp.MD5 = img.MD5
+		resp.SHA256 = img.SHA256
+	}
+
+	if result {
+		f.log.Info("FIRMWAREH", oper)
+	} else {
+		f.log.Error("FIRMWAREH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (f *FirmwareHandler) responseRollouts(ctx *fasthttp.RequestCtx, oper string, result bool, err string, rollouts []core.FirmwareRollout) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.FirmwareRolloutListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, rollout := range rollouts {
+		var dev, err = f.storage.DeviceByID(rollout.Device)
+		if err != nil {
+			continue
+		}
+
+		resp.Rollouts = append(resp.Rollouts, api.FirmwareRolloutResponse{
+			Device:   dev.Name(),
+			Type:     dev.Type(),
+			From:     rollout.From,
+			Target:   rollout.Target,
+			Reported: rollout.Reported,
+			State:    rollout.State,
+			Updated:  unixTime(rollout.Updated),
+		})
+	}
+
+	if result {
+		f.log.Info("FIRMWAREH", oper)
+	} else {
+		f.log.Error("FIRMWAREH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
@@ -0,0 +1,72 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/futcity/controller/core"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFirmwareRollout(t *testing.T) {
+	var env = newTestEnv(t)
+	var image = []byte("firmware image")
+
+	for _, version := range []string{"1.9", "1.10"} {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetBody(image)
+		ctx.SetUserValue("user", testAdminKey)
+		ctx.SetUserValue("type", "relay")
+		ctx.SetUserValue("version", version)
+		env.fwh.Upload(&ctx)
+	}
+
+	var latest, ok = env.fw.Latest("relay")
+	if !ok || latest.Version != "1.10" {
+		t.Fatalf("latest firmware is %q, want 1.10", latest.Version)
+	}
+
+	var ctx = request(env.fwh.Check, map[string]string{"user": testUserKey, "id": "relay0", "version": "1.10"})
+	if strings.Contains(string(ctx.Response.Body()), `"available":true`) {
+		t.Error("firmware offered for up to date device")
+	}
+
+	ctx = request(env.fwh.Check, map[string]string{"user": testUserKey, "id": "relay0", "version": "1.2"})
+	if !strings.Contains(string(ctx.Response.Body()), `"available":true`) {
+		t.Errorf("firmware is not offered: %s", ctx.Response.Body())
+	}
+
+	ctx = request(env.fwh.Download, map[string]string{"user": testUserKey, "id": "relay0"})
+	if string(ctx.Response.Body()) != string(image) || string(ctx.Response.Header.Peek("x-MD5")) != latest.MD5 {
+		t.Error("wrong firmware download")
+	}
+
+	var rollouts = env.fw.Rollouts()
+	if len(rollouts) != 1 || rollouts[0].State != core.RolloutDownloading {
+		t.Fatalf("wrong rollout state %v", rollouts)
+	}
+
+	request(env.devh.UpdateInfo, map[string]string{
+		"user": testUserKey, "name": "relay0", "firmware": "1.10", "ip": "10.0.0.2", "mac": "aa:bb"})
+
+	rollouts = env.fw.Rollouts()
+	if rollouts[0].State != core.RolloutInstalled || env.storage.Device("relay0").Firmware() != "1.10" {
+		t.Errorf("rollout is not installed: %v", rollouts)
+	}
+
+	// Other user device has no rights
+	ctx = request(env.fwh.Download, map[string]string{"user": testUserKey, "id": "relay1"})
+	if len(ctx.Response.Header.Peek("x-MD5")) != 0 {
+		t.Error("firmware downloaded without rights")
+	}
+}
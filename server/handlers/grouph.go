@@ -11,7 +11,13 @@
 package handlers
 
 import (
+	"errors"
+	"net/url"
+	"strconv"
+
 	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
 	"github.com/futcity/controller/server/api"
 	"github.com/futcity/controller/utils"
 	jsoniter "github.com/json-iterator/go"
@@ -19,14 +25,23 @@ import (
 )
 
 type GroupHandler struct {
-	aut *auth.Authorization
-	log *utils.Log
+	aut     *auth.Authorization
+	storage *core.Storage
+	groups  *core.Groups
+	cmd     *core.Commander
+	db      *db.Database
+	log     *utils.Log
 }
 
-func NewGroupHandler(a *auth.Authorization, l *utils.Log) *GroupHandler {
+func NewGroupHandler(a *auth.Authorization, s *core.Storage, g *core.Groups, c *core.Commander,
+	d *db.Database, l *utils.Log) *GroupHandler {
 	return &GroupHandler{
-		aut: a,
-		log: l,
+		aut:     a,
+		storage: s,
+		groups:  g,
+		cmd:     c,
+		db:      d,
+		log:     l,
 	}
 }
 
@@ -42,7 +57,178 @@ func (r *GroupHandler) Groups(ctx *fasthttp.RequestCtx) {
 	r.responseList(ctx, "Groups list", true, "", groups)
 }
 
-func (r *GroupHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, groups *[]string) {
+// GroupDevices Get groups with member devices. Admin gets all groups,
+// user gets profile groups with readable devices only
+func (r *GroupHandler) GroupDevices(ctx *fasthttp.RequestCtx) {
+	var key = ctx.UserValue("user").(string)
+
+	// Check user rights
+	var names, err = r.aut.Groups(key)
+	if err != nil {
+		r.responseGroups(ctx, "Groups devices list", false, "Authorization failed", nil)
+		return
+	}
+
+	// Find groups
+	var groups []core.Group
+	if r.aut.IsAdmin(key) {
+		groups = r.groups.Groups()
+	} else {
+		for _, name := range names {
+			var grp, ok = r.groups.Group(name)
+			if !ok {
+				continue
+			}
+
+			var devs []string
+			for _, dev := range grp.Devices {
+				if read, _ := r.aut.Validation(key, dev); read {
+					devs = append(devs, dev)
+				}
+			}
+			grp.Devices = devs
+			groups = append(groups, grp)
+		}
+	}
+
+	// Send response
+	r.responseGroups(ctx, "Groups devices list", true, "", groups)
+}
+
+func (r *GroupHandler) AddGroup(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	r.editGroup(ctx, "Add group", func() error {
+		return r.groups.Add(name)
+	})
+}
+
+func (r *GroupHandler) RemoveGroup(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	r.editGroup(ctx, "Remove group", func() error {
+		var err = r.groups.Remove(name)
+		if err != nil {
+			return err
+		}
+
+		r.aut.RemoveGroup(name)
+		return r.db.SaveProfileBase()
+	})
+}
+
+func (r *GroupHandler) AddGroupDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	r.editGroup(ctx, "Add group device", func() error {
+		if r.storage.Device(device) == nil {
+			return errors.New("Device not found")
+		}
+		return r.groups.AddDevice(name, device)
+	})
+}
+
+func (r *GroupHandler) MoveGroupDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	r.editGroup(ctx, "Move group device", func() error {
+		var pos, err = strconv.Atoi(ctx.UserValue("position").(string))
+		if err != nil {
+			return errors.New("Fail to convert position")
+		}
+		return r.groups.MoveDevice(name, device, pos)
+	})
+}
+
+func (r *GroupHandler) RemoveGroupDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	r.editGroup(ctx, "Remove group device", func() error {
+		return r.groups.RemoveDevice(name, device)
+	})
+}
+
+// SetStatus Set status of all group devices the user can write
+func (r *GroupHandler) SetStatus(ctx *fasthttp.RequestCtx) {
+	var key = ctx.UserValue("user").(string)
+
+	// Check user rights
+	var act = actor(r.aut, key)
+	if act == "" {
+		r.responseCommand(ctx, "Set group status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Find group
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var grp, ok = r.groups.Group(name)
+	if !ok {
+		r.responseCommand(ctx, "Set group status", false, "Group not found", nil)
+		return
+	}
+
+	// Process operation
+	var status, err = strconv.ParseBool(ctx.UserValue("status").(string))
+	if err != nil {
+		r.responseCommand(ctx, "Set group status", false, "Fail to convert status", nil)
+		return
+	}
+
+	var results []api.GroupDeviceResult
+	for _, name := range grp.Devices {
+		var dev = r.storage.Device(name)
+		if dev == nil || !r.cmd.IsSwitchable(dev) {
+			continue
+		}
+
+		// Check user rights and apply status
+		err = r.cmd.Apply(act, core.SceneAction{Device: name, Status: status}, writeAllowed(r.aut, key))
+		if err == core.ErrForbidden {
+			continue
+		}
+
+		var res = api.GroupDeviceResult{Device: name, Result: err == nil}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+
+	// Send response
+	r.responseCommand(ctx, "Set group status", true, "", results)
+}
+
+// editGroup Change groups by admin and save groups base
+func (r *GroupHandler) editGroup(ctx *fasthttp.RequestCtx, oper string, edit func() error) {
+	// Check user rights
+	var admin = r.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		r.responseGroups(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var err = edit()
+	if err != nil {
+		r.responseGroups(ctx, oper, false, err.Error(), nil)
+		return
+	}
+
+	// Save groups
+	err = r.db.SaveGroupBase()
+	if err != nil {
+		r.responseGroups(ctx, "Save group", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	r.responseGroups(ctx, oper, true, "", nil)
+}
+
+func (r *GroupHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, groups []string) {
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	ctx.Response.Header.SetContentType("application/json")
 
@@ -52,7 +238,7 @@ func (r *GroupHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resul
 		Error:     err,
 	}
 
-	for _, group := range *groups {
+	for _, group := range groups {
 		grpResp.Groups = append(grpResp.Groups, group)
 	}
 
@@ -66,3 +252,53 @@ func (r *GroupHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resul
 
 	ctx.Write(bytes)
 }
+
+func (r *GroupHandler) responseGroups(ctx *fasthttp.RequestCtx, oper string, result bool, err string, groups []core.Group) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var grpResp = api.GroupListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, group := range groups {
+		grpResp.Groups = append(grpResp.Groups, api.GroupSingleResponse{
+			Name:    group.Name,
+			Devices: group.Devices,
+		})
+	}
+
+	if result {
+		r.log.Info("GROUPH", oper)
+	} else {
+		r.log.Error("GROUPH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(grpResp)
+
+	ctx.Write(bytes)
+}
+
+func (r *GroupHandler) responseCommand(ctx *fasthttp.RequestCtx, oper string, result bool, err string, devices []api.GroupDeviceResult) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var grpResp = api.GroupCommandResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+		Devices:   devices,
+	}
+
+	if result {
+		r.log.Info("GROUPH", oper)
+	} else {
+		r.log.Error("GROUPH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(grpResp)
+
+	ctx.Write(bytes)
+}
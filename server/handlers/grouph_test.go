@@ -0,0 +1,84 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestGroups(t *testing.T) {
+	var env = newTestEnv(t)
+
+	request(env.grph.AddGroup, map[string]string{"user": testUserKey, "name": "Hall"})
+	if _, ok := env.groups.Group("Hall"); ok {
+		t.Fatal("group added without admin rights")
+	}
+
+	request(env.grph.AddGroup, map[string]string{"user": testAdminKey, "name": "Hall"})
+	for _, dev := range []string{"relay0", "relay1", "dimmer0"} {
+		request(env.grph.AddGroupDevice, map[string]string{"user": testAdminKey, "name": "Hall", "device": dev})
+	}
+	request(env.grph.MoveGroupDevice, map[string]string{"user": testAdminKey, "name": "Hall", "device": "dimmer0",
+		"position": "0"})
+
+	var grp, ok = env.groups.Group("Hall")
+	if !ok || strings.Join(grp.Devices, ",") != "dimmer0,relay0,relay1" {
+		t.Fatal("wrong group devices order:", grp.Devices)
+	}
+
+	// Unknown key and group are refused
+	var resp api.GroupCommandResponse
+	var ctx = request(env.grph.SetStatus, map[string]string{"user": "unknown", "name": "Hall", "status": "true"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result || resp.Error != "Authorization failed" {
+		t.Error("group is switched with unknown key:", resp)
+	}
+	ctx = request(env.grph.SetStatus, map[string]string{"user": testUserKey, "name": "Garden", "status": "true"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result || resp.Error != "Group not found" {
+		t.Error("unknown group is switched:", resp)
+	}
+
+	// Only writable relays are switched
+	request(env.grph.SetStatus, map[string]string{"user": testUserKey, "name": "Hall", "status": "true"})
+	if !env.storage.Device("relay0").(*base.Relay).Status() {
+		t.Error("writable relay was not switched")
+	}
+	if env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Error("relay was switched without write rights")
+	}
+
+	// Removed and renamed devices are kept in sync
+	request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay0", "newname": "boiler"})
+	request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey, "id": strconv.Itoa(env.storage.Device("relay1").ID())})
+
+	var groups db.GroupDB
+	var err = env.cfg.LoadFromFile(&groups, filepath.Join(env.dir, "group.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups.Groups) != 1 || strings.Join(groups.Groups[0].Devices, ",") != "dimmer0,boiler" {
+		t.Error("wrong saved groups:", groups.Groups)
+	}
+
+	request(env.profh.AddProfileGroup, map[string]string{"user": testAdminKey, "name": "user", "group": "Garden"})
+	if grps, _ := env.aut.Groups(testUserKey); len(grps) != 0 {
+		t.Error("unknown group added to profile")
+	}
+}
@@ -0,0 +1,277 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/utils"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	testAdminKey = "admin-key"
+	testUserKey  = "user-key"
+)
+
+type testEnv struct {
+	dir     string
+	log     *utils.Log
+	cfg     *utils.Configs
+	aut     *auth.Authorization
+	storage *core.Storage
+	events  *core.Events
+	fw      *core.Firmware
+	groups  *core.Groups
+	scenes  *core.Scenes
+	locks   *core.Interlocks
+	sched   *core.Scheduler
+	rules   *core.Rules
+	timers  *core.Timers
+	sun     *core.Sun
+	db      *db.Database
+	rec     *core.Reconciler
+	cmd     *core.Commander
+	wdt     *core.Watchdog
+	devh    *DeviceHandler
+	fwh     *FirmwareHandler
+	pairh   *PairingHandler
+	profh   *ProfileHandler
+	grph    *GroupHandler
+	sch     *SceneHandler
+	ilh     *InterlockHandler
+	schedh  *ScheduleHandler
+	ruleh   *RuleHandler
+	relayh  *RelayHandler
+	dimh    *DimmerHandler
+	senh    *SensorHandler
+	meth    *MeterHandler
+	pushh   *PushHandler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	var dir, err = ioutil.TempDir("", "futcity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	var env = &testEnv{dir: dir}
+	var log = utils.NewLog()
+	log.SetPath(dir + string(os.PathSeparator))
+	env.log = log
+
+	env.cfg = utils.NewConfigs()
+	env.aut = auth.NewAuthorization()
+	env.storage = core.NewStorage(log)
+	env.events = core.NewEvents()
+	env.fw = core.NewFirmware(env.storage, env.events, log)
+	env.fw.SetPath(filepath.Join(dir, "firmware"))
+	env.groups = core.NewGroups()
+	env.scenes = core.NewScenes()
+	env.locks = core.NewInterlocks()
+	env.rec = core.NewReconciler(env.storage, env.events, log)
+	env.cmd = core.NewCommander(env.storage, env.rec, env.scenes, env.locks, env.events, log)
+	env.sun = core.NewSun()
+	env.sched = core.NewScheduler(env.cmd, env.sun, log)
+	env.rules = core.NewRules(env.storage, env.cmd, env.events, env.sun, log)
+	env.timers = core.NewTimers(env.storage, env.cmd, log)
+	env.db = db.NewDatabase(env.cfg, env.aut, env.storage, env.fw, env.groups, env.scenes, env.locks, env.sched, env.rules, env.timers, log)
+	registerTypes(t, env.storage, env.db)
+	env.wdt = core.NewWatchdog(env.storage, env.events, log)
+
+	env.db.SetDBType("text")
+	for _, name := range []string{"device", "profile", "relay", "light", "dimmer", "multirelay", "meter", "firmware", "token", "group", "scene", "interlock", "schedule", "rule", "timer"} {
+		env.db.AddFilename(name, filepath.Join(dir, name+".json"))
+	}
+
+	env.devh = NewDeviceHandler(env.storage, env.aut, env.fw, env.events, env.db, log)
+	env.fwh = NewFirmwareHandler(env.fw, env.storage, env.aut, env.db, log)
+	env.pairh = NewPairingHandler(env.storage, env.aut, env.db, log)
+	env.profh = NewProfileHandler(env.storage, env.aut, env.groups, env.events, env.db, log)
+	env.grph = NewGroupHandler(env.aut, env.storage, env.groups, env.cmd, env.db, log)
+	env.sch = NewSceneHandler(env.aut, env.storage, env.scenes, env.cmd, env.sched, env.rules, env.db, log)
+	env.ilh = NewInterlockHandler(env.aut, env.storage, env.locks, env.db, log)
+	env.schedh = NewScheduleHandler(env.aut, env.cmd, env.sched, env.sun, env.db, log)
+	env.ruleh = NewRuleHandler(env.aut, env.storage, env.rules, env.db, log)
+	env.relayh = NewRelayHandler(env.storage, env.aut, log, env.rec, env.cmd, env.timers, env.events)
+	env.dimh = NewDimmerHandler(env.storage, env.aut, log, env.cmd, env.events)
+	env.senh = NewSensorHandler(env.storage, env.aut, env.events, log)
+	env.meth = NewMeterHandler(env.storage, env.aut, env.events, log)
+	env.pushh = NewPushHandler(env.aut, env.storage, env.events, log)
+
+	env.aut.AddProfile(auth.NewProfile("admin", testAdminKey, true))
+	var user = auth.NewProfile("user", testUserKey, false)
+	user.AddDevice(auth.NewProfileDevice("relay0", true, true))
+	env.aut.AddProfile(user)
+
+	for i := 0; i < 4; i++ {
+		var err = env.storage.AddDevice("relay"+strconv.Itoa(i), "Relay", "relay")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = env.storage.AddDevice("dimmer0", "Dimmer", "dimmer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return env
+}
+
+// registerTypes Register device types with databases like types packages
+// do on start
+func registerTypes(t *testing.T, s *core.Storage, d *db.Database) {
+	s.RegisterType("relay", func(name string, desc string) devices.IDevice {
+		return base.NewRelay(name, desc)
+	})
+	s.RegisterType("light", func(name string, desc string) devices.IDevice {
+		return base.NewLight(name, desc, false, nil)
+	})
+	s.RegisterType("sensor", func(name string, desc string) devices.IDevice {
+		return base.NewSensor(name, desc)
+	})
+	s.RegisterType("dimmer", func(name string, desc string) devices.IDevice {
+		return base.NewDimmer(name, desc)
+	})
+	s.RegisterType("meter", func(name string, desc string) devices.IDevice {
+		return base.NewMeter(name, desc)
+	})
+	s.RegisterType("multirelay", func(name string, desc string) devices.IDevice {
+		return base.NewMultiRelay(name, desc)
+	})
+	for typ := range base.BinaryTypes {
+		var devType = typ
+		s.RegisterType(devType, func(name string, desc string) devices.IDevice {
+			return base.NewBinary(name, desc, devType)
+		})
+	}
+
+	var bases = []struct {
+		devType string
+		load    func() error
+		base    func() interface{}
+	}{
+		{"relay", d.LoadRelayBase, d.RelayBase},
+		{"light", d.LoadLightBase, d.LightBase},
+		{"dimmer", d.LoadDimmerBase, d.DimmerBase},
+		{"meter", d.LoadMeterBase, d.MeterBase},
+		{"multirelay", d.LoadMultiRelayBase, d.MultiRelayBase},
+	}
+	for _, b := range bases {
+		var err = d.RegisterBase(b.devType, b.load, b.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func request(handler fasthttp.RequestHandler, values map[string]string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+
+	for key, value := range values {
+		ctx.SetUserValue(key, value)
+	}
+	handler(&ctx)
+
+	return &ctx
+}
+
+func TestConcurrentHandlers(t *testing.T) {
+	var env = newTestEnv(t)
+	var wg sync.WaitGroup
+
+	var run = func(count int, fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	for w := 0; w < 4; w++ {
+		var worker = w
+
+		run(50, func(i int) {
+			request(env.relayh.Switch, map[string]string{"user": testUserKey, "id": "relay0"})
+		})
+		run(50, func(i int) {
+			request(env.relayh.SetStatus, map[string]string{
+				"user": testAdminKey, "id": "relay" + strconv.Itoa(i%4), "status": strconv.FormatBool(i%2 == 0)})
+		})
+		run(50, func(i int) {
+			request(env.relayh.Update, map[string]string{
+				"user": testAdminKey, "id": "relay" + strconv.Itoa(i%4), "state": strconv.FormatBool(i%3 == 0)})
+			request(env.relayh.Status, map[string]string{"user": testUserKey, "id": "relay0"})
+			request(env.relayh.Devices, map[string]string{"user": testUserKey})
+		})
+		run(50, func(i int) {
+			var name = "dev" + strconv.Itoa(worker) + "_" + strconv.Itoa(i)
+			request(env.devh.AddDevice, map[string]string{
+				"user": testAdminKey, "name": name, "desc": "Test", "type": "relay"})
+			request(env.devh.DeviceList, map[string]string{"user": testAdminKey})
+
+			var dev = env.storage.Device(name)
+			if dev != nil {
+				request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey, "id": strconv.Itoa(dev.ID())})
+			}
+		})
+		run(50, func(i int) {
+			var device = "relay" + strconv.Itoa(i%4)
+			request(env.profh.AddProfileDevice, map[string]string{
+				"user": testAdminKey, "name": "user", "device": device, "read": "true", "write": "false"})
+			request(env.profh.AddProfileGroup, map[string]string{"user": testAdminKey, "name": "user", "group": "Group"})
+			request(env.profh.RemoveProfileGroup, map[string]string{"user": testAdminKey, "name": "user", "group": "Group"})
+			request(env.profh.ProfileList, map[string]string{"user": testAdminKey})
+			request(env.grph.Groups, map[string]string{"user": testUserKey})
+		})
+		run(50, func(i int) {
+			request(env.dimh.SetLevel, map[string]string{
+				"user": testAdminKey, "id": "dimmer0", "level": strconv.Itoa(i * 2), "time": "1"})
+			request(env.dimh.Status, map[string]string{"user": testAdminKey, "id": "dimmer0"})
+		})
+		run(50, func(i int) {
+			env.wdt.Check()
+			env.rec.Check()
+		})
+	}
+	wg.Wait()
+
+	// Saved relays must match storage
+	var relays db.RelaysDB
+	var err = env.cfg.LoadFromFile(&relays, filepath.Join(env.dir, "relay.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, saved := range relays.Relays {
+		var relay, ok = env.storage.Device(saved.Name).(*base.Relay)
+		if !ok {
+			continue
+		}
+		if relay.Status() != saved.Status {
+			t.Errorf("relay %q saved status %v, storage status %v", saved.Name, saved.Status, relay.Status())
+		}
+	}
+}
@@ -0,0 +1,199 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type InterlockHandler struct {
+	aut     *auth.Authorization
+	storage *core.Storage
+	locks   *core.Interlocks
+	db      *db.Database
+	log     *utils.Log
+}
+
+func NewInterlockHandler(a *auth.Authorization, s *core.Storage, il *core.Interlocks, d *db.Database,
+	l *utils.Log) *InterlockHandler {
+	return &InterlockHandler{
+		aut:     a,
+		storage: s,
+		locks:   il,
+		db:      d,
+		log:     l,
+	}
+}
+
+func (i *InterlockHandler) Interlocks(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = i.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		i.responseList(ctx, "Interlocks list", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	i.responseList(ctx, "Interlocks list", true, "", i.locks.Interlocks())
+}
+
+func (i *InterlockHandler) AddInterlock(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	i.editInterlock(ctx, "Add interlock", func() error {
+		return i.locks.Add(name, ctx.UserValue("policy").(string))
+	})
+}
+
+func (i *InterlockHandler) RemoveInterlock(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	i.editInterlock(ctx, "Remove interlock", func() error {
+		return i.locks.Remove(name)
+	})
+}
+
+func (i *InterlockHandler) SetPolicy(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	i.editInterlock(ctx, "Set interlock policy", func() error {
+		return i.locks.SetPolicy(name, ctx.UserValue("policy").(string))
+	})
+}
+
+func (i *InterlockHandler) AddDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	i.editInterlock(ctx, "Add interlock device", func() error {
+		if _, ok := i.storage.Device(device).(*base.Relay); !ok {
+			return errors.New("Relay not found")
+		}
+		return i.locks.AddDevice(name, device, -1)
+	})
+}
+
+func (i *InterlockHandler) RemoveDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	i.editInterlock(ctx, "Remove interlock device", func() error {
+		return i.locks.RemoveDevice(name, ctx.UserValue("device").(string), -1)
+	})
+}
+
+// AddChannel Add multi relay channel to interlock
+func (i *InterlockHandler) AddChannel(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	i.editInterlock(ctx, "Add interlock channel", func() error {
+		var channel, err = strconv.Atoi(ctx.UserValue("channel").(string))
+		if err != nil {
+			return errors.New("Fail to convert channel")
+		}
+
+		var relay, ok = i.storage.Device(device).(*base.MultiRelay)
+		if !ok {
+			return errors.New("Multi relay not found")
+		}
+		if relay.Channel(channel) == nil {
+			return errors.New("Channel not found")
+		}
+		return i.locks.AddDevice(name, device, channel)
+	})
+}
+
+// RemoveChannel Remove multi relay channel from interlock
+func (i *InterlockHandler) RemoveChannel(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	i.editInterlock(ctx, "Remove interlock channel", func() error {
+		var channel, err = strconv.Atoi(ctx.UserValue("channel").(string))
+		if err != nil {
+			return errors.New("Fail to convert channel")
+		}
+		return i.locks.RemoveDevice(name, ctx.UserValue("device").(string), channel)
+	})
+}
+
+// editInterlock Change interlocks by admin and save interlocks base
+func (i *InterlockHandler) editInterlock(ctx *fasthttp.RequestCtx, oper string, edit func() error) {
+	// Check user rights
+	var admin = i.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		i.responseList(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var err = edit()
+	if err != nil {
+		i.responseList(ctx, oper, false, err.Error(), nil)
+		return
+	}
+
+	// Save interlocks
+	err = i.db.SaveInterlockBase()
+	if err != nil {
+		i.responseList(ctx, "Save interlock", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	i.responseList(ctx, oper, true, "", nil)
+}
+
+func (i *InterlockHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, locks []core.Interlock) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var ilResp = api.InterlockListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, lock := range locks {
+		var il = api.InterlockSingleResponse{
+			Name:   lock.Name,
+			Policy: lock.Policy,
+		}
+		for _, m := range lock.Members {
+			if m.Channel < 0 {
+				il.Devices = append(il.Devices, m.Device)
+			} else {
+				il.Channels = append(il.Channels, api.InterlockChannelResponse{Device: m.Device, Channel: m.Channel})
+			}
+		}
+		ilResp.Interlocks = append(ilResp.Interlocks, il)
+	}
+
+	if result {
+		i.log.Info("INTERLOCKH", oper)
+	} else {
+		i.log.Error("INTERLOCKH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(ilResp)
+
+	ctx.Write(bytes)
+}
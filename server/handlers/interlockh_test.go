@@ -0,0 +1,106 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestInterlocks(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	// Motor direction relays refuse, heater and cooler force
+	for _, lock := range []map[string]string{
+		{"user": testAdminKey, "name": "motor", "policy": core.InterlockRefuse},
+		{"user": testAdminKey, "name": "climate", "policy": core.InterlockForce},
+	} {
+		request(env.ilh.AddInterlock, lock)
+	}
+	for name, devices := range map[string][]string{"motor": {"relay0", "relay1"}, "climate": {"relay2", "relay3"}} {
+		for _, dev := range devices {
+			request(env.ilh.AddDevice, map[string]string{"user": testAdminKey, "name": name, "device": dev})
+		}
+	}
+	var ctx = request(env.ilh.AddDevice, map[string]string{"user": testAdminKey, "name": "motor", "device": "dimmer0"})
+	var listResp api.InterlockListResponse
+	json.Unmarshal(ctx.Response.Body(), &listResp)
+	if listResp.Result {
+		t.Error("dimmer is added to interlock")
+	}
+
+	// Multi relay channels are added by channel number
+	var err = env.storage.AddDevice("board0", "Board", "multirelay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.storage.Device("board0").(*base.MultiRelay).SetChannels(2)
+	for _, ch := range []string{"1", "2"} {
+		ctx = request(env.ilh.AddChannel, map[string]string{"user": testAdminKey, "name": "climate", "device": "board0",
+			"channel": ch})
+	}
+	json.Unmarshal(ctx.Response.Body(), &listResp)
+	if listResp.Result {
+		t.Error("missing channel is added to interlock")
+	}
+
+	var resp api.RelayResponse
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay0", "status": "true"})
+	ctx = request(env.relayh.Switch, map[string]string{"user": testAdminKey, "id": "relay1"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result || resp.Status || !strings.Contains(resp.Error, "relay0") {
+		t.Error("interlocked relay was turned on:", resp)
+	}
+
+	// Forced relay waits for other relay reporting off and is turned on
+	env.cmd.Start()
+	defer env.cmd.Stop()
+
+	var relay = env.storage.Device("relay3").(*base.Relay)
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay2", "status": "true"})
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay2", "state": "true"})
+	ctx = request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay3", "status": "true"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result || !strings.Contains(resp.Error, "switching off") || relay.Status() {
+		t.Error("forced relay was turned on before other relay reported off:", resp)
+	}
+
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay2", "state": "false"})
+	var deadline = time.Now().Add(time.Second)
+	for !relay.Status() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !relay.Status() {
+		t.Error("pending forced relay was not turned on")
+	}
+
+	// Check saved base
+	var locks db.InterlockDB
+	err = env.cfg.LoadFromFile(&locks, filepath.Join(env.dir, "interlock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks.Interlocks) != 2 || locks.Interlocks[1].Name != "motor" || len(locks.Interlocks[1].Devices) != 2 {
+		t.Error("wrong saved interlocks:", locks)
+	}
+	if len(locks.Interlocks[0].Channels) != 1 || locks.Interlocks[0].Channels[0].Channel != 1 {
+		t.Error("wrong saved interlock channels:", locks.Interlocks[0])
+	}
+}
@@ -0,0 +1,237 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type LightHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	cmd     *core.Commander
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewLightHandler(s *core.Storage, a *auth.Authorization, l *utils.Log, cmd *core.Commander,
+	e *core.Events) *LightHandler {
+	return &LightHandler{
+		storage: s,
+		aut:     a,
+		cmd:     cmd,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Light web API routes
+func (l *LightHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqLightStatus, l.Status},
+		{api.HttpReqLightSet, l.SetStatus},
+		{api.HttpReqLightUpdate, l.Update},
+		{api.HttpReqLightSwitch, l.Switch},
+		{api.HttpReqLightList, l.Devices},
+	}
+}
+
+func (l *LightHandler) Switch(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var light, ok = l.storage.Device(ctx.UserValue("id").(string)).(*base.Light)
+	if !ok {
+		l.response(ctx, "Switch light", false, "Light not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = l.aut.Validation(ctx.UserValue("user").(string), light.Name())
+	if !write {
+		l.response(ctx, "Switch light", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation and save to database
+	var err = l.cmd.Switch(actor(l.aut, ctx.UserValue("user").(string)), light)
+	if err != nil {
+		l.response(ctx, "Switch light", false, err.Error(), light)
+		return
+	}
+
+	// Send response
+	l.response(ctx, "Switch light", true, "", light)
+}
+
+func (l *LightHandler) SetStatus(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var light, ok = l.storage.Device(ctx.UserValue("id").(string)).(*base.Light)
+	if !ok {
+		l.response(ctx, "Set light status", false, "Light not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = l.aut.Validation(ctx.UserValue("user").(string), light.Name())
+	if !write {
+		l.response(ctx, "Set light status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var status, err = strconv.ParseBool(ctx.UserValue("status").(string))
+	if err != nil {
+		l.response(ctx, "Set light status", false, "Fail to convert status", light)
+		return
+	}
+
+	// Apply changes and save to database
+	err = l.cmd.SetStatus(actor(l.aut, ctx.UserValue("user").(string)), light, status)
+	if err != nil {
+		l.response(ctx, "Set light status", false, err.Error(), light)
+		return
+	}
+
+	// Send response
+	l.response(ctx, "Set light status", true, "", light)
+}
+
+func (l *LightHandler) Status(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var light, ok = l.storage.Device(ctx.UserValue("id").(string)).(*base.Light)
+	if !ok {
+		l.response(ctx, "Get light status", false, "Light not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = l.aut.Validation(ctx.UserValue("user").(string), light.Name())
+	if !read {
+		l.response(ctx, "Get light status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	l.response(ctx, "Get light status", true, "", light)
+}
+
+func (l *LightHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var lights []*base.Light
+	for _, device := range l.storage.FindDevices(deviceFilter(ctx, "light")) {
+		var read, _ = l.aut.Validation(ctx.UserValue("user").(string), device.Name())
+		if read {
+			lights = append(lights, device.(*base.Light))
+		}
+	}
+
+	// Send response
+	l.responseList(ctx, "Get lights list", true, "", lights)
+}
+
+func (l *LightHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var light, ok = l.storage.Device(ctx.UserValue("id").(string)).(*base.Light)
+	if !ok {
+		l.response(ctx, "Update light", false, "Light not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = l.aut.ReportValidation(ctx.UserValue("user").(string), light.Name())
+	if !report {
+		l.response(ctx, "Update light", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var state, err = strconv.ParseBool(ctx.UserValue("state").(string))
+	if err != nil {
+		l.response(ctx, "Update light", false, "Fail to convert state", light)
+		return
+	}
+	var changed = light.State() != state
+	light.Update(state)
+	if changed {
+		l.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(l.aut, ctx.UserValue("user").(string)),
+			Device: light.Name(),
+			Value:  core.DeviceState(light),
+		})
+	}
+
+	// Send response
+	l.response(ctx, "Update light", true, "", light)
+}
+
+func (l *LightHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, light *base.Light) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.LightResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if light != nil {
+		resp.Status = light.Status()
+		resp.State = light.State()
+	}
+
+	if result && oper != "Update light" {
+		l.log.Info("LIGHTH", oper)
+	} else {
+		l.log.Error("LIGHTH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (l *LightHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, lights []*base.Light) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var lightResp = api.LightDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, light := range lights {
+		lightResp.Lights = append(lightResp.Lights, api.LightSingleDevResponse{
+			Name:        light.Name(),
+			Description: light.Description(),
+			Online:      light.Online(),
+			Status:      light.Status(),
+			State:       light.State(),
+		})
+	}
+
+	if result {
+		l.log.Info("LIGHTH", oper)
+	} else {
+		l.log.Error("LIGHTH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(lightResp)
+
+	ctx.Write(bytes)
+}
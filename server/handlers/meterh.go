@@ -0,0 +1,259 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type MeterHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewMeterHandler(s *core.Storage, a *auth.Authorization, e *core.Events, l *utils.Log) *MeterHandler {
+	return &MeterHandler{
+		storage: s,
+		aut:     a,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Energy meter web API routes
+func (m *MeterHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqMeterStatus, m.Status},
+		{api.HttpReqMeterDaily, m.Daily},
+		{api.HttpReqMeterMonthly, m.Monthly},
+		{api.HttpReqMeterUpdate, m.Update},
+		{api.HttpReqMeterList, m.Devices},
+	}
+}
+
+func (m *MeterHandler) Status(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var meter, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.Meter)
+	if !ok {
+		m.response(ctx, "Get meter status", false, "Meter not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = m.aut.Validation(ctx.UserValue("user").(string), meter.Name())
+	if !read {
+		m.response(ctx, "Get meter status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Get meter status", true, "", meter)
+}
+
+func (m *MeterHandler) Daily(ctx *fasthttp.RequestCtx) {
+	m.totals(ctx, "Get meter daily totals", func(meter *base.Meter) []base.MeterTotal {
+		return meter.Daily()
+	})
+}
+
+func (m *MeterHandler) Monthly(ctx *fasthttp.RequestCtx) {
+	m.totals(ctx, "Get meter monthly totals", func(meter *base.Meter) []base.MeterTotal {
+		return meter.Monthly()
+	})
+}
+
+func (m *MeterHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var meters []*base.Meter
+	for _, device := range m.storage.FindDevices(deviceFilter(ctx, "meter")) {
+		var read, _ = m.aut.Validation(ctx.UserValue("user").(string), device.Name())
+		if read {
+			meters = append(meters, device.(*base.Meter))
+		}
+	}
+
+	// Send response
+	m.responseList(ctx, "Get meters list", true, "", meters)
+}
+
+func (m *MeterHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var meter, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.Meter)
+	if !ok {
+		m.response(ctx, "Update meter", false, "Meter not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = m.aut.ReportValidation(ctx.UserValue("user").(string), meter.Name())
+	if !report {
+		m.response(ctx, "Update meter", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var values [4]float64
+	for i, name := range []string{"voltage", "current", "power", "energy"} {
+		var value, err = strconv.ParseFloat(ctx.UserValue(name).(string), 64)
+		if err != nil {
+			m.response(ctx, "Update meter", false, "Fail to convert "+name, meter)
+			return
+		}
+		values[i] = value
+	}
+
+	// Apply changes and save to database
+	var old = core.DeviceState(meter)
+	var err = m.storage.Commit(meter.Type(), func() error {
+		meter.Update(values[0], values[1], values[2], values[3])
+		return nil
+	})
+	if core.DeviceState(meter) != old {
+		m.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(m.aut, ctx.UserValue("user").(string)),
+			Device: meter.Name(),
+			Value:  core.DeviceState(meter),
+		})
+	}
+	if err != nil {
+		m.response(ctx, "Save to meter database", false, err.Error(), meter)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Update meter", true, "", meter)
+}
+
+func (m *MeterHandler) totals(ctx *fasthttp.RequestCtx, oper string, totals func(meter *base.Meter) []base.MeterTotal) {
+	// Find device in storage
+	var meter, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.Meter)
+	if !ok {
+		m.responseTotals(ctx, oper, false, "Meter not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = m.aut.Validation(ctx.UserValue("user").(string), meter.Name())
+	if !read {
+		m.responseTotals(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	m.responseTotals(ctx, oper, true, "", totals(meter))
+}
+
+func (m *MeterHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, meter *base.Meter) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.MeterResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if meter != nil {
+		var now = time.Now()
+		resp.Voltage = meter.Voltage()
+		resp.Current = meter.Current()
+		resp.Power = meter.Power()
+		resp.Counter = meter.Counter()
+		resp.Total = meter.Total()
+		resp.Today = meter.Day(now)
+		resp.Month = meter.Month(now)
+	}
+
+	if result && oper != "Update meter" {
+		m.log.Info("METERH", oper)
+	} else if !result {
+		m.log.Error("METERH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (m *MeterHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, meters []*base.Meter) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var metResp = api.MeterDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	var now = time.Now()
+	for _, meter := range meters {
+		metResp.Meters = append(metResp.Meters, api.MeterSingleDevResponse{
+			Name:        meter.Name(),
+			Description: meter.Description(),
+			Online:      meter.Online(),
+			Voltage:     meter.Voltage(),
+			Current:     meter.Current(),
+			Power:       meter.Power(),
+			Total:       meter.Total(),
+			Today:       meter.Day(now),
+			Month:       meter.Month(now),
+		})
+	}
+
+	if result {
+		m.log.Info("METERH", oper)
+	} else {
+		m.log.Error("METERH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(metResp)
+
+	ctx.Write(bytes)
+}
+
+func (m *MeterHandler) responseTotals(ctx *fasthttp.RequestCtx, oper string, result bool, err string, totals []base.MeterTotal) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var totResp = api.MeterTotalsResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, total := range totals {
+		totResp.Totals = append(totResp.Totals, api.MeterTotalResponse{
+			Period: total.Period,
+			Energy: total.Energy,
+		})
+	}
+
+	if result {
+		m.log.Info("METERH", oper)
+	} else {
+		m.log.Error("METERH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(totResp)
+
+	ctx.Write(bytes)
+}
@@ -0,0 +1,44 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+func TestMeterEvents(t *testing.T) {
+	var env = newTestEnv(t)
+	var sub = env.events.Subscribe(0, core.EventStateChanged)
+
+	var err = env.storage.AddDevice("meter0", "Test", "meter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only changed state is published
+	for i := 0; i < 2; i++ {
+		request(env.meth.Update, map[string]string{"user": testAdminKey, "id": "meter0", "voltage": "230",
+			"current": "1", "power": "230", "energy": "10"})
+	}
+
+	var want = core.Event{Type: core.EventStateChanged, Actor: core.UserActor("admin"), Device: "meter0", Value: "230"}
+	if len(sub.Events()) != 1 {
+		t.Fatal("wrong events count:", len(sub.Events()))
+	}
+	var e = <-sub.Events()
+	e.Time = time.Time{}
+	if e != want {
+		t.Error("wrong event:", e, "want:", want)
+	}
+}
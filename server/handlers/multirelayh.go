@@ -0,0 +1,364 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type MultiRelayHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	cmd     *core.Commander
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewMultiRelayHandler(s *core.Storage, a *auth.Authorization, l *utils.Log,
+	cmd *core.Commander, e *core.Events) *MultiRelayHandler {
+	return &MultiRelayHandler{
+		storage: s,
+		aut:     a,
+		cmd:     cmd,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Multi-channel relay web API routes
+func (m *MultiRelayHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqMultiRelayStatus, m.Status},
+		{api.HttpReqMultiRelaySet, m.SetStatus},
+		{api.HttpReqMultiRelaySwitch, m.Switch},
+		{api.HttpReqMultiRelayDesc, m.SetDescription},
+		{api.HttpReqMultiRelayChannels, m.SetChannels},
+		{api.HttpReqMultiRelayUpdate, m.Update},
+		{api.HttpReqMultiRelayList, m.Devices},
+	}
+}
+
+func (m *MultiRelayHandler) Switch(ctx *fasthttp.RequestCtx) {
+	// Find device channel in storage
+	var relay, _, num, err = m.channel(ctx)
+	if err != "" {
+		m.response(ctx, "Switch relay channel", false, err, nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = m.aut.ChannelValidation(ctx.UserValue("user").(string), relay.Name(), num)
+	if !write {
+		m.response(ctx, "Switch relay channel", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation and save to database
+	var errCmd = m.cmd.SwitchChannel(actor(m.aut, ctx.UserValue("user").(string)), relay, num)
+	if errCmd != nil {
+		m.response(ctx, "Switch relay channel", false, errCmd.Error(), relay)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Switch relay channel", true, "", relay)
+}
+
+func (m *MultiRelayHandler) SetStatus(ctx *fasthttp.RequestCtx) {
+	// Find device channel in storage
+	var relay, _, num, err = m.channel(ctx)
+	if err != "" {
+		m.response(ctx, "Set relay channel status", false, err, nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = m.aut.ChannelValidation(ctx.UserValue("user").(string), relay.Name(), num)
+	if !write {
+		m.response(ctx, "Set relay channel status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var status, errConv = strconv.ParseBool(ctx.UserValue("status").(string))
+	if errConv != nil {
+		m.response(ctx, "Set relay channel status", false, "Fail to convert status", relay)
+		return
+	}
+
+	// Apply changes and save to database
+	var errCmd = m.cmd.SetChannelStatus(actor(m.aut, ctx.UserValue("user").(string)), relay, num, status)
+	if errCmd != nil {
+		m.response(ctx, "Set relay channel status", false, errCmd.Error(), relay)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Set relay channel status", true, "", relay)
+}
+
+func (m *MultiRelayHandler) SetDescription(ctx *fasthttp.RequestCtx) {
+	// Find device channel in storage
+	var relay, _, num, err = m.channel(ctx)
+	if err != "" {
+		m.response(ctx, "Set relay channel description", false, err, nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = m.aut.ChannelValidation(ctx.UserValue("user").(string), relay.Name(), num)
+	if !write {
+		m.response(ctx, "Set relay channel description", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var desc, _ = url.QueryUnescape(ctx.UserValue("desc").(string))
+
+	// Apply changes and save to database
+	var errCmd = m.cmd.SetChannelDescription(actor(m.aut, ctx.UserValue("user").(string)), relay, num, desc)
+	if errCmd != nil {
+		m.response(ctx, "Set relay channel description", false, errCmd.Error(), relay)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Set relay channel description", true, "", relay)
+}
+
+// SetChannels Set board channels count by admin
+func (m *MultiRelayHandler) SetChannels(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.MultiRelay)
+	if !ok {
+		m.response(ctx, "Set multi relay channels", false, "Multi relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var admin = m.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		m.response(ctx, "Set multi relay channels", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var count, err = strconv.Atoi(ctx.UserValue("count").(string))
+	if err != nil {
+		m.response(ctx, "Set multi relay channels", false, "Fail to convert channels count", relay)
+		return
+	}
+
+	// Apply changes and save to database
+	err = m.cmd.SetChannels(actor(m.aut, ctx.UserValue("user").(string)), relay, count)
+	if err != nil {
+		m.response(ctx, "Set multi relay channels", false, err.Error(), relay)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Set multi relay channels", true, "", relay)
+}
+
+func (m *MultiRelayHandler) Status(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.MultiRelay)
+	if !ok {
+		m.response(ctx, "Get multi relay status", false, "Multi relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var channels = m.readableChannels(ctx.UserValue("user").(string), relay)
+	if len(channels) == 0 {
+		m.response(ctx, "Get multi relay status", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	m.response(ctx, "Get multi relay status", true, "", relay)
+}
+
+func (m *MultiRelayHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var relays []*base.MultiRelay
+	for _, device := range m.storage.FindDevices(deviceFilter(ctx, "")) {
+		var relay, ok = device.(*base.MultiRelay)
+		if !ok {
+			continue
+		}
+
+		if len(m.readableChannels(ctx.UserValue("user").(string), relay)) > 0 {
+			relays = append(relays, relay)
+		}
+	}
+
+	// Send response
+	m.responseList(ctx, "Get multi relays list", true, "", relays)
+}
+
+func (m *MultiRelayHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.MultiRelay)
+	if !ok {
+		m.response(ctx, "Update multi relay", false, "Multi relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = m.aut.ReportValidation(ctx.UserValue("user").(string), relay.Name())
+	if !report {
+		m.response(ctx, "Update multi relay", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation, states are sent as "0" and "1" chars for each channel
+	var states []bool
+	for _, c := range ctx.UserValue("states").(string) {
+		if c != '0' && c != '1' {
+			m.response(ctx, "Update multi relay", false, "Fail to convert states", relay)
+			return
+		}
+		states = append(states, c == '1')
+	}
+
+	var old = core.DeviceState(relay)
+	var err = relay.Update(states)
+	if err != nil {
+		m.response(ctx, "Update multi relay", false, err.Error(), relay)
+		return
+	}
+	if core.DeviceState(relay) != old {
+		m.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(m.aut, ctx.UserValue("user").(string)),
+			Device: relay.Name(),
+			Value:  core.DeviceState(relay),
+		})
+	}
+
+	// Send response
+	m.response(ctx, "Update multi relay", true, "", relay)
+}
+
+// channel Find device channel by request values
+func (m *MultiRelayHandler) channel(ctx *fasthttp.RequestCtx) (*base.MultiRelay, *base.RelayChannel, int, string) {
+	var relay, ok = m.storage.Device(ctx.UserValue("id").(string)).(*base.MultiRelay)
+	if !ok {
+		return nil, nil, 0, "Multi relay not found"
+	}
+
+	var num, err = strconv.Atoi(ctx.UserValue("channel").(string))
+	if err != nil {
+		return nil, nil, 0, "Fail to convert channel"
+	}
+
+	var channel = relay.Channel(num)
+	if channel == nil {
+		return nil, nil, 0, "Channel not found"
+	}
+
+	return relay, channel, num, ""
+}
+
+// readableChannels Get channels numbers available for reading
+func (m *MultiRelayHandler) readableChannels(key string, relay *base.MultiRelay) []int {
+	var list []int
+
+	for i := range relay.Channels() {
+		var read, _ = m.aut.ChannelValidation(key, relay.Name(), i)
+		if read {
+			list = append(list, i)
+		}
+	}
+
+	return list
+}
+
+func (m *MultiRelayHandler) channelsResponse(key string, relay *base.MultiRelay) []api.RelayChannelResponse {
+	var list []api.RelayChannelResponse
+
+	for _, num := range m.readableChannels(key, relay) {
+		var channel = relay.Channel(num)
+		list = append(list, api.RelayChannelResponse{
+			Channel:     num,
+			Description: channel.Description(),
+			Status:      channel.Status(),
+			State:       channel.State(),
+		})
+	}
+
+	return list
+}
+
+func (m *MultiRelayHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, relay *base.MultiRelay) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.MultiRelayResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if relay != nil {
+		resp.Channels = m.channelsResponse(ctx.UserValue("user").(string), relay)
+	}
+
+	if result && oper != "Update multi relay" {
+		m.log.Info("MULTIRELAYH", oper)
+	} else if !result {
+		m.log.Error("MULTIRELAYH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (m *MultiRelayHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, relays []*base.MultiRelay) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var relResp = api.MultiRelayDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, relay := range relays {
+		relResp.MultiRelays = append(relResp.MultiRelays, api.MultiRelaySingleDevResponse{
+			Name:        relay.Name(),
+			Description: relay.Description(),
+			Type:        relay.Type(),
+			Online:      relay.Online(),
+			Channels:    m.channelsResponse(ctx.UserValue("user").(string), relay),
+		})
+	}
+
+	if result {
+		m.log.Info("MULTIRELAYH", oper)
+	} else {
+		m.log.Error("MULTIRELAYH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(relResp)
+
+	ctx.Write(bytes)
+}
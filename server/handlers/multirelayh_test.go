@@ -0,0 +1,108 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestMultiRelayRights(t *testing.T) {
+	var env = newTestEnv(t)
+	var mrh = NewMultiRelayHandler(env.storage, env.aut, env.log, env.cmd, env.events)
+
+	var err = env.storage.AddDevice("board0", "Garage", "multirelay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prof = env.aut.ProfileByKey(testUserKey)
+	var dev = auth.NewProfileDevice("board0", false, false)
+	dev.AddChannel(auth.NewProfileChannel(1, true, true))
+	prof.AddDevice(dev)
+
+	// Channels count is set by admin only
+	var resp api.MultiRelayResponse
+	var ctx = request(mrh.SetChannels, map[string]string{"user": testUserKey, "id": "board0", "count": "4"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("channels count is set by user")
+	}
+	ctx = request(mrh.SetChannels, map[string]string{"user": testAdminKey, "id": "board0", "count": "4"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || len(resp.Channels) != 4 {
+		t.Fatal("channels count is not set:", resp)
+	}
+
+	// User gets and sets only allowed channels
+	resp = api.MultiRelayResponse{}
+	ctx = request(mrh.Status, map[string]string{"user": testUserKey, "id": "board0"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || len(resp.Channels) != 1 || resp.Channels[0].Channel != 1 {
+		t.Error("wrong readable channels:", resp)
+	}
+
+	for channel, want := range map[string]bool{"0": false, "1": true} {
+		resp = api.MultiRelayResponse{}
+		ctx = request(mrh.SetStatus, map[string]string{"user": testUserKey, "id": "board0", "channel": channel,
+			"status": "true"})
+		jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+		if resp.Result != want {
+			t.Error("wrong channel", channel, "set result:", resp)
+		}
+	}
+
+	// Channel description is published for push clients
+	var sub = env.events.Subscribe(0, core.EventStatusRequested)
+	ctx = request(mrh.SetDescription, map[string]string{"user": testUserKey, "id": "board0", "channel": "1",
+		"desc": "Gate"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || len(sub.Events()) != 1 {
+		t.Error("channel description is not published:", resp)
+	}
+
+	// Removed channels are forgotten by interlocks and profiles
+	env.locks.Add("gates", core.InterlockRefuse)
+	env.locks.AddDevice("gates", "board0", 0)
+	env.locks.AddDevice("gates", "board0", 3)
+	ctx = request(mrh.SetChannels, map[string]string{"user": testAdminKey, "id": "board0", "count": "1"})
+	jsoniter.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || len(resp.Channels) != 1 {
+		t.Fatal("channels count is not reduced:", resp)
+	}
+
+	var profiles db.ProfileDB
+	err = env.cfg.LoadFromFile(&profiles, filepath.Join(env.dir, "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range profiles.Profiles {
+		for _, d := range p.Devices {
+			if d.Name == "board0" && len(d.Channels) != 0 {
+				t.Error("removed channel rights are saved:", d.Channels)
+			}
+		}
+	}
+
+	var locks db.InterlockDB
+	err = env.cfg.LoadFromFile(&locks, filepath.Join(env.dir, "interlock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks.Interlocks) != 1 || len(locks.Interlocks[0].Channels) != 1 || locks.Interlocks[0].Channels[0].Channel != 0 {
+		t.Error("wrong saved interlock channels:", locks.Interlocks)
+	}
+}
@@ -0,0 +1,182 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type PairingHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	db      *db.Database
+	log     *utils.Log
+}
+
+func NewPairingHandler(s *core.Storage, a *auth.Authorization, d *db.Database,
+	l *utils.Log) *PairingHandler {
+	return &PairingHandler{
+		storage: s,
+		aut:     a,
+		db:      d,
+		log:     l,
+	}
+}
+
+// CreateCode Make one-time pairing code for device
+func (p *PairingHandler) CreateCode(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = p.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		p.response(ctx, "Create pairing code", false, "Authorization failed", api.PairingResponse{})
+		return
+	}
+
+	// Find device in storage
+	var device = p.storage.Device(ctx.UserValue("id").(string))
+	if device == nil {
+		p.response(ctx, "Create pairing code", false, "Device not found", api.PairingResponse{})
+		return
+	}
+
+	// Process operation
+	var code, err = p.aut.CreatePairingCode(device.Name())
+	if err != nil {
+		p.response(ctx, "Create pairing code", false, err.Error(), api.PairingResponse{})
+		return
+	}
+
+	// Send response
+	p.response(ctx, "Create pairing code", true, "", api.PairingResponse{
+		Device:  code.Device,
+		Code:    code.Code,
+		Expires: unixTime(code.Expires),
+	})
+}
+
+// Pair Exchange pairing code for device token
+func (p *PairingHandler) Pair(ctx *fasthttp.RequestCtx) {
+	// Process operation
+	var device, token, err = p.aut.Pair(ctx.UserValue("code").(string))
+	if err != nil {
+		p.response(ctx, "Pair device", false, err.Error(), api.PairingResponse{})
+		return
+	}
+
+	// Save device tokens
+	err = p.db.SaveTokenBase()
+	if err != nil {
+		p.response(ctx, "Save device token", false, err.Error(), api.PairingResponse{})
+		return
+	}
+
+	// Send response
+	p.response(ctx, "Pair device", true, "", api.PairingResponse{
+		Device: device,
+		Token:  token,
+	})
+}
+
+// Revoke Delete device token and pairing codes
+func (p *PairingHandler) Revoke(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = p.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		p.response(ctx, "Revoke device token", false, "Authorization failed", api.PairingResponse{})
+		return
+	}
+
+	// Process operation
+	var name = ctx.UserValue("id").(string)
+	if !p.aut.RevokeDevice(name) {
+		p.response(ctx, "Revoke device token", false, "Token not found", api.PairingResponse{})
+		return
+	}
+
+	// Save device tokens
+	var err = p.db.SaveTokenBase()
+	if err != nil {
+		p.response(ctx, "Save device token", false, err.Error(), api.PairingResponse{})
+		return
+	}
+
+	// Send response
+	p.response(ctx, "Revoke device token", true, "", api.PairingResponse{Device: name})
+}
+
+func (p *PairingHandler) PairingList(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = p.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		p.responseList(ctx, "Pairing list", false, "Authorization failed")
+		return
+	}
+
+	// Send response
+	p.responseList(ctx, "Pairing list", true, "")
+}
+
+func (p *PairingHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, resp api.PairingResponse) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	resp.Operation = oper
+	resp.Result = result
+	resp.Error = err
+
+	if result {
+		p.log.Info("PAIRINGH", oper)
+	} else {
+		p.log.Error("PAIRINGH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (p *PairingHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.PairingListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if result {
+		for _, code := range p.aut.PairingCodes() {
+			resp.Codes = append(resp.Codes, api.PairingCodeResponse{
+				Device:  code.Device,
+				Code:    code.Code,
+				Expires: unixTime(code.Expires),
+			})
+		}
+		for _, token := range p.aut.DeviceTokens() {
+			resp.Tokens = append(resp.Tokens, api.DeviceTokenResponse{
+				Device:  token.Device,
+				Created: unixTime(token.Created),
+			})
+		}
+		p.log.Info("PAIRINGH", oper)
+	} else {
+		p.log.Error("PAIRINGH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
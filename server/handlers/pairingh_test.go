@@ -0,0 +1,86 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDevicePairing(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var resp api.PairingResponse
+
+	var ctx = request(env.pairh.CreateCode, map[string]string{"user": testUserKey, "id": "relay0"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Fatal("pairing code created without admin rights")
+	}
+
+	ctx = request(env.pairh.CreateCode, map[string]string{"user": testAdminKey, "id": "relay0"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	var code = resp.Code
+	if len(code) != 2*auth.PairingCodeSize {
+		t.Error("wrong pairing code size:", code)
+	}
+
+	ctx = request(env.pairh.Pair, map[string]string{"code": code})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || resp.Token == "" || resp.Device != "relay0" {
+		t.Fatalf("device is not paired: %s", ctx.Response.Body())
+	}
+	var token = resp.Token
+
+	ctx = request(env.pairh.Pair, map[string]string{"code": code})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("pairing code used twice")
+	}
+
+	var relayResult = func(handler fasthttp.RequestHandler, values map[string]string) bool {
+		var resp api.RelayResponse
+		var ctx = request(handler, values)
+		json.Unmarshal(ctx.Response.Body(), &resp)
+		return resp.Result
+	}
+
+	if !relayResult(env.relayh.Update, map[string]string{"user": token, "id": "relay0", "state": "true"}) {
+		t.Error("device token can't report own state")
+	}
+	if !relayResult(env.relayh.Status, map[string]string{"user": token, "id": "relay0"}) {
+		t.Error("device token can't read own status")
+	}
+	if relayResult(env.relayh.Switch, map[string]string{"user": token, "id": "relay0"}) {
+		t.Error("device token can switch relay")
+	}
+	if relayResult(env.relayh.Update, map[string]string{"user": token, "id": "relay1", "state": "true"}) {
+		t.Error("device token can report other device state")
+	}
+	if relayResult(env.relayh.Status, map[string]string{"user": token, "id": "relay1"}) {
+		t.Error("device token can read other device status")
+	}
+
+	// Token follows renamed device
+	request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay0", "newname": "boiler"})
+	if !relayResult(env.relayh.Update, map[string]string{"user": token, "id": "boiler", "state": "false"}) {
+		t.Error("device token is lost after rename")
+	}
+
+	request(env.pairh.Revoke, map[string]string{"user": testAdminKey, "id": "boiler"})
+	if relayResult(env.relayh.Update, map[string]string{"user": token, "id": "boiler", "state": "true"}) {
+		t.Error("revoked device token is valid")
+	}
+}
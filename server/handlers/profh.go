@@ -26,15 +26,19 @@ import (
 type ProfileHandler struct {
 	aut     *auth.Authorization
 	storage *core.Storage
+	groups  *core.Groups
+	events  *core.Events
 	db      *db.Database
 	log     *utils.Log
 }
 
-func NewProfileHandler(s *core.Storage, a *auth.Authorization, db *db.Database,
-	l *utils.Log) *ProfileHandler {
+func NewProfileHandler(s *core.Storage, a *auth.Authorization, g *core.Groups, e *core.Events,
+	db *db.Database, l *utils.Log) *ProfileHandler {
 	return &ProfileHandler{
 		aut:     a,
 		storage: s,
+		groups:  g,
+		events:  e,
 		db:      db,
 		log:     l,
 	}
@@ -62,6 +66,8 @@ func (d *ProfileHandler) RemoveProfile(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Remove profile")
+
 	// Send response
 	d.response(ctx, "Remove profile", true, "")
 }
@@ -85,6 +91,8 @@ func (d *ProfileHandler) AddProfile(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Add profile")
+
 	// Send response
 	d.response(ctx, "Add profile", true, "")
 }
@@ -115,6 +123,8 @@ func (d *ProfileHandler) AddProfileDevice(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Add profile device")
+
 	// Send response
 	d.response(ctx, "Add profile device", true, "")
 }
@@ -135,6 +145,10 @@ func (d *ProfileHandler) AddProfileGroup(ctx *fasthttp.RequestCtx) {
 	}
 
 	var grp, _ = url.QueryUnescape(ctx.UserValue("group").(string))
+	if _, ok := d.groups.Group(grp); !ok {
+		d.response(ctx, "Add profile group", false, "Group not found")
+		return
+	}
 	profile.AddGroup(grp)
 
 	// Save new profile list
@@ -144,6 +158,8 @@ func (d *ProfileHandler) AddProfileGroup(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Add profile group")
+
 	// Send response
 	d.response(ctx, "Add profile group", true, "")
 }
@@ -173,6 +189,8 @@ func (d *ProfileHandler) RemoveProfileGroup(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Remove profile group")
+
 	// Send response
 	d.response(ctx, "Remove profile group", true, "")
 }
@@ -201,10 +219,97 @@ func (d *ProfileHandler) RemoveProfileDevice(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	d.publish(ctx, "Remove profile device")
+
 	// Send response
 	d.response(ctx, "Remove profile device", true, "")
 }
 
+func (d *ProfileHandler) AddProfileChannel(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = d.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		d.response(ctx, "Add profile channel", false, "Authorization failed")
+		return
+	}
+
+	// Add profile device channel
+	var profile = d.aut.Profile(ctx.UserValue("name").(string))
+	if profile == nil {
+		d.response(ctx, "Add profile channel", false, "Profile not found")
+		return
+	}
+
+	var channel, err = strconv.Atoi(ctx.UserValue("channel").(string))
+	if err != nil {
+		d.response(ctx, "Add profile channel", false, "Fail to convert channel")
+		return
+	}
+
+	var device = profile.Device(ctx.UserValue("device").(string))
+	if device == nil {
+		device = auth.NewProfileDevice(ctx.UserValue("device").(string), false, false)
+		profile.AddDevice(device)
+	}
+
+	var read, _ = strconv.ParseBool(ctx.UserValue("read").(string))
+	var write, _ = strconv.ParseBool(ctx.UserValue("write").(string))
+	device.AddChannel(auth.NewProfileChannel(channel, read, write))
+
+	// Save new profile list
+	err = d.db.SaveProfileBase()
+	if err != nil {
+		d.response(ctx, "Add profile channel", false, err.Error())
+		return
+	}
+
+	d.publish(ctx, "Add profile channel")
+
+	// Send response
+	d.response(ctx, "Add profile channel", true, "")
+}
+
+func (d *ProfileHandler) RemoveProfileChannel(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = d.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		d.response(ctx, "Remove profile channel", false, "Authorization failed")
+		return
+	}
+
+	// Delete profile device channel
+	var profile = d.aut.Profile(ctx.UserValue("name").(string))
+	if profile == nil {
+		d.response(ctx, "Remove profile channel", false, "Profile not found")
+		return
+	}
+
+	var device = profile.Device(ctx.UserValue("device").(string))
+	if device == nil {
+		d.response(ctx, "Remove profile channel", false, "Profile device not found")
+		return
+	}
+
+	var channel, err = strconv.Atoi(ctx.UserValue("channel").(string))
+	if err != nil {
+		d.response(ctx, "Remove profile channel", false, "Fail to convert channel")
+		return
+	}
+	device.RemoveChannel(channel)
+
+	// Save new profile list
+	err = d.db.SaveProfileBase()
+	if err != nil {
+		d.response(ctx, "Remove profile channel", false, err.Error())
+		return
+	}
+
+	d.publish(ctx, "Remove profile channel")
+
+	// Send response
+	d.response(ctx, "Remove profile channel", true, "")
+}
+
 func (d *ProfileHandler) ProfileList(ctx *fasthttp.RequestCtx) {
 	// Check user rights
 	var admin = d.aut.IsAdmin(ctx.UserValue("user").(string))
@@ -255,14 +360,21 @@ func (d *ProfileHandler) responseList(ctx *fasthttp.RequestCtx, oper string, res
 				Key:   prof.APIKey(),
 				Admin: prof.Admin(),
 			}
-			p.Groups = make([]string, len(*prof.Groups()))
-			copy(p.Groups, *prof.Groups())
+			p.Groups = prof.Groups()
 			for _, dev := range prof.Devices() {
-				p.Devices = append(p.Devices, api.ProfileDeviceResponse{
+				var pdev = api.ProfileDeviceResponse{
 					Name:  dev.Name(),
 					Read:  dev.Read(),
 					Write: dev.Write(),
-				})
+				}
+				for _, ch := range dev.Channels() {
+					pdev.Channels = append(pdev.Channels, api.ProfileChannelResponse{
+						Channel: ch.Channel(),
+						Read:    ch.Read(),
+						Write:   ch.Write(),
+					})
+				}
+				p.Devices = append(p.Devices, pdev)
 			}
 			devResp.Profiles = append(devResp.Profiles, p)
 		}
@@ -278,3 +390,13 @@ func (d *ProfileHandler) responseList(ctx *fasthttp.RequestCtx, oper string, res
 
 	ctx.Write(bytes)
 }
+
+// publish Publish profile change event
+func (d *ProfileHandler) publish(ctx *fasthttp.RequestCtx, oper string) {
+	d.events.Publish(core.Event{
+		Type:    core.EventProfile,
+		Actor:   actor(d.aut, ctx.UserValue("user").(string)),
+		Profile: ctx.UserValue("name").(string),
+		Value:   oper,
+	})
+}
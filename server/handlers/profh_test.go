@@ -0,0 +1,31 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+)
+
+func TestDeleteProfile(t *testing.T) {
+	var env = newTestEnv(t)
+
+	var err = env.aut.DeleteProfile("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env.aut.Profile("user") != nil {
+		t.Error("profile was not deleted")
+	}
+	if read, _ := env.aut.Validation(testUserKey, "relay0"); read {
+		t.Error("deleted profile key is still valid")
+	}
+}
@@ -0,0 +1,244 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+// Push channel default settings
+const (
+	PushPingInterval = 30 * time.Second
+	PushWriteTimeout = 10 * time.Second
+	PushReadLimit    = 512
+)
+
+// pushEvents Events types which change devices snapshot
+var pushEvents = []string{
+	core.EventStateChanged,
+	core.EventStatusRequested,
+	core.EventOnline,
+	core.EventOffline,
+	core.EventSync,
+	core.EventDeviceAdded,
+	core.EventDeviceRemoved,
+	core.EventDeviceRenamed,
+	core.EventProfile,
+}
+
+type PushHandler struct {
+	aut      *auth.Authorization
+	storage  *core.Storage
+	events   *core.Events
+	log      *utils.Log
+	upgrader websocket.FastHTTPUpgrader
+}
+
+func NewPushHandler(a *auth.Authorization, s *core.Storage, e *core.Events, l *utils.Log) *PushHandler {
+	return &PushHandler{
+		aut:     a,
+		storage: s,
+		events:  e,
+		log:     l,
+		upgrader: websocket.FastHTTPUpgrader{
+			// Clients are authorized by key, not by cookies
+			CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
+				return true
+			},
+		},
+	}
+}
+
+// Connect Upgrade connection to web socket. Snapshot of devices readable
+// by profile is sent first, then every device change is pushed with new
+// device snapshot. Full snapshot is sent again when profile rights were
+// changed or events were dropped
+func (p *PushHandler) Connect(ctx *fasthttp.RequestCtx) {
+	var key = ctx.UserValue("user").(string)
+
+	// Check user rights
+	var prof = p.aut.ProfileByKey(key)
+	if prof == nil {
+		p.response(ctx, "Connect push", false, "Authorization failed")
+		return
+	}
+
+	// Process operation
+	var name = prof.Name()
+	var err = p.upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		p.log.Info("PUSHH", "Push connected \""+name+"\"")
+		p.serve(conn, key, name)
+		p.log.Info("PUSHH", "Push disconnected \""+name+"\"")
+	})
+	if err != nil {
+		p.log.Error("PUSHH", "Connect push", err.Error())
+	}
+}
+
+// serve Push devices changes until client closes connection or profile
+// is removed
+func (p *PushHandler) serve(conn *websocket.Conn, key string, profile string) {
+	var sub = p.events.Subscribe(core.EventsBufferSize, pushEvents...)
+	defer p.events.Unsubscribe(sub)
+
+	// Client messages are not expected, reading only detects closing
+	var closed = make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		conn.SetReadLimit(PushReadLimit)
+		for {
+			var _, _, err = conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Connection is released by server after return, so reader must be
+	// stopped first
+	defer func() {
+		conn.Close()
+		<-closed
+	}()
+
+	var ping = time.NewTicker(PushPingInterval)
+	defer ping.Stop()
+
+	var dropped int
+	var err = p.write(conn, p.snapshot(key))
+	for err == nil {
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			if p.aut.ProfileByKey(key) == nil {
+				return
+			}
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(PushWriteTimeout))
+		case event := <-sub.Events():
+			if p.aut.ProfileByKey(key) == nil {
+				return
+			}
+
+			if sub.Dropped() != dropped || event.Type == core.EventProfile && event.Profile == profile {
+				dropped = sub.Dropped()
+				err = p.write(conn, p.snapshot(key))
+				continue
+			}
+			if event.Type == core.EventProfile {
+				continue
+			}
+
+			var read, _ = p.aut.Validation(key, event.Device)
+			if read {
+				err = p.write(conn, p.update(event))
+			}
+		}
+	}
+
+	p.log.Error("PUSHH", "Push \""+profile+"\"", err.Error())
+}
+
+// snapshot Make snapshot of all devices readable by profile
+func (p *PushHandler) snapshot(key string) api.PushResponse {
+	var resp = api.PushResponse{
+		Operation: "Devices snapshot",
+		Result:    true,
+	}
+
+	for _, dev := range p.storage.Devices() {
+		var read, _ = p.aut.Validation(key, dev.Name())
+		if read {
+			resp.Devices = append(resp.Devices, pushDevice(dev))
+		}
+	}
+
+	return resp
+}
+
+// update Make single device update. Removed device is sent without
+// snapshot
+func (p *PushHandler) update(event core.Event) api.PushResponse {
+	var resp = api.PushResponse{
+		Operation: "Device update",
+		Result:    true,
+		Events: []api.EventSingleResponse{{
+			Time:    event.Time.Unix(),
+			Type:    event.Type,
+			Actor:   event.Actor,
+			Device:  event.Device,
+			Profile: event.Profile,
+			Value:   event.Value,
+		}},
+	}
+
+	var dev = p.storage.Device(event.Device)
+	if dev != nil {
+		resp.Devices = append(resp.Devices, pushDevice(dev))
+	}
+
+	return resp
+}
+
+func (p *PushHandler) write(conn *websocket.Conn, resp api.PushResponse) error {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var bytes, err = json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(PushWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, bytes)
+}
+
+func (p *PushHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.PushResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if result {
+		p.log.Info("PUSHH", oper)
+	} else {
+		p.log.Error("PUSHH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+
+	ctx.Write(bytes)
+}
+
+// pushDevice Make device snapshot with desired status and reported state
+func pushDevice(dev devices.IDevice) api.PushDeviceResponse {
+	return api.PushDeviceResponse{
+		Name:        dev.Name(),
+		Description: dev.Description(),
+		Type:        dev.Type(),
+		Online:      dev.Online(),
+		Status:      core.DesiredState(dev),
+		State:       core.DeviceState(dev),
+	}
+}
@@ -0,0 +1,121 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/router"
+	"github.com/fasthttp/websocket"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestWebSocketPush(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	// Push requires profile key
+	var resp api.PushResponse
+	var ctx = request(env.pushh.Connect, map[string]string{"user": "wrong-key"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result || resp.Error != "Authorization failed" {
+		t.Error("push connected with wrong key:", resp)
+	}
+
+	var r = router.New()
+	r.GET(api.HttpReqPush, env.pushh.Connect)
+
+	var ln = fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, r.Handler)
+
+	var dialer = websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	var conn, _, err = dialer.Dial("ws://controller/user/"+testUserKey+"/push", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Read next snapshot or update by event type, other updates are skipped
+	var read = func(typ string) api.PushResponse {
+		for {
+			var resp api.PushResponse
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var _, msg, err = conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			json.Unmarshal(msg, &resp)
+			if typ == "" && resp.Operation == "Devices snapshot" ||
+				len(resp.Events) > 0 && resp.Events[0].Type == typ {
+				return resp
+			}
+		}
+	}
+
+	// Snapshot contains only readable devices
+	resp = read("")
+	if len(resp.Devices) != 1 || resp.Devices[0].Name != "relay0" {
+		t.Fatal("wrong snapshot:", resp)
+	}
+
+	// Changes of other devices are filtered
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay1", "state": "true"})
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay0", "status": "true"})
+	resp = read(core.EventStatusRequested)
+	if resp.Operation != "Device update" || len(resp.Events) != 1 || len(resp.Devices) != 1 {
+		t.Fatal("wrong update:", resp)
+	}
+	if resp.Events[0].Device != "relay0" ||
+		resp.Devices[0].Status != "on" || resp.Devices[0].State != "off" {
+		t.Error("wrong status update:", resp)
+	}
+
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay0", "state": "true"})
+	resp = read(core.EventStateChanged)
+	if resp.Devices[0].Name != "relay0" || resp.Devices[0].State != "on" {
+		t.Error("wrong state update:", resp)
+	}
+
+	// Profile rights change sends new snapshot
+	request(env.profh.AddProfileDevice, map[string]string{"user": testAdminKey, "name": "user", "device": "dimmer0",
+		"read": "true", "write": "false"})
+	resp = read("")
+	if len(resp.Devices) != 2 {
+		t.Fatal("wrong snapshot after rights change:", resp)
+	}
+
+	request(env.dimh.Update, map[string]string{"user": testAdminKey, "id": "dimmer0", "level": "40"})
+	resp = read(core.EventStateChanged)
+	if resp.Devices[0].Name != "dimmer0" ||
+		resp.Devices[0].State != "40" {
+		t.Error("wrong dimmer update:", resp)
+	}
+
+	// Push is closed for removed profile
+	env.aut.DeleteProfile("user")
+	request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay0", "state": "false"})
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if err == nil {
+		t.Error("push is not closed for removed profile")
+	}
+}
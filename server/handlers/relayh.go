@@ -12,11 +12,11 @@ package handlers
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/futcity/controller/auth"
 	"github.com/futcity/controller/core"
 	"github.com/futcity/controller/core/devices/base"
-	"github.com/futcity/controller/db"
 	"github.com/futcity/controller/server/api"
 	"github.com/futcity/controller/utils"
 	jsoniter "github.com/json-iterator/go"
@@ -26,42 +26,59 @@ import (
 type RelayHandler struct {
 	storage *core.Storage
 	aut     *auth.Authorization
-	db      *db.Database
+	rec     *core.Reconciler
+	cmd     *core.Commander
+	timers  *core.Timers
+	events  *core.Events
 	log     *utils.Log
 }
 
-func NewRelayHandler(s *core.Storage, a *auth.Authorization, l *utils.Log, db *db.Database) *RelayHandler {
+func NewRelayHandler(s *core.Storage, a *auth.Authorization, l *utils.Log, rec *core.Reconciler,
+	cmd *core.Commander, t *core.Timers, e *core.Events) *RelayHandler {
 	return &RelayHandler{
 		storage: s,
 		aut:     a,
 		log:     l,
-		db:      db,
+		rec:     rec,
+		cmd:     cmd,
+		timers:  t,
+		events:  e,
+	}
+}
+
+// Routes Relay web API routes
+func (r *RelayHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqRelayStatus, r.Status},
+		{api.HttpReqRelaySet, r.SetStatus},
+		{api.HttpReqRelayUpdate, r.Update},
+		{api.HttpReqRelaySwitch, r.Switch},
+		{api.HttpReqRelayTimer, r.SetTimer},
+		{api.HttpReqRelayTimerCancel, r.CancelTimer},
+		{api.HttpReqRelayMode, r.SetMode},
+		{api.HttpReqRelayList, r.Devices},
 	}
 }
 
 func (r *RelayHandler) Switch(ctx *fasthttp.RequestCtx) {
 	// Find device in storage
-	var device = r.storage.Device(ctx.UserValue("id").(string))
-	if device == nil {
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
 		r.response(ctx, "Switch relay", false, "Relay not found", nil)
 		return
 	}
 
 	// Check user rights
-	var _, write = r.aut.Validation(ctx.UserValue("user").(string), device.Name())
+	var _, write = r.aut.Validation(ctx.UserValue("user").(string), relay.Name())
 	if !write {
 		r.response(ctx, "Switch relay", false, "Authorization failed", nil)
 		return
 	}
 
-	// Process operation
-	var relay = device.(*base.Relay)
-	relay.Switch()
-
-	// Save to database
-	var err = r.db.SaveRelayBase(relay.Name(), relay.Status())
+	// Process operation and save to database
+	var err = r.cmd.Switch(actor(r.aut, ctx.UserValue("user").(string)), relay)
 	if err != nil {
-		r.response(ctx, "Save to relay database", false, err.Error(), relay)
+		r.response(ctx, "Switch relay", false, err.Error(), relay)
 		return
 	}
 
@@ -71,32 +88,30 @@ func (r *RelayHandler) Switch(ctx *fasthttp.RequestCtx) {
 
 func (r *RelayHandler) SetStatus(ctx *fasthttp.RequestCtx) {
 	// Find device in storage
-	var device = r.storage.Device(ctx.UserValue("id").(string))
-	if device == nil {
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
 		r.response(ctx, "Set relay status", false, "Relay not found", nil)
 		return
 	}
 
 	// Check user rights
-	var _, write = r.aut.Validation(ctx.UserValue("user").(string), device.Name())
+	var _, write = r.aut.Validation(ctx.UserValue("user").(string), relay.Name())
 	if !write {
 		r.response(ctx, "Set relay status", false, "Authorization failed", nil)
 		return
 	}
 
 	// Process operation
-	var relay = device.(*base.Relay)
 	var status, err = strconv.ParseBool(ctx.UserValue("status").(string))
 	if err != nil {
 		r.response(ctx, "Set relay status", false, "Fail to convert status", relay)
 		return
 	}
-	relay.SetStatus(status)
 
-	// Save to database
-	err = r.db.SaveRelayBase(relay.Name(), relay.Status())
+	// Apply changes and save to database
+	err = r.cmd.SetStatus(actor(r.aut, ctx.UserValue("user").(string)), relay, status)
 	if err != nil {
-		r.response(ctx, "Save to relay database", false, err.Error(), relay)
+		r.response(ctx, "Set relay status", false, err.Error(), relay)
 		return
 	}
 
@@ -104,24 +119,135 @@ func (r *RelayHandler) SetStatus(ctx *fasthttp.RequestCtx) {
 	r.response(ctx, "Set relay status", true, "", relay)
 }
 
+// SetTimer Switch relay on and switch it off after duration in minutes
+func (r *RelayHandler) SetTimer(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
+		r.response(ctx, "Set relay timer", false, "Relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = r.aut.Validation(ctx.UserValue("user").(string), relay.Name())
+	if !write {
+		r.response(ctx, "Set relay timer", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var minutes, err = strconv.Atoi(ctx.UserValue("minutes").(string))
+	if err != nil {
+		r.response(ctx, "Set relay timer", false, "Fail to convert minutes", relay)
+		return
+	}
+
+	// Apply changes and save to database
+	err = r.timers.Set(actor(r.aut, ctx.UserValue("user").(string)), relay, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		r.response(ctx, "Set relay timer", false, err.Error(), relay)
+		return
+	}
+
+	// Send response
+	r.response(ctx, "Set relay timer", true, "", relay)
+}
+
+// CancelTimer Cancel relay timer, relay status is kept
+func (r *RelayHandler) CancelTimer(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
+		r.response(ctx, "Cancel relay timer", false, "Relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var _, write = r.aut.Validation(ctx.UserValue("user").(string), relay.Name())
+	if !write {
+		r.response(ctx, "Cancel relay timer", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var found, err = r.timers.Cancel(relay.Name())
+	if err != nil {
+		r.response(ctx, "Cancel relay timer", false, err.Error(), relay)
+		return
+	}
+	if !found {
+		r.response(ctx, "Cancel relay timer", false, "Timer not found", relay)
+		return
+	}
+
+	// Send response
+	r.response(ctx, "Cancel relay timer", true, "", relay)
+}
+
+// SetMode Set relay mode by admin, pulse width is set in milliseconds by
+// "width" query argument
+func (r *RelayHandler) SetMode(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
+		r.response(ctx, "Set relay mode", false, "Relay not found", nil)
+		return
+	}
+
+	// Check user rights
+	var admin = r.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		r.response(ctx, "Set relay mode", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var mode = ctx.UserValue("mode").(string)
+	if !base.IsRelayMode(mode) {
+		r.response(ctx, "Set relay mode", false, "Unknown relay mode", relay)
+		return
+	}
+
+	var width = relay.PulseWidth()
+	if ctx.QueryArgs().Has("width") {
+		var ms, err = strconv.Atoi(string(ctx.QueryArgs().Peek("width")))
+		if err != nil {
+			r.response(ctx, "Set relay mode", false, "Fail to convert width", relay)
+			return
+		}
+		width = time.Duration(ms) * time.Millisecond
+	}
+	if width <= 0 || width > base.RelayPulseMax {
+		r.response(ctx, "Set relay mode", false, "Wrong pulse width", relay)
+		return
+	}
+
+	// Apply changes and save to database
+	var err = r.cmd.SetMode(actor(r.aut, ctx.UserValue("user").(string)), relay, mode, width)
+	if err != nil {
+		r.response(ctx, "Save to relay database", false, err.Error(), relay)
+		return
+	}
+
+	// Send response
+	r.response(ctx, "Set relay mode", true, "", relay)
+}
+
 func (r *RelayHandler) Status(ctx *fasthttp.RequestCtx) {
 	// Find device in storage
-	var device = r.storage.Device(ctx.UserValue("id").(string))
-	if device == nil {
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
 		r.response(ctx, "Get relay status", true, "Relay not found", nil)
 		return
 	}
 
 	// Check user rights
-	var read, _ = r.aut.Validation(ctx.UserValue("user").(string), device.Name())
+	var read, _ = r.aut.Validation(ctx.UserValue("user").(string), relay.Name())
 	if !read {
 		r.response(ctx, "Get relay status", false, "Authorization failed", nil)
 		return
 	}
 
-	// Process operation
-	var relay = device.(*base.Relay)
-
 	// Send response
 	r.response(ctx, "Get relay status", true, "", relay)
 }
@@ -129,7 +255,7 @@ func (r *RelayHandler) Status(ctx *fasthttp.RequestCtx) {
 func (r *RelayHandler) Devices(ctx *fasthttp.RequestCtx) {
 	// Find all devices
 	var relays []*base.Relay
-	for _, device := range r.storage.DevicesByType("relay") {
+	for _, device := range r.storage.FindDevices(deviceFilter(ctx, "relay")) {
 		var read, _ = r.aut.Validation(ctx.UserValue("user").(string), device.Name())
 		if read {
 			relays = append(relays, device.(*base.Relay))
@@ -142,27 +268,36 @@ func (r *RelayHandler) Devices(ctx *fasthttp.RequestCtx) {
 
 func (r *RelayHandler) Update(ctx *fasthttp.RequestCtx) {
 	// Find device in storage
-	var device = r.storage.Device(ctx.UserValue("id").(string))
-	if device == nil {
+	var relay, ok = r.storage.Device(ctx.UserValue("id").(string)).(*base.Relay)
+	if !ok {
 		r.response(ctx, "Update relay", false, "Relay not found", nil)
 		return
 	}
 
 	// Check user rights
-	var _, write = r.aut.Validation(ctx.UserValue("user").(string), device.Name())
-	if !write {
+	var report = r.aut.ReportValidation(ctx.UserValue("user").(string), relay.Name())
+	if !report {
 		r.response(ctx, "Update relay", false, "Authorization failed", nil)
 		return
 	}
 
-	// Process operation
-	var relay = device.(*base.Relay)
-	var state, err = strconv.ParseBool(ctx.UserValue("state").(string))
+	// Process operation. Device reports output level
+	var level, err = strconv.ParseBool(ctx.UserValue("state").(string))
 	if err != nil {
 		r.response(ctx, "Update relay", false, "Fail to convert state", relay)
 		return
 	}
-	relay.Update(state)
+	var old = relay.State()
+	relay.Update(level)
+	r.rec.CheckRelay(relay)
+	if relay.State() != old {
+		r.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(r.aut, ctx.UserValue("user").(string)),
+			Device: relay.Name(),
+			Value:  core.DeviceState(relay),
+		})
+	}
 
 	// Send response
 	r.response(ctx, "Update relay", true, "", relay)
@@ -181,6 +316,13 @@ func (r *RelayHandler) response(ctx *fasthttp.RequestCtx, oper string, result bo
 	if relay != nil {
 		resp.Status = relay.Status()
 		resp.State = relay.State()
+		resp.Sync = r.rec.Sync(relay)
+		resp.Changed = unixTime(relay.Changed())
+		resp.Remaining = r.remaining(relay)
+		resp.Mode = relay.Mode()
+		resp.Width = int64(relay.PulseWidth() / time.Millisecond)
+		resp.Pulse = relay.Pulse()
+		resp.Output = relay.Output()
 	}
 
 	if result && oper != "Update relay" {
@@ -208,8 +350,16 @@ func (r *RelayHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resul
 			Name:        relay.Name(),
 			Description: relay.Description(),
 			Online:      relay.Online(),
+			LastSeen:    unixTime(relay.LastSeen()),
 			Status:      relay.Status(),
 			State:       relay.State(),
+			Sync:        r.rec.Sync(relay),
+			Changed:     unixTime(relay.Changed()),
+			Remaining:   r.remaining(relay),
+			Mode:        relay.Mode(),
+			Width:       int64(relay.PulseWidth() / time.Millisecond),
+			Pulse:       relay.Pulse(),
+			Output:      relay.Output(),
 		})
 	}
 
@@ -223,3 +373,9 @@ func (r *RelayHandler) responseList(ctx *fasthttp.RequestCtx, oper string, resul
 
 	ctx.Write(bytes)
 }
+
+// remaining Get relay timer remaining seconds rounded up
+func (r *RelayHandler) remaining(relay *base.Relay) int64 {
+	var left = r.timers.Remaining(relay.Name(), time.Now())
+	return int64((left + time.Second - 1) / time.Second)
+}
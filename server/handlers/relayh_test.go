@@ -0,0 +1,232 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRelayTimers(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var relay = env.storage.Device("relay1").(*base.Relay)
+
+	// Switch sauna on for 20 minutes
+	var ctx = request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "20"})
+	var resp api.RelayResponse
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || !resp.Status || resp.Remaining <= 1190 || resp.Remaining > 1200 {
+		t.Fatal("wrong relay timer response:", resp)
+	}
+
+	ctx = request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "0"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("zero timer is set")
+	}
+
+	var timers db.TimerDB
+	var err = env.cfg.LoadFromFile(&timers, filepath.Join(env.dir, "timer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timers.Timers) != 1 || timers.Timers[0].Device != "relay1" || timers.Timers[0].Duration != 1200 {
+		t.Fatal("wrong saved timers:", timers)
+	}
+
+	env.timers.Check(time.Now().Add(10 * time.Minute))
+	if !relay.Status() {
+		t.Error("relay was switched off before timer end")
+	}
+	env.timers.Check(time.Now().Add(21 * time.Minute))
+	if relay.Status() {
+		t.Error("relay was not switched off by timer")
+	}
+	if _, ok := env.timers.Timer("relay1"); ok {
+		t.Error("expired timer is kept")
+	}
+
+	// Canceled timer keeps relay on
+	request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "20"})
+	ctx = request(env.relayh.CancelTimer, map[string]string{"user": testAdminKey, "id": "relay1"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result || !resp.Status || resp.Remaining != 0 {
+		t.Error("wrong canceled timer response:", resp)
+	}
+
+	// Timer survives restart and switches relay off after it
+	timers.Timers[0].Until = time.Now().Add(-time.Minute).Unix()
+	err = env.cfg.SaveToFile(&timers, filepath.Join(env.dir, "timer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var timersNew = core.NewTimers(env.storage, env.cmd, env.log)
+	var database = db.NewDatabase(env.cfg, env.aut, env.storage, env.fw, env.groups, env.scenes, env.locks, env.sched, env.rules, timersNew, env.log)
+	database.SetDBType("text")
+	database.AddFilename("timer", filepath.Join(env.dir, "timer.json"))
+	database.AddFilename("relay", filepath.Join(env.dir, "relay.json"))
+
+	err = database.LoadTimerBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timersNew.Check(time.Now())
+	if relay.Status() {
+		t.Error("relay was not switched off by restored timer")
+	}
+}
+
+func TestRelayTimerCommands(t *testing.T) {
+	var env = newTestEnv(t)
+	var relay = env.storage.Device("relay1").(*base.Relay)
+
+	// Relay switched off by automation has no timer
+	request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "20"})
+	var err = env.cmd.Execute(core.ActorRules, "relay1", core.ActionOff, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := env.timers.Timer("relay1"); ok {
+		t.Error("timer of switched off relay is kept")
+	}
+
+	// Switching on keeps timer
+	request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "20"})
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay1", "status": "true"})
+	if _, ok := env.timers.Timer("relay1"); !ok {
+		t.Error("timer of switched on relay is canceled")
+	}
+
+	// Failed switching off keeps timer for next check
+	var relayFile = filepath.Join(env.dir, "relay.json")
+	env.db.AddFilename("relay", filepath.Join(env.dir, "missing", "relay.json"))
+	env.timers.Check(time.Now().Add(time.Hour))
+	if _, ok := env.timers.Timer("relay1"); !ok {
+		t.Fatal("failed timer is removed")
+	}
+
+	env.db.AddFilename("relay", relayFile)
+	env.timers.Check(time.Now().Add(time.Hour))
+	if _, ok := env.timers.Timer("relay1"); ok || relay.Status() {
+		t.Error("relay is not switched off on retry")
+	}
+
+	var relays db.RelaysDB
+	err = env.cfg.LoadFromFile(&relays, relayFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, saved := range relays.Relays {
+		if saved.Name == "relay1" && saved.Status {
+			t.Error("switched off relay status is not saved")
+		}
+	}
+}
+
+func TestRelayModes(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var resp api.RelayResponse
+
+	var ctx = request(env.relayh.SetMode, map[string]string{"user": testAdminKey, "id": "relay1", "mode": "toggle"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("unknown relay mode is set")
+	}
+
+	// Relay owner can't change wiring mode
+	ctx = request(env.relayh.SetMode, map[string]string{"user": testUserKey, "id": "relay0", "mode": base.RelayInverted})
+	if env.storage.Device("relay0").(*base.Relay).Mode() != base.RelayLatching {
+		t.Errorf("relay mode is set without admin rights: %s", ctx.Response.Body())
+	}
+
+	// Gate opener makes pulse on every command
+	var sub = env.events.Subscribe(0, core.EventStatusRequested)
+	var modeCtx fasthttp.RequestCtx
+	modeCtx.Request.SetRequestURI("/?width=300")
+	modeCtx.SetUserValue("user", testAdminKey)
+	modeCtx.SetUserValue("id", "relay1")
+	modeCtx.SetUserValue("mode", base.RelayPulse)
+	env.relayh.SetMode(&modeCtx)
+	json.Unmarshal(modeCtx.Response.Body(), &resp)
+	if !resp.Result || resp.Mode != base.RelayPulse || resp.Width != 300 {
+		t.Fatal("wrong pulse relay response:", resp)
+	}
+	if len(sub.Events()) != 1 {
+		t.Fatal("relay mode change is not published")
+	}
+	if event := <-sub.Events(); event.Device != "relay1" || event.Value != "pulse 0" {
+		t.Error("wrong relay mode event:", event)
+	}
+	env.events.Unsubscribe(sub)
+
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay1", "status": "true"})
+	request(env.relayh.Switch, map[string]string{"user": testAdminKey, "id": "relay1"})
+	ctx = request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay1", "status": "false"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Status || resp.Output || resp.Pulse != 2 || resp.Sync != base.RelaySynced {
+		t.Error("wrong pulse relay status:", resp)
+	}
+
+	ctx = request(env.relayh.SetTimer, map[string]string{"user": testAdminKey, "id": "relay1", "minutes": "5"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("pulse relay timer is set")
+	}
+
+	// Inverted relay output and reported level are active low
+	request(env.relayh.SetMode, map[string]string{"user": testAdminKey, "id": "relay2", "mode": base.RelayInverted})
+	request(env.relayh.SetStatus, map[string]string{"user": testAdminKey, "id": "relay2", "status": "true"})
+	ctx = request(env.relayh.Update, map[string]string{"user": testAdminKey, "id": "relay2", "state": "false"})
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Status || resp.Output || !resp.State || resp.Sync != base.RelaySynced {
+		t.Error("wrong inverted relay status:", resp)
+	}
+
+	// Modes are saved and loaded
+	var relays db.RelaysDB
+	var err = env.cfg.LoadFromFile(&relays, filepath.Join(env.dir, "relay.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var storage = core.NewStorage(env.log)
+	var database = db.NewDatabase(env.cfg, env.aut, storage, env.fw, env.groups, env.scenes, env.locks, env.sched, env.rules, env.timers, env.log)
+	registerTypes(t, storage, database)
+	for _, name := range []string{"relay1", "relay2"} {
+		storage.AddDevice(name, "Relay", "relay")
+	}
+	database.SetDBType("text")
+	database.AddFilename("relay", filepath.Join(env.dir, "relay.json"))
+	err = database.LoadRelayBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pulse = storage.Device("relay1").(*base.Relay)
+	if pulse.Mode() != base.RelayPulse || pulse.PulseWidth() != 300*time.Millisecond || pulse.Pulse() != 2 {
+		t.Error("wrong loaded pulse relay:", relays)
+	}
+	var inverted = storage.Device("relay2").(*base.Relay)
+	if inverted.Mode() != base.RelayInverted || !inverted.Status() || inverted.Output() {
+		t.Error("wrong loaded inverted relay:", relays)
+	}
+}
@@ -0,0 +1,289 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type RuleHandler struct {
+	aut     *auth.Authorization
+	storage *core.Storage
+	rules   *core.Rules
+	db      *db.Database
+	log     *utils.Log
+}
+
+func NewRuleHandler(a *auth.Authorization, s *core.Storage, r *core.Rules, d *db.Database,
+	l *utils.Log) *RuleHandler {
+	return &RuleHandler{
+		aut:     a,
+		storage: s,
+		rules:   r,
+		db:      d,
+		log:     l,
+	}
+}
+
+func (r *RuleHandler) Rules(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = r.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		r.responseList(ctx, "Rules list", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	r.responseList(ctx, "Rules list", true, "", r.rules.Rules())
+}
+
+// AddRule Add disabled rule. Query arguments: trigger "device" with
+// optional "state" for state rule and "cron" for time rule
+func (r *RuleHandler) AddRule(ctx *fasthttp.RequestCtx) {
+	var args = ctx.QueryArgs()
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	var rule = core.Rule{
+		Name:    name,
+		Trigger: ctx.UserValue("trigger").(string),
+		Device:  string(args.Peek("device")),
+		State:   string(args.Peek("state")),
+		Cron:    string(args.Peek("cron")),
+	}
+
+	r.editRules(ctx, "Add rule", func() error {
+		if rule.Trigger == core.RuleTriggerState && r.storage.Device(rule.Device) == nil {
+			return errors.New("Device not found")
+		}
+
+		var _, err = r.rules.Add(rule)
+		return err
+	})
+}
+
+func (r *RuleHandler) RemoveRule(ctx *fasthttp.RequestCtx) {
+	r.editRule(ctx, "Remove rule", func(id int) error {
+		return r.rules.Remove(id)
+	})
+}
+
+func (r *RuleHandler) EnableRule(ctx *fasthttp.RequestCtx) {
+	r.editRule(ctx, "Enable rule", func(id int) error {
+		return r.rules.SetEnabled(id, true)
+	})
+}
+
+func (r *RuleHandler) DisableRule(ctx *fasthttp.RequestCtx) {
+	r.editRule(ctx, "Disable rule", func(id int) error {
+		return r.rules.SetEnabled(id, false)
+	})
+}
+
+// AddCondition Add rule condition. Query arguments: "device", "op" and
+// "value" for device state condition or "after" and "before" time
+func (r *RuleHandler) AddCondition(ctx *fasthttp.RequestCtx) {
+	var args = ctx.QueryArgs()
+
+	var cond = core.RuleCondition{
+		Device: string(args.Peek("device")),
+		Op:     string(args.Peek("op")),
+		Value:  string(args.Peek("value")),
+		After:  string(args.Peek("after")),
+		Before: string(args.Peek("before")),
+	}
+
+	r.editRule(ctx, "Add rule condition", func(id int) error {
+		return r.rules.AddCondition(id, cond)
+	})
+}
+
+func (r *RuleHandler) RemoveCondition(ctx *fasthttp.RequestCtx) {
+	r.editRule(ctx, "Remove rule condition", func(id int) error {
+		var index, err = strconv.Atoi(ctx.UserValue("index").(string))
+		if err != nil {
+			return errors.New("Fail to convert index")
+		}
+		return r.rules.RemoveCondition(id, index)
+	})
+}
+
+// AddAction Add rule action, dimmer level is set by "level" query argument
+func (r *RuleHandler) AddAction(ctx *fasthttp.RequestCtx) {
+	var target, _ = url.QueryUnescape(ctx.UserValue("target").(string))
+
+	var action = core.RuleAction{
+		Target: target,
+		Action: ctx.UserValue("action").(string),
+	}
+
+	r.editRule(ctx, "Add rule action", func(id int) error {
+		if action.Action == core.ActionLevel {
+			var level, err = strconv.Atoi(string(ctx.QueryArgs().Peek("level")))
+			if err != nil {
+				return errors.New("Fail to convert level")
+			}
+			action.Level = level
+		}
+		return r.rules.AddAction(id, action)
+	})
+}
+
+func (r *RuleHandler) RemoveAction(ctx *fasthttp.RequestCtx) {
+	r.editRule(ctx, "Remove rule action", func(id int) error {
+		var index, err = strconv.Atoi(ctx.UserValue("index").(string))
+		if err != nil {
+			return errors.New("Fail to convert index")
+		}
+		return r.rules.RemoveAction(id, index)
+	})
+}
+
+func (r *RuleHandler) Log(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = r.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		r.responseLog(ctx, "Rules log", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	r.responseLog(ctx, "Rules log", true, "", r.rules.Log())
+}
+
+// editRule Change rule found by request ID
+func (r *RuleHandler) editRule(ctx *fasthttp.RequestCtx, oper string, edit func(id int) error) {
+	r.editRules(ctx, oper, func() error {
+		var id, err = strconv.Atoi(ctx.UserValue("id").(string))
+		if err != nil {
+			return errors.New("Fail to convert ID")
+		}
+		return edit(id)
+	})
+}
+
+// editRules Change rules by admin and save rules base
+func (r *RuleHandler) editRules(ctx *fasthttp.RequestCtx, oper string, edit func() error) {
+	// Check user rights
+	var admin = r.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		r.responseList(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var err = edit()
+	if err != nil {
+		r.responseList(ctx, oper, false, err.Error(), nil)
+		return
+	}
+
+	// Save rules
+	err = r.db.SaveRuleBase()
+	if err != nil {
+		r.responseList(ctx, "Save rule", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	r.responseList(ctx, oper, true, "", nil)
+}
+
+func (r *RuleHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, rules []core.Rule) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var rulesResp = api.RuleListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, rule := range rules {
+		var resp = api.RuleSingleResponse{
+			ID:      rule.ID,
+			Name:    rule.Name,
+			Enabled: rule.Enabled,
+			Trigger: rule.Trigger,
+			Device:  rule.Device,
+			State:   rule.State,
+			Cron:    rule.Cron,
+		}
+		for _, cond := range rule.Conditions {
+			resp.Conditions = append(resp.Conditions, api.RuleConditionResponse{
+				Device: cond.Device,
+				Op:     cond.Op,
+				Value:  cond.Value,
+				After:  cond.After,
+				Before: cond.Before,
+			})
+		}
+		for _, action := range rule.Actions {
+			resp.Actions = append(resp.Actions, api.RuleActionResponse{
+				Target: action.Target,
+				Action: action.Action,
+				Level:  action.Level,
+			})
+		}
+		rulesResp.Rules = append(rulesResp.Rules, resp)
+	}
+
+	if result {
+		r.log.Info("RULEH", oper)
+	} else {
+		r.log.Error("RULEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(rulesResp)
+
+	ctx.Write(bytes)
+}
+
+func (r *RuleHandler) responseLog(ctx *fasthttp.RequestCtx, oper string, result bool, err string, journal []core.RuleLog) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var logResp = api.RuleLogResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, entry := range journal {
+		logResp.Log = append(logResp.Log, api.RuleLogSingleResponse{
+			Time:     entry.Time.Unix(),
+			Rule:     entry.Rule,
+			Name:     entry.Name,
+			Trigger:  entry.Trigger,
+			Executed: entry.Executed,
+			Error:    entry.Error,
+		})
+	}
+
+	if result {
+		r.log.Info("RULEH", oper)
+	} else {
+		r.log.Error("RULEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(logResp)
+
+	ctx.Write(bytes)
+}
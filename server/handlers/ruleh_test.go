@@ -0,0 +1,160 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRules(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var resp api.RuleListResponse
+
+	var err = env.storage.AddDevice("leak0", "Leak", "leak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.storage.Device("relay1").(*base.Relay).SetStatus(true)
+
+	// Close valve on leak at daytime while pump is off
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/?device=leak0&state=detected")
+	ctx.SetUserValue("user", testAdminKey)
+	ctx.SetUserValue("name", "Leak")
+	ctx.SetUserValue("trigger", core.RuleTriggerState)
+	env.ruleh.AddRule(&ctx)
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result {
+		t.Fatal("fail to add rule:", resp.Error)
+	}
+
+	var values = map[string]string{"user": testAdminKey, "id": "1"}
+	request(env.ruleh.EnableRule, values)
+	values["action"] = core.ActionOff
+	values["target"] = "relay1"
+	request(env.ruleh.AddAction, values)
+
+	ctx = fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/?after=08:00&before=20:00")
+	ctx.SetUserValue("user", testAdminKey)
+	ctx.SetUserValue("id", "1")
+	env.ruleh.AddCondition(&ctx)
+
+	ctx = fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/?device=relay2&op=" + core.RuleOpEqual + "&value=off")
+	ctx.SetUserValue("user", testAdminKey)
+	ctx.SetUserValue("id", "1")
+	env.ruleh.AddCondition(&ctx)
+
+	var rule, _ = env.rules.Rule(1)
+	if !rule.Enabled || len(rule.Conditions) != 2 || len(rule.Actions) != 1 {
+		t.Fatal("wrong rule:", rule)
+	}
+
+	var day = time.Date(2021, 6, 1, 12, 0, 0, 0, env.sun.Location())
+	var night = time.Date(2021, 6, 1, 23, 0, 0, 0, env.sun.Location())
+
+	// Other states and night time do not run actions
+	env.rules.HandleEvent(core.Event{Time: day, Type: core.EventStateChanged, Device: "leak0", Value: "clear"})
+	env.rules.HandleEvent(core.Event{Time: night, Type: core.EventStateChanged, Device: "leak0", Value: "detected"})
+	if !env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Fatal("rule was run at night")
+	}
+
+	// Device state condition is checked
+	env.storage.Device("relay2").(*base.Relay).SetStatus(true)
+	env.storage.Device("relay2").(*base.Relay).SetState(true)
+	env.rules.HandleEvent(core.Event{Time: day, Type: core.EventStateChanged, Device: "leak0", Value: "detected"})
+	if !env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Fatal("rule was run while pump is on")
+	}
+
+	env.storage.Device("relay2").(*base.Relay).SetStatus(false)
+	env.storage.Device("relay2").(*base.Relay).SetState(false)
+	env.rules.HandleEvent(core.Event{Time: day, Type: core.EventStateChanged, Device: "leak0", Value: "detected"})
+	if env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Fatal("valve was not closed on leak")
+	}
+
+	var journal = env.rules.Log()
+	if len(journal) != 3 || journal[0].Executed || journal[1].Executed || !journal[2].Executed {
+		t.Error("wrong rules log:", journal)
+	}
+
+	// Check saved base
+	var rules db.RuleDB
+	err = env.cfg.LoadFromFile(&rules, filepath.Join(env.dir, "rule.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.LastID != 1 || len(rules.Rules) != 1 || len(rules.Rules[0].Conditions) != 2 ||
+		rules.Rules[0].Actions[0].Target != "relay1" {
+		t.Error("wrong saved rules:", rules)
+	}
+
+	// Removed device is forgotten
+	request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey, "id": strconv.Itoa(env.storage.Device("relay2").ID())})
+	rule, _ = env.rules.Rule(1)
+	if len(rule.Conditions) != 1 {
+		t.Error("removed device condition is kept:", rule.Conditions)
+	}
+}
+
+func TestRulesResync(t *testing.T) {
+	var env = newTestEnv(t)
+
+	var err = env.storage.AddDevice("leak0", "Leak", "leak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relay = env.storage.Device("relay1").(*base.Relay)
+	relay.SetStatus(true)
+
+	_, err = env.rules.Add(core.Rule{Name: "Leak", Enabled: true, Trigger: core.RuleTriggerState, Device: "leak0",
+		State: "detected", Actions: []core.RuleAction{{Target: "relay1", Action: core.ActionOff}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// State of device which was not seen before is only remembered
+	var leak = env.storage.Device("leak0").(*base.Binary)
+	leak.Update(true)
+	env.rules.Resync(time.Now())
+	if !relay.Status() {
+		t.Fatal("rule was run by first seen state")
+	}
+
+	// Missed state change runs rule once
+	leak.Update(false)
+	env.rules.HandleEvent(core.Event{Type: core.EventStateChanged, Device: "leak0", Value: "clear"})
+	leak.Update(true)
+	env.rules.Resync(time.Now())
+	if relay.Status() {
+		t.Fatal("rule was not run by missed state change")
+	}
+
+	relay.SetStatus(true)
+	env.rules.Resync(time.Now())
+	if !relay.Status() {
+		t.Error("resynced state change was run twice")
+	}
+}
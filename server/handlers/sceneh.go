@@ -0,0 +1,283 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type SceneHandler struct {
+	aut     *auth.Authorization
+	storage *core.Storage
+	scenes  *core.Scenes
+	cmd     *core.Commander
+	sched   *core.Scheduler
+	rules   *core.Rules
+	db      *db.Database
+	log     *utils.Log
+}
+
+func NewSceneHandler(a *auth.Authorization, s *core.Storage, sc *core.Scenes, c *core.Commander,
+	sch *core.Scheduler, r *core.Rules, d *db.Database, l *utils.Log) *SceneHandler {
+	return &SceneHandler{
+		aut:     a,
+		storage: s,
+		scenes:  sc,
+		cmd:     c,
+		sched:   sch,
+		rules:   r,
+		db:      d,
+		log:     l,
+	}
+}
+
+// Scenes Get scenes list. Admin gets all scenes, user gets scenes
+// with readable devices only
+func (s *SceneHandler) Scenes(ctx *fasthttp.RequestCtx) {
+	var key = ctx.UserValue("user").(string)
+
+	// Find scenes
+	var scenes []core.Scene
+	for _, scene := range s.scenes.Scenes() {
+		if s.aut.IsAdmin(key) {
+			scenes = append(scenes, scene)
+			continue
+		}
+
+		var actions []core.SceneAction
+		for _, action := range scene.Actions {
+			if read, _ := s.aut.Validation(key, action.Device); read {
+				actions = append(actions, action)
+			}
+		}
+		if len(actions) > 0 {
+			scene.Actions = actions
+			scenes = append(scenes, scene)
+		}
+	}
+
+	// Send response
+	s.responseList(ctx, "Scenes list", true, "", scenes)
+}
+
+func (s *SceneHandler) AddScene(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	s.editScene(ctx, "Add scene", func() error {
+		return s.scenes.Add(name)
+	})
+}
+
+func (s *SceneHandler) RemoveScene(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	s.editScene(ctx, "Remove scene", func() error {
+		var err = s.scenes.Remove(name)
+		if err != nil {
+			return err
+		}
+
+		if s.sched.ForgetScene(name) {
+			err = s.db.SaveScheduleBase()
+			if err != nil {
+				return err
+			}
+		}
+
+		if s.rules.ForgetScene(name) {
+			return s.db.SaveRuleBase()
+		}
+		return nil
+	})
+}
+
+// SetDeviceStatus Set target status of relay, light or multi relay
+func (s *SceneHandler) SetDeviceStatus(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	s.editScene(ctx, "Set scene device status", func() error {
+		var dev = s.storage.Device(device)
+		if dev == nil {
+			return errors.New("Device not found")
+		}
+		if !s.cmd.IsSwitchable(dev) {
+			return errors.New("Device can't be switched")
+		}
+
+		var status, err = strconv.ParseBool(ctx.UserValue("status").(string))
+		if err != nil {
+			return errors.New("Fail to convert status")
+		}
+
+		return s.scenes.SetAction(name, core.SceneAction{Device: device, Status: status})
+	})
+}
+
+// SetDeviceLevel Set target level of dimmer
+func (s *SceneHandler) SetDeviceLevel(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	s.editScene(ctx, "Set scene device level", func() error {
+		if _, ok := s.storage.Device(device).(*base.Dimmer); !ok {
+			return errors.New("Dimmer not found")
+		}
+
+		var level, err = strconv.Atoi(ctx.UserValue("level").(string))
+		if err != nil || level < base.DimmerMinLevel || level > base.DimmerMaxLevel {
+			return errors.New("Fail to convert level")
+		}
+
+		return s.scenes.SetAction(name, core.SceneAction{Device: device, Level: level})
+	})
+}
+
+func (s *SceneHandler) RemoveSceneDevice(ctx *fasthttp.RequestCtx) {
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+	var device = ctx.UserValue("device").(string)
+
+	s.editScene(ctx, "Remove scene device", func() error {
+		return s.scenes.RemoveAction(name, device)
+	})
+}
+
+// Activate Apply scene. Devices the user can't write are reported
+// as failed, devices the user can't read are not reported
+func (s *SceneHandler) Activate(ctx *fasthttp.RequestCtx) {
+	var key = ctx.UserValue("user").(string)
+	var name, _ = url.QueryUnescape(ctx.UserValue("name").(string))
+
+	// Check user rights
+	var act = actor(s.aut, key)
+	if act == "" {
+		s.responseCommand(ctx, "Activate scene", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var results, err = s.cmd.ActivateScene(act, name, writeAllowed(s.aut, key))
+	if err != nil {
+		s.responseCommand(ctx, "Activate scene", false, err.Error(), nil)
+		return
+	}
+
+	var readable []core.ActionResult
+	for _, res := range results {
+		var read, _ = s.aut.Validation(key, res.Device)
+		if read {
+			readable = append(readable, res)
+		}
+	}
+
+	// Send response
+	s.responseCommand(ctx, "Activate scene", true, "", readable)
+}
+
+// editScene Change scenes by admin and save scenes base
+func (s *SceneHandler) editScene(ctx *fasthttp.RequestCtx, oper string, edit func() error) {
+	// Check user rights
+	var admin = s.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		s.responseList(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var err = edit()
+	if err != nil {
+		s.responseList(ctx, oper, false, err.Error(), nil)
+		return
+	}
+
+	// Save scenes
+	err = s.db.SaveSceneBase()
+	if err != nil {
+		s.responseList(ctx, "Save scene", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	s.responseList(ctx, oper, true, "", nil)
+}
+
+func (s *SceneHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, scenes []core.Scene) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var scResp = api.SceneListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, scene := range scenes {
+		var sc = api.SceneSingleResponse{Name: scene.Name}
+		for _, action := range scene.Actions {
+			sc.Actions = append(sc.Actions, api.SceneActionResponse{
+				Device: action.Device,
+				Status: action.Status,
+				Level:  action.Level,
+			})
+		}
+		scResp.Scenes = append(scResp.Scenes, sc)
+	}
+
+	if result {
+		s.log.Info("SCENEH", oper)
+	} else {
+		s.log.Error("SCENEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(scResp)
+
+	ctx.Write(bytes)
+}
+
+func (s *SceneHandler) responseCommand(ctx *fasthttp.RequestCtx, oper string, result bool, err string, results []core.ActionResult) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var scResp = api.SceneCommandResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, res := range results {
+		var dev = api.GroupDeviceResult{Device: res.Device, Result: res.Err == nil}
+		if res.Err != nil {
+			dev.Error = res.Err.Error()
+		}
+		scResp.Devices = append(scResp.Devices, dev)
+	}
+
+	if result {
+		s.log.Info("SCENEH", oper)
+	} else {
+		s.log.Error("SCENEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(scResp)
+
+	ctx.Write(bytes)
+}
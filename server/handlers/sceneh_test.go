@@ -0,0 +1,104 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestScenes(t *testing.T) {
+	var env = newTestEnv(t)
+
+	var sub = env.events.Subscribe(0, core.EventScene)
+
+	request(env.sch.AddScene, map[string]string{"user": testAdminKey, "name": "Night"})
+	request(env.sch.SetDeviceStatus, map[string]string{"user": testAdminKey, "name": "Night", "device": "relay0",
+		"status": "true"})
+	request(env.sch.SetDeviceStatus, map[string]string{"user": testAdminKey, "name": "Night", "device": "relay1",
+		"status": "true"})
+	request(env.sch.SetDeviceLevel, map[string]string{"user": testAdminKey, "name": "Night", "device": "dimmer0",
+		"level": "30"})
+	request(env.sch.SetDeviceLevel, map[string]string{"user": testAdminKey, "name": "Night", "device": "relay2",
+		"level": "30"})
+	for _, level := range []string{"-1", "101"} {
+		request(env.sch.SetDeviceLevel, map[string]string{"user": testAdminKey, "name": "Night", "device": "dimmer0",
+			"level": level})
+	}
+
+	var scene, ok = env.scenes.Scene("Night")
+	if !ok || len(scene.Actions) != 3 || scene.Actions[2].Level != 30 {
+		t.Fatal("wrong scene actions:", scene.Actions)
+	}
+
+	// Unknown key can't activate scene
+	var ctx = request(env.sch.Activate, map[string]string{"user": "unknown", "name": "Night"})
+	if strings.Contains(string(ctx.Response.Body()), `"result":true`) || len(sub.Events()) != 0 {
+		t.Fatalf("scene activated by unknown key: %s", ctx.Response.Body())
+	}
+
+	// User can read and write relay0 only
+	ctx = request(env.sch.Activate, map[string]string{"user": testUserKey, "name": "Night"})
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var resp api.SceneCommandResponse
+	var err = json.Unmarshal(ctx.Response.Body(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Result || len(resp.Devices) != 1 || resp.Devices[0].Device != "relay0" || !resp.Devices[0].Result {
+		t.Fatal("wrong scene activation report:", resp)
+	}
+	if !env.storage.Device("relay0").(*base.Relay).Status() || env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Error("wrong relays statuses after scene activation")
+	}
+	if len(sub.Events()) != 1 {
+		t.Fatal("scene event was not published")
+	}
+	var event = <-sub.Events()
+	if event.Value != "Night" || event.Device != "" || event.Actor != core.UserActor("user") {
+		t.Error("wrong scene event:", event)
+	}
+
+	// Scene without applied actions is not activated
+	request(env.sch.AddScene, map[string]string{"user": testAdminKey, "name": "Day"})
+	request(env.sch.SetDeviceStatus, map[string]string{"user": testAdminKey, "name": "Day", "device": "relay1",
+		"status": "true"})
+	request(env.sch.Activate, map[string]string{"user": testUserKey, "name": "Day"})
+	if len(sub.Events()) != 0 || env.storage.Device("relay1").(*base.Relay).Status() {
+		t.Error("scene without applied actions is activated")
+	}
+	request(env.sch.RemoveScene, map[string]string{"user": testAdminKey, "name": "Day"})
+
+	request(env.sch.Activate, map[string]string{"user": testAdminKey, "name": "Night"})
+	if env.storage.Device("dimmer0").(*base.Dimmer).Target() != 30 {
+		t.Error("dimmer level was not applied")
+	}
+
+	// Check saved base
+	request(env.devh.RenameDevice, map[string]string{"user": testAdminKey, "name": "relay1", "newname": "boiler"})
+
+	var scenes db.SceneDB
+	err = env.cfg.LoadFromFile(&scenes, filepath.Join(env.dir, "scene.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenes.Scenes) != 1 || len(scenes.Scenes[0].Actions) != 3 || scenes.Scenes[0].Actions[1].Device != "boiler" {
+		t.Error("wrong saved scenes:", scenes.Scenes)
+	}
+}
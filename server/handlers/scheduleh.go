@@ -0,0 +1,269 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type ScheduleHandler struct {
+	aut   *auth.Authorization
+	cmd   *core.Commander
+	sched *core.Scheduler
+	sun   *core.Sun
+	db    *db.Database
+	log   *utils.Log
+}
+
+func NewScheduleHandler(a *auth.Authorization, c *core.Commander, sch *core.Scheduler, sun *core.Sun,
+	d *db.Database, l *utils.Log) *ScheduleHandler {
+	return &ScheduleHandler{
+		aut:   a,
+		cmd:   c,
+		sched: sch,
+		sun:   sun,
+		db:    d,
+		log:   l,
+	}
+}
+
+func (s *ScheduleHandler) Jobs(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = s.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		s.responseList(ctx, "Jobs list", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	s.responseList(ctx, "Jobs list", true, "", s.sched.Jobs())
+}
+
+// AddJob Add job. Query arguments: cron expression, sun event with
+// "offset" in minutes or one-shot unix time "at", dimmer "level" and
+// "catchup" flag
+func (s *ScheduleHandler) AddJob(ctx *fasthttp.RequestCtx) {
+	var args = ctx.QueryArgs()
+	var target, _ = url.QueryUnescape(ctx.UserValue("target").(string))
+
+	var job = core.Job{
+		Cron:    string(args.Peek("cron")),
+		Sun:     string(args.Peek("sun")),
+		Target:  target,
+		Action:  ctx.UserValue("action").(string),
+		Enabled: true,
+		CatchUp: args.GetBool("catchup"),
+	}
+
+	s.editJobs(ctx, "Add job", func() error {
+		// Check job target
+		var err = s.cmd.CheckTarget(target, job.Action)
+		if err != nil {
+			return err
+		}
+		if job.Action == core.ActionLevel {
+			job.Level, err = strconv.Atoi(string(args.Peek("level")))
+			if err != nil {
+				return errors.New("Fail to convert level")
+			}
+		}
+
+		if job.Sun != "" {
+			if !s.sun.Configured() {
+				return errors.New("Location is not configured")
+			}
+			if args.Has("offset") {
+				var offset, err = strconv.Atoi(string(args.Peek("offset")))
+				if err != nil {
+					return errors.New("Fail to convert offset")
+				}
+				job.Offset = time.Duration(offset) * time.Minute
+			}
+		}
+
+		if job.Cron == "" && job.Sun == "" {
+			var at, err = strconv.ParseInt(string(args.Peek("at")), 10, 64)
+			if err != nil {
+				return errors.New("Fail to convert job time")
+			}
+			job.At = time.Unix(at, 0)
+		}
+
+		_, err = s.sched.Add(job)
+		return err
+	})
+}
+
+// SunTimes Preview sun events for upcoming days
+func (s *ScheduleHandler) SunTimes(ctx *fasthttp.RequestCtx) {
+	// Check user rights
+	var admin = s.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		s.responseSun(ctx, "Sun times", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var days, err = strconv.Atoi(ctx.UserValue("days").(string))
+	if err != nil || days <= 0 || days > core.SunMaxDays {
+		s.responseSun(ctx, "Sun times", false, "Fail to convert days", nil)
+		return
+	}
+	if !s.sun.Configured() {
+		s.responseSun(ctx, "Sun times", false, "Location is not configured", nil)
+		return
+	}
+
+	var times []core.SunTimes
+	var now = time.Now()
+	for i := 0; i < days; i++ {
+		times = append(times, s.sun.Times(now.AddDate(0, 0, i)))
+	}
+
+	// Send response
+	s.responseSun(ctx, "Sun times", true, "", times)
+}
+
+func (s *ScheduleHandler) RemoveJob(ctx *fasthttp.RequestCtx) {
+	s.editJobs(ctx, "Remove job", func() error {
+		var id, err = strconv.Atoi(ctx.UserValue("id").(string))
+		if err != nil {
+			return errors.New("Fail to convert ID")
+		}
+		return s.sched.Remove(id)
+	})
+}
+
+func (s *ScheduleHandler) EnableJob(ctx *fasthttp.RequestCtx) {
+	s.setEnabled(ctx, "Enable job", true)
+}
+
+func (s *ScheduleHandler) DisableJob(ctx *fasthttp.RequestCtx) {
+	s.setEnabled(ctx, "Disable job", false)
+}
+
+func (s *ScheduleHandler) setEnabled(ctx *fasthttp.RequestCtx, oper string, enabled bool) {
+	s.editJobs(ctx, oper, func() error {
+		var id, err = strconv.Atoi(ctx.UserValue("id").(string))
+		if err != nil {
+			return errors.New("Fail to convert ID")
+		}
+		return s.sched.SetEnabled(id, enabled)
+	})
+}
+
+// editJobs Change jobs by admin and save schedule base
+func (s *ScheduleHandler) editJobs(ctx *fasthttp.RequestCtx, oper string, edit func() error) {
+	// Check user rights
+	var admin = s.aut.IsAdmin(ctx.UserValue("user").(string))
+	if !admin {
+		s.responseList(ctx, oper, false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var err = edit()
+	if err != nil {
+		s.responseList(ctx, oper, false, err.Error(), nil)
+		return
+	}
+
+	// Save jobs
+	err = s.db.SaveScheduleBase()
+	if err != nil {
+		s.responseList(ctx, "Save schedule", false, err.Error(), nil)
+		return
+	}
+
+	// Send response
+	s.responseList(ctx, oper, true, "", nil)
+}
+
+func (s *ScheduleHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, jobs []core.Job) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var jobsResp = api.JobListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, job := range jobs {
+		jobsResp.Jobs = append(jobsResp.Jobs, api.JobSingleResponse{
+			ID:      job.ID,
+			Cron:    job.Cron,
+			Sun:     job.Sun,
+			Offset:  int(job.Offset / time.Minute),
+			At:      unixTime(job.At),
+			Target:  job.Target,
+			Action:  job.Action,
+			Level:   job.Level,
+			Enabled: job.Enabled,
+			CatchUp: job.CatchUp,
+			LastRun: unixTime(job.LastRun),
+			Next:    unixTime(job.Next),
+		})
+	}
+
+	if result {
+		s.log.Info("SCHEDULEH", oper)
+	} else {
+		s.log.Error("SCHEDULEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(jobsResp)
+
+	ctx.Write(bytes)
+}
+
+func (s *ScheduleHandler) responseSun(ctx *fasthttp.RequestCtx, oper string, result bool, err string, times []core.SunTimes) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var sunResp = api.SunListResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+		Timezone:  s.sun.Location().String(),
+	}
+
+	for _, day := range times {
+		sunResp.Days = append(sunResp.Days, api.SunSingleResponse{
+			Date:    day.Date.Format("2006-01-02"),
+			Dawn:    unixTime(day.Dawn),
+			Sunrise: unixTime(day.Sunrise),
+			Sunset:  unixTime(day.Sunset),
+			Dusk:    unixTime(day.Dusk),
+		})
+	}
+
+	if result {
+		s.log.Info("SCHEDULEH", oper)
+	} else {
+		s.log.Error("SCHEDULEH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(sunResp)
+
+	ctx.Write(bytes)
+}
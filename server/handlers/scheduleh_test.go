@@ -0,0 +1,153 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+func TestScheduler(t *testing.T) {
+	var env = newTestEnv(t)
+	var now = time.Now()
+
+	// Jobs were planned before downtime
+	var jobs = []core.Job{
+		{ID: 1, Cron: "0 * * * *", Target: "relay1", Action: core.ActionOn, Enabled: true, CatchUp: true,
+			Created: now.Add(-3 * time.Hour)},
+		{ID: 2, Cron: "0 * * * *", Target: "relay2", Action: core.ActionOn, Enabled: true,
+			Created: now.Add(-3 * time.Hour)},
+		{ID: 3, At: now.Add(-10 * time.Second), Target: "relay3", Action: core.ActionSwitch, Enabled: true},
+		{ID: 4, At: now.Add(time.Hour), Target: "relay0", Action: core.ActionOn, Enabled: true},
+	}
+	for _, job := range jobs {
+		var err = env.sched.Restore(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env.sched.Check(now)
+
+	for dev, want := range map[string]bool{"relay0": false, "relay1": true, "relay2": false, "relay3": true} {
+		if env.storage.Device(dev).(*base.Relay).Status() != want {
+			t.Error("wrong", dev, "status after scheduler check")
+		}
+	}
+
+	var job, _ = env.sched.Job(1)
+	if !job.LastRun.Equal(now) || !job.Next.After(now) {
+		t.Error("missed job was not planned again:", job)
+	}
+	job, _ = env.sched.Job(3)
+	if job.Enabled {
+		t.Error("one-shot job is enabled after run")
+	}
+
+	// Missed runs are not repeated
+	env.sched.Check(now.Add(time.Second))
+	if !env.storage.Device("relay3").(*base.Relay).Status() {
+		t.Error("one-shot job was run twice")
+	}
+
+	// Check API and saved base
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/?cron=" + url.QueryEscape("*/5 * * * *") + "&catchup=true")
+	ctx.SetUserValue("user", testAdminKey)
+	ctx.SetUserValue("target", "dimmer0")
+	ctx.SetUserValue("action", core.ActionOn)
+	env.schedh.AddJob(&ctx)
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	var resp api.JobListResponse
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Error("dimmer on job is added")
+	}
+
+	ctx.SetUserValue("target", "relay2")
+	ctx.Response.Reset()
+	env.schedh.AddJob(&ctx)
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result {
+		t.Fatal("fail to add job:", resp.Error)
+	}
+
+	var schedule db.ScheduleDB
+	var err = env.cfg.LoadFromFile(&schedule, filepath.Join(env.dir, "schedule.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.LastID != 5 || len(schedule.Jobs) != 5 || schedule.Jobs[4].Cron != "*/5 * * * *" ||
+		!schedule.Jobs[4].CatchUp || schedule.Jobs[0].LastRun != now.Unix() {
+		t.Error("wrong saved schedule:", schedule)
+	}
+
+	request(env.devh.RemoveDevice, map[string]string{"user": testAdminKey, "id": strconv.Itoa(env.storage.Device("relay2").ID())})
+	if len(env.sched.Jobs()) != 3 {
+		t.Error("removed device jobs are kept")
+	}
+}
+
+func TestSunSchedule(t *testing.T) {
+	var env = newTestEnv(t)
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/?sun=" + core.SunSunset + "&offset=-30")
+	ctx.SetUserValue("user", testAdminKey)
+	ctx.SetUserValue("target", "relay1")
+	ctx.SetUserValue("action", core.ActionOn)
+	env.schedh.AddJob(&ctx)
+
+	var resp api.JobListResponse
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Result {
+		t.Fatal("sun job is added without location")
+	}
+
+	var sunResp api.SunListResponse
+	var preview = request(env.schedh.SunTimes, map[string]string{"user": testAdminKey, "days": "3"})
+	json.Unmarshal(preview.Response.Body(), &sunResp)
+	if sunResp.Result || sunResp.Error != "Location is not configured" {
+		t.Error("sun times are previewed without location:", sunResp)
+	}
+
+	env.sun.SetLocation(55.75, 37.62, time.FixedZone("MSK", 3*60*60))
+
+	ctx.Response.Reset()
+	env.schedh.AddJob(&ctx)
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if !resp.Result {
+		t.Fatal("fail to add sun job:", resp.Error)
+	}
+	var job, _ = env.sched.Job(1)
+	if job.Offset != -30*time.Minute || !job.Next.After(time.Now()) {
+		t.Error("wrong sun job:", job)
+	}
+
+	// Preview sun times
+	preview = request(env.schedh.SunTimes, map[string]string{"user": testAdminKey, "days": "3"})
+	json.Unmarshal(preview.Response.Body(), &sunResp)
+	if !sunResp.Result || len(sunResp.Days) != 3 || sunResp.Days[0].Sunrise >= sunResp.Days[0].Sunset {
+		t.Error("wrong sun times preview:", sunResp)
+	}
+}
@@ -0,0 +1,257 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/api"
+	"github.com/futcity/controller/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+)
+
+type SensorHandler struct {
+	storage *core.Storage
+	aut     *auth.Authorization
+	events  *core.Events
+	log     *utils.Log
+}
+
+func NewSensorHandler(s *core.Storage, a *auth.Authorization, e *core.Events, l *utils.Log) *SensorHandler {
+	return &SensorHandler{
+		storage: s,
+		aut:     a,
+		events:  e,
+		log:     l,
+	}
+}
+
+// Routes Sensor web API routes
+func (s *SensorHandler) Routes() []Route {
+	return []Route{
+		{api.HttpReqSensorData, s.Data},
+		{api.HttpReqSensorHistory, s.History},
+		{api.HttpReqSensorUpdate, s.Update},
+		{api.HttpReqSensorUpdatePress, s.Update},
+		{api.HttpReqSensorList, s.Devices},
+	}
+}
+
+func (s *SensorHandler) Data(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var sensor, ok = s.storage.Device(ctx.UserValue("id").(string)).(*base.Sensor)
+	if !ok {
+		s.response(ctx, "Get sensor data", false, "Sensor not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = s.aut.Validation(ctx.UserValue("user").(string), sensor.Name())
+	if !read {
+		s.response(ctx, "Get sensor data", false, "Authorization failed", nil)
+		return
+	}
+
+	// Send response
+	s.response(ctx, "Get sensor data", true, "", sensor)
+}
+
+func (s *SensorHandler) History(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var sensor, ok = s.storage.Device(ctx.UserValue("id").(string)).(*base.Sensor)
+	if !ok {
+		s.responseHistory(ctx, "Get sensor history", false, "Sensor not found", nil)
+		return
+	}
+
+	// Check user rights
+	var read, _ = s.aut.Validation(ctx.UserValue("user").(string), sensor.Name())
+	if !read {
+		s.responseHistory(ctx, "Get sensor history", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var from, err = strconv.ParseInt(ctx.UserValue("from").(string), 10, 64)
+	if err != nil {
+		s.responseHistory(ctx, "Get sensor history", false, "Fail to convert time range", nil)
+		return
+	}
+	to, err := strconv.ParseInt(ctx.UserValue("to").(string), 10, 64)
+	if err != nil {
+		s.responseHistory(ctx, "Get sensor history", false, "Fail to convert time range", nil)
+		return
+	}
+
+	// Send response
+	s.responseHistory(ctx, "Get sensor history", true, "", sensor.History(time.Unix(from, 0), time.Unix(to, 0)))
+}
+
+func (s *SensorHandler) Devices(ctx *fasthttp.RequestCtx) {
+	// Find all devices
+	var sensors []*base.Sensor
+	for _, device := range s.storage.FindDevices(deviceFilter(ctx, "sensor")) {
+		var read, _ = s.aut.Validation(ctx.UserValue("user").(string), device.Name())
+		if read {
+			sensors = append(sensors, device.(*base.Sensor))
+		}
+	}
+
+	// Send response
+	s.responseList(ctx, "Get sensors list", true, "", sensors)
+}
+
+func (s *SensorHandler) Update(ctx *fasthttp.RequestCtx) {
+	// Find device in storage
+	var sensor, ok = s.storage.Device(ctx.UserValue("id").(string)).(*base.Sensor)
+	if !ok {
+		s.response(ctx, "Update sensor", false, "Sensor not found", nil)
+		return
+	}
+
+	// Check user rights
+	var report = s.aut.ReportValidation(ctx.UserValue("user").(string), sensor.Name())
+	if !report {
+		s.response(ctx, "Update sensor", false, "Authorization failed", nil)
+		return
+	}
+
+	// Process operation
+	var data base.SensorData
+	var err error
+
+	data.Temperature, err = strconv.ParseFloat(ctx.UserValue("temp").(string), 64)
+	if err != nil {
+		s.response(ctx, "Update sensor", false, "Fail to convert temperature", sensor)
+		return
+	}
+	data.Humidity, err = strconv.ParseFloat(ctx.UserValue("hum").(string), 64)
+	if err != nil {
+		s.response(ctx, "Update sensor", false, "Fail to convert humidity", sensor)
+		return
+	}
+	if pres, ok := ctx.UserValue("pres").(string); ok {
+		data.Pressure, err = strconv.ParseFloat(pres, 64)
+		if err != nil {
+			s.response(ctx, "Update sensor", false, "Fail to convert pressure", sensor)
+			return
+		}
+		data.HasPressure = true
+	}
+	var old = core.DeviceState(sensor)
+	sensor.Update(data)
+	if core.DeviceState(sensor) != old {
+		s.events.Publish(core.Event{
+			Type:   core.EventStateChanged,
+			Actor:  actor(s.aut, ctx.UserValue("user").(string)),
+			Device: sensor.Name(),
+			Value:  core.DeviceState(sensor),
+		})
+	}
+
+	// Send response
+	s.response(ctx, "Update sensor", true, "", sensor)
+}
+
+func (s *SensorHandler) dataResponse(data base.SensorData) api.SensorDataResponse {
+	return api.SensorDataResponse{
+		Time:        unixTime(data.Time),
+		Temperature: data.Temperature,
+		Humidity:    data.Humidity,
+		Pressure:    data.Pressure,
+		HasPressure: data.HasPressure,
+	}
+}
+
+func (s *SensorHandler) response(ctx *fasthttp.RequestCtx, oper string, result bool, err string, sensor *base.Sensor) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var resp = api.SensorResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	if sensor != nil {
+		resp.Online = sensor.Online()
+		resp.Data = s.dataResponse(sensor.Data())
+	}
+
+	if result && oper != "Update sensor" {
+		s.log.Info("SENSORH", oper)
+	} else if !result {
+		s.log.Error("SENSORH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(resp)
+	ctx.Write(bytes)
+}
+
+func (s *SensorHandler) responseList(ctx *fasthttp.RequestCtx, oper string, result bool, err string, sensors []*base.Sensor) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var sensResp = api.SensorDevResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, sensor := range sensors {
+		sensResp.Sensors = append(sensResp.Sensors, api.SensorSingleDevResponse{
+			Name:        sensor.Name(),
+			Description: sensor.Description(),
+			Online:      sensor.Online(),
+			Data:        s.dataResponse(sensor.Data()),
+		})
+	}
+
+	if result {
+		s.log.Info("SENSORH", oper)
+	} else {
+		s.log.Error("SENSORH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(sensResp)
+
+	ctx.Write(bytes)
+}
+
+func (s *SensorHandler) responseHistory(ctx *fasthttp.RequestCtx, oper string, result bool, err string, history []base.SensorData) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx.Response.Header.SetContentType("application/json")
+
+	var histResp = api.SensorHistoryResponse{
+		Operation: oper,
+		Result:    result,
+		Error:     err,
+	}
+
+	for _, data := range history {
+		histResp.History = append(histResp.History, s.dataResponse(data))
+	}
+
+	if result {
+		s.log.Info("SENSORH", oper)
+	} else {
+		s.log.Error("SENSORH", oper, err)
+	}
+
+	var bytes, _ = json.Marshal(histResp)
+
+	ctx.Write(bytes)
+}
@@ -0,0 +1,43 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/futcity/controller/core"
+)
+
+func TestSensorEvents(t *testing.T) {
+	var env = newTestEnv(t)
+	var sub = env.events.Subscribe(0, core.EventStateChanged)
+
+	var err = env.storage.AddDevice("sensor0", "Test", "sensor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only changed state is published
+	for i := 0; i < 2; i++ {
+		request(env.senh.Update, map[string]string{"user": testAdminKey, "id": "sensor0", "temp": "21.5", "hum": "40"})
+	}
+
+	var want = core.Event{Type: core.EventStateChanged, Actor: core.UserActor("admin"), Device: "sensor0", Value: "21.5"}
+	if len(sub.Events()) != 1 {
+		t.Fatal("wrong events count:", len(sub.Events()))
+	}
+	var e = <-sub.Events()
+	e.Time = time.Time{}
+	if e != want {
+		t.Error("wrong event:", e, "want:", want)
+	}
+}
@@ -0,0 +1,135 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/valyala/fasthttp"
+)
+
+// Route Web API route
+type Route struct {
+	Path    string
+	Handler fasthttp.RequestHandler
+}
+
+// IDeviceHandler Device type web API
+type IDeviceHandler interface {
+	Routes() []Route
+}
+
+// DeviceTypeHandlers All device types web API handlers, device types
+// add own handlers on registration
+type DeviceTypeHandlers struct {
+	handlers []IDeviceHandler
+}
+
+// NewDeviceTypeHandlers Make empty list of device types handlers
+func NewDeviceTypeHandlers() *DeviceTypeHandlers {
+	return &DeviceTypeHandlers{}
+}
+
+// Add Add device type handler
+func (d *DeviceTypeHandlers) Add(handler IDeviceHandler) {
+	d.handlers = append(d.handlers, handler)
+}
+
+// Routes Get routes of all device types handlers
+func (d *DeviceTypeHandlers) Routes() []Route {
+	var routes []Route
+
+	for _, handler := range d.handlers {
+		routes = append(routes, handler.Routes()...)
+	}
+
+	return routes
+}
+
+// unixTime Convert time to unix seconds, zero time is converted to zero
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// actor Get events actor of user key or device token
+func actor(a *auth.Authorization, key string) string {
+	var prof = a.ProfileByKey(key)
+	if prof != nil {
+		return core.UserActor(prof.Name())
+	}
+
+	if device, ok := a.TokenDevice(key); ok {
+		return core.DeviceActor(device)
+	}
+	return ""
+}
+
+// writeAllowed Make commander rights check for user key
+func writeAllowed(a *auth.Authorization, key string) core.Allowed {
+	return func(device string, channel int) bool {
+		if channel < 0 {
+			var _, write = a.Validation(key, device)
+			return write
+		}
+
+		var _, write = a.ChannelValidation(key, device, channel)
+		return write
+	}
+}
+
+// deviceFilter Make device list filter from request query arguments:
+// type, room, firmware, ip, mac, online, tag and attr. Tag and attr can
+// be repeated, attr is passed as "key:value" or "key"
+func deviceFilter(ctx *fasthttp.RequestCtx, devType string) *core.DeviceFilter {
+	var args = ctx.QueryArgs()
+
+	var filter = &core.DeviceFilter{
+		Type:       devType,
+		Room:       string(args.Peek("room")),
+		Firmware:   string(args.Peek("firmware")),
+		IP:         string(args.Peek("ip")),
+		MAC:        string(args.Peek("mac")),
+		Attributes: make(map[string]string),
+	}
+
+	if filter.Type == "" {
+		filter.Type = string(args.Peek("type"))
+	}
+
+	if args.Has("online") {
+		var online, err = strconv.ParseBool(string(args.Peek("online")))
+		if err == nil {
+			filter.Online = &online
+		}
+	}
+
+	for _, tag := range args.PeekMulti("tag") {
+		filter.Tags = append(filter.Tags, string(tag))
+	}
+
+	for _, attr := range args.PeekMulti("attr") {
+		var kv = strings.SplitN(string(attr), ":", 2)
+		if len(kv) == 2 {
+			filter.Attributes[kv[0]] = kv[1]
+		} else {
+			filter.Attributes[kv[0]] = ""
+		}
+	}
+
+	return filter
+}
@@ -21,20 +21,39 @@ import (
 
 // WebServer Main server
 type WebServer struct {
-	relayh *handlers.RelayHandler
-	grph   *handlers.GroupHandler
-	devh   *handlers.DeviceHandler
-	profh  *handlers.ProfileHandler
+	grph  *handlers.GroupHandler
+	sch   *handlers.SceneHandler
+	ilh   *handlers.InterlockHandler
+	schdh *handlers.ScheduleHandler
+	ruleh *handlers.RuleHandler
+	devh  *handlers.DeviceHandler
+	profh *handlers.ProfileHandler
+	evh   *handlers.EventHandler
+	push  *handlers.PushHandler
+	fwh   *handlers.FirmwareHandler
+	pairh *handlers.PairingHandler
+	types *handlers.DeviceTypeHandlers
 }
 
 // NewWebServer Make new struct
-func NewWebServer(rh *handlers.RelayHandler, gh *handlers.GroupHandler,
-	dh *handlers.DeviceHandler, ph *handlers.ProfileHandler) *WebServer {
+func NewWebServer(gh *handlers.GroupHandler, sh *handlers.SceneHandler,
+	ih *handlers.InterlockHandler, sdh *handlers.ScheduleHandler, rh *handlers.RuleHandler, dh *handlers.DeviceHandler,
+	ph *handlers.ProfileHandler, eh *handlers.EventHandler, pu *handlers.PushHandler,
+	fh *handlers.FirmwareHandler, pah *handlers.PairingHandler,
+	th *handlers.DeviceTypeHandlers) *WebServer {
 	return &WebServer{
-		relayh: rh,
-		grph:   gh,
-		devh:   dh,
-		profh:  ph,
+		grph:  gh,
+		sch:   sh,
+		ilh:   ih,
+		schdh: sdh,
+		ruleh: rh,
+		devh:  dh,
+		profh: ph,
+		evh:   eh,
+		push:  pu,
+		fwh:   fh,
+		pairh: pah,
+		types: th,
 	}
 }
 
@@ -58,24 +77,87 @@ func (w *WebServer) Start(ip string, port int) error {
 	r.NotFound = w.NotFoundHandler
 
 	r.GET(api.HttpReqGroupList, w.grph.Groups)
+	r.GET(api.HttpReqGroupDevList, w.grph.GroupDevices)
+	r.GET(api.HttpReqGroupAdd, w.grph.AddGroup)
+	r.GET(api.HttpReqGroupRemove, w.grph.RemoveGroup)
+	r.GET(api.HttpReqGroupAddDev, w.grph.AddGroupDevice)
+	r.GET(api.HttpReqGroupMoveDev, w.grph.MoveGroupDevice)
+	r.GET(api.HttpReqGroupDevRemove, w.grph.RemoveGroupDevice)
+	r.GET(api.HttpReqGroupSet, w.grph.SetStatus)
+	r.GET(api.HttpReqSceneList, w.sch.Scenes)
+	r.GET(api.HttpReqSceneAdd, w.sch.AddScene)
+	r.GET(api.HttpReqSceneRemove, w.sch.RemoveScene)
+	r.GET(api.HttpReqSceneSetDev, w.sch.SetDeviceStatus)
+	r.GET(api.HttpReqSceneSetLevel, w.sch.SetDeviceLevel)
+	r.GET(api.HttpReqSceneDevRemove, w.sch.RemoveSceneDevice)
+	r.GET(api.HttpReqSceneActivate, w.sch.Activate)
+	r.GET(api.HttpReqInterlockList, w.ilh.Interlocks)
+	r.GET(api.HttpReqInterlockAdd, w.ilh.AddInterlock)
+	r.GET(api.HttpReqInterlockRemove, w.ilh.RemoveInterlock)
+	r.GET(api.HttpReqInterlockPolicy, w.ilh.SetPolicy)
+	r.GET(api.HttpReqInterlockAddDev, w.ilh.AddDevice)
+	r.GET(api.HttpReqInterlockDevRemove, w.ilh.RemoveDevice)
+	r.GET(api.HttpReqInterlockAddChan, w.ilh.AddChannel)
+	r.GET(api.HttpReqInterlockChRemove, w.ilh.RemoveChannel)
+	r.GET(api.HttpReqScheduleList, w.schdh.Jobs)
+	r.GET(api.HttpReqScheduleAdd, w.schdh.AddJob)
+	r.GET(api.HttpReqScheduleRemove, w.schdh.RemoveJob)
+	r.GET(api.HttpReqScheduleEnable, w.schdh.EnableJob)
+	r.GET(api.HttpReqScheduleDisable, w.schdh.DisableJob)
+	r.GET(api.HttpReqScheduleSun, w.schdh.SunTimes)
+	r.GET(api.HttpReqRuleList, w.ruleh.Rules)
+	r.GET(api.HttpReqRuleLog, w.ruleh.Log)
+	r.GET(api.HttpReqRuleAdd, w.ruleh.AddRule)
+	r.GET(api.HttpReqRuleRemove, w.ruleh.RemoveRule)
+	r.GET(api.HttpReqRuleEnable, w.ruleh.EnableRule)
+	r.GET(api.HttpReqRuleDisable, w.ruleh.DisableRule)
+	r.GET(api.HttpReqRuleAddCond, w.ruleh.AddCondition)
+	r.GET(api.HttpReqRuleCondRemove, w.ruleh.RemoveCondition)
+	r.GET(api.HttpReqRuleAddAction, w.ruleh.AddAction)
+	r.GET(api.HttpReqRuleActionRemove, w.ruleh.RemoveAction)
 	r.GET(api.HttpReqDevList, w.devh.DeviceList)
 	r.GET(api.HttpReqDevAdd, w.devh.AddDevice)
 	r.GET(api.HttpReqDevRemove, w.devh.RemoveDevice)
 	r.GET(api.HttpReqDevByDesc, w.devh.DeviceByDescription)
+	r.GET(api.HttpReqDevSetDesc, w.devh.SetDescription)
+	r.GET(api.HttpReqDevRename, w.devh.RenameDevice)
+	r.GET(api.HttpReqDevSetRoom, w.devh.SetRoom)
+	r.GET(api.HttpReqDevRoomRemove, w.devh.RemoveRoom)
+	r.GET(api.HttpReqDevAddTag, w.devh.AddTag)
+	r.GET(api.HttpReqDevTagRemove, w.devh.RemoveTag)
+	r.GET(api.HttpReqDevSetAttr, w.devh.SetAttribute)
+	r.GET(api.HttpReqDevAttrRemove, w.devh.RemoveAttribute)
+	r.GET(api.HttpReqDevUpdateInfo, w.devh.UpdateInfo)
+
+	r.GET(api.HttpReqEventList, w.evh.Events)
+	r.GET(api.HttpReqEventDevList, w.evh.Events)
+	r.GET(api.HttpReqPush, w.push.Connect)
 
 	r.GET(api.HttpReqProfAdd, w.profh.AddProfile)
 	r.GET(api.HttpReqProfAddDev, w.profh.AddProfileDevice)
 	r.GET(api.HttpReqProfAddGrp, w.profh.AddProfileGroup)
 	r.GET(api.HttpReqProfDevRemove, w.profh.RemoveProfileDevice)
 	r.GET(api.HttpReqProfGrpRemove, w.profh.RemoveProfileGroup)
+	r.GET(api.HttpReqProfAddChan, w.profh.AddProfileChannel)
+	r.GET(api.HttpReqProfChanRemove, w.profh.RemoveProfileChannel)
 	r.GET(api.HttpReqProfRemove, w.profh.RemoveProfile)
 	r.GET(api.HttpReqProfList, w.profh.ProfileList)
 
-	r.GET(api.HttpReqRelayStatus, w.relayh.Status)
-	r.GET(api.HttpReqRelaySet, w.relayh.SetStatus)
-	r.GET(api.HttpReqRelayUpdate, w.relayh.Update)
-	r.GET(api.HttpReqRelaySwitch, w.relayh.Switch)
-	r.GET(api.HttpReqRelayList, w.relayh.Devices)
+	r.GET(api.HttpReqFirmwareList, w.fwh.Images)
+	r.POST(api.HttpReqFirmwareUpload, w.fwh.Upload)
+	r.GET(api.HttpReqFirmwareRemove, w.fwh.RemoveImage)
+	r.GET(api.HttpReqFirmwareRollout, w.fwh.Rollouts)
+	r.GET(api.HttpReqFirmwareCheck, w.fwh.Check)
+	r.GET(api.HttpReqFirmwareDownload, w.fwh.Download)
+
+	r.GET(api.HttpReqPairingList, w.pairh.PairingList)
+	r.GET(api.HttpReqPairingCode, w.pairh.CreateCode)
+	r.GET(api.HttpReqPairingRevoke, w.pairh.Revoke)
+	r.GET(api.HttpReqPair, w.pairh.Pair)
+
+	for _, route := range w.types.Routes() {
+		r.GET(route.Path, route.Handler)
+	}
 
 	return fasthttp.ListenAndServe(fmt.Sprintf("%s:%d", ip, port), r.Handler)
 }
@@ -0,0 +1,34 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package binary
+
+import (
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/handlers"
+	"github.com/futcity/controller/utils"
+)
+
+// Register Register all binary input device types and their web API.
+// Binary inputs states are not saved
+func Register(s *core.Storage, th *handlers.DeviceTypeHandlers, a *auth.Authorization,
+	e *core.Events, l *utils.Log) {
+	for typ := range base.BinaryTypes {
+		var devType = typ
+		s.RegisterType(devType, func(name string, desc string) devices.IDevice {
+			return base.NewBinary(name, desc, devType)
+		})
+	}
+
+	th.Add(handlers.NewBinaryHandler(s, a, e, l))
+}
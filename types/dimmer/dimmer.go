@@ -0,0 +1,38 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package dimmer
+
+import (
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/handlers"
+	"github.com/futcity/controller/utils"
+)
+
+// Register Register dimmer device type, its database and web API
+func Register(s *core.Storage, d *db.Database, th *handlers.DeviceTypeHandlers, a *auth.Authorization,
+	cmd *core.Commander, e *core.Events, l *utils.Log) error {
+	s.RegisterType("dimmer", func(name string, desc string) devices.IDevice {
+		return base.NewDimmer(name, desc)
+	})
+
+	var err = d.RegisterBase("dimmer", d.LoadDimmerBase, d.DimmerBase)
+	if err != nil {
+		return err
+	}
+
+	th.Add(handlers.NewDimmerHandler(s, a, l, cmd, e))
+
+	return nil
+}
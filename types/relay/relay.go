@@ -0,0 +1,38 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package relay
+
+import (
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/db"
+	"github.com/futcity/controller/server/handlers"
+	"github.com/futcity/controller/utils"
+)
+
+// Register Register relay device type, its database and web API
+func Register(s *core.Storage, d *db.Database, th *handlers.DeviceTypeHandlers, a *auth.Authorization,
+	rec *core.Reconciler, cmd *core.Commander, t *core.Timers, e *core.Events, l *utils.Log) error {
+	s.RegisterType("relay", func(name string, desc string) devices.IDevice {
+		return base.NewRelay(name, desc)
+	})
+
+	var err = d.RegisterBase("relay", d.LoadRelayBase, d.RelayBase)
+	if err != nil {
+		return err
+	}
+
+	th.Add(handlers.NewRelayHandler(s, a, l, rec, cmd, t, e))
+
+	return nil
+}
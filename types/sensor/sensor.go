@@ -0,0 +1,31 @@
+///////////////////////////////////////////////////////////////////
+//
+// Future City Project
+//
+// Copyright (C) 2020-2021 Sergey Denisov. GPLv3
+//
+// Written by Sergey Denisov aka LittleBuster (DenisovS21@gmail.com)
+//
+///////////////////////////////////////////////////////////////////
+
+package sensor
+
+import (
+	"github.com/futcity/controller/auth"
+	"github.com/futcity/controller/core"
+	"github.com/futcity/controller/core/devices"
+	"github.com/futcity/controller/core/devices/base"
+	"github.com/futcity/controller/server/handlers"
+	"github.com/futcity/controller/utils"
+)
+
+// Register Register sensor device type and its web API. Sensor readings
+// are not saved
+func Register(s *core.Storage, th *handlers.DeviceTypeHandlers, a *auth.Authorization,
+	e *core.Events, l *utils.Log) {
+	s.RegisterType("sensor", func(name string, desc string) devices.IDevice {
+		return base.NewSensor(name, desc)
+	})
+
+	th.Add(handlers.NewSensorHandler(s, a, e, l))
+}